@@ -529,18 +529,20 @@ class _OrdersScreenState extends State<OrdersScreen> {
                                                       Chip(
                                                         label: Text(
                                                           order.paymentStatus == 'paid' ? 'Paid' :
-                                                          order.paymentStatus == 'pending' ? 'Pending' :
+                                                          order.paymentStatus == 'unpaid' ? 'Unpaid' :
+                                                          order.paymentStatus == 'partial' ? 'Partially Paid' :
+                                                          order.paymentStatus == 'overpaid' ? 'Overpaid' :
                                                           order.paymentStatus == 'failed' ? 'Failed' :
                                                           order.paymentStatus!,
                                                           style: const TextStyle(fontSize: 12),
                                                         ),
-                                                        backgroundColor: order.paymentStatus == 'paid' ? Colors.green.withValues(alpha: 0.2) :
-                                                                        order.paymentStatus == 'pending' ? Colors.orange.withValues(alpha: 0.2) :
+                                                        backgroundColor: order.paymentStatus == 'paid' || order.paymentStatus == 'overpaid' ? Colors.green.withValues(alpha: 0.2) :
+                                                                        order.paymentStatus == 'unpaid' || order.paymentStatus == 'partial' ? Colors.orange.withValues(alpha: 0.2) :
                                                                         order.paymentStatus == 'failed' ? Colors.red.withValues(alpha: 0.2) :
                                                                         Colors.grey.withValues(alpha: 0.2),
                                                         labelStyle: TextStyle(
-                                                          color: order.paymentStatus == 'paid' ? Colors.green :
-                                                                 order.paymentStatus == 'pending' ? Colors.orange :
+                                                          color: order.paymentStatus == 'paid' || order.paymentStatus == 'overpaid' ? Colors.green :
+                                                                 order.paymentStatus == 'unpaid' || order.paymentStatus == 'partial' ? Colors.orange :
                                                                  order.paymentStatus == 'failed' ? Colors.red :
                                                                  Colors.grey,
                                                         ),
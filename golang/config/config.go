@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -23,8 +24,18 @@ type Config struct {
 	SMTPUser     string
 	SMTPPassword string
 	SMTPFrom     string
+
+	// PaymentSimulatorSecret enables the simulated payment provider and signs
+	// its webhooks. Leave empty in production.
+	PaymentSimulatorSecret string
+
+	// StockReservationTTL is how long a draft order holds stock before the
+	// reservation sweeper releases it.
+	StockReservationTTL time.Duration
 }
 
+const DefaultStockReservationTTL = 2 * time.Hour
+
 func Load() (*Config, error) {
 	godotenv.Load()
 
@@ -45,6 +56,10 @@ func Load() (*Config, error) {
 		SMTPUser:     getEnv("SMTP_USER", ""),
 		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
 		SMTPFrom:     getEnv("SMTP_FROM", ""),
+
+		PaymentSimulatorSecret: getEnv("PAYMENT_SIMULATOR_SECRET", ""),
+
+		StockReservationTTL: getEnvDuration("STOCK_RESERVATION_TTL", DefaultStockReservationTTL),
 	}, nil
 }
 
@@ -54,3 +69,12 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultValue
+}
@@ -7,10 +7,12 @@ import (
 	"os"
 	"siargao-trading-road/config"
 	"siargao-trading-road/models"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
@@ -64,6 +66,11 @@ func Connect(cfg *config.Config) error {
 		return fmt.Errorf("failed to migrate feature_flags index: %w", err)
 	}
 
+	err = migratePaymentVerificationReferenceIndex()
+	if err != nil {
+		return fmt.Errorf("failed to migrate payment_verifications index: %w", err)
+	}
+
 	if DB == nil {
 		return fmt.Errorf("database connection not initialized before AutoMigrate")
 	}
@@ -76,6 +83,8 @@ func Connect(cfg *config.Config) error {
 		&models.FeatureFlag{},
 		&models.Product{},
 		&models.BusinessDocument{},
+		&models.DeliverySlot{},
+		&models.Shipment{},
 		&models.Order{},
 		&models.OrderItem{},
 		&models.Message{},
@@ -84,7 +93,35 @@ func Connect(cfg *config.Config) error {
 		&models.BugReport{},
 		&models.ScheduleException{},
 		&models.StockHistory{},
-	}
+		&models.OrderStatusHistory{},
+		&models.StockReservation{},
+		&models.StandingOrder{},
+		&models.StandingOrderItem{},
+		&models.SupplierDeliverySettings{},
+		&models.DeliveryFeeBand{},
+		&models.DeliveryFeeZone{},
+		&models.ServiceAreaPlace{},
+		&models.ReturnRequest{},
+		&models.ReturnItem{},
+		&models.CreditMemo{},
+		&models.RFQ{},
+		&models.RFQLine{},
+		&models.RFQRecipient{},
+		&models.Quote{},
+		&models.QuoteLine{},
+		&models.DeliveryProof{},
+		&models.DeliveryLocationPing{},
+		&models.PaymentVerification{},
+		&models.PaymentReference{},
+		&models.Payment{},
+		&models.CreditAccount{},
+		&models.StatementDelivery{},
+		&models.PaymentIntent{},
+	}
+
+	hadReservations := migrator.HasTable(&models.StockReservation{})
+	hadPayments := migrator.HasTable(&models.Payment{})
+	hadPaymentReferences := migrator.HasTable(&models.PaymentReference{})
 
 	for _, model := range modelsToMigrate {
 		if err := migrator.AutoMigrate(model); err != nil {
@@ -92,6 +129,24 @@ func Connect(cfg *config.Config) error {
 		}
 	}
 
+	if !hadReservations {
+		if err := migrateDraftStockToReservations(cfg); err != nil {
+			return fmt.Errorf("failed to migrate draft stock to reservations: %w", err)
+		}
+	}
+
+	if !hadPayments {
+		if err := migrateOrderPaymentsToLedger(); err != nil {
+			return fmt.Errorf("failed to migrate order payments to ledger: %w", err)
+		}
+	}
+
+	if !hadPaymentReferences {
+		if err := migratePaymentReferences(); err != nil {
+			return fmt.Errorf("failed to migrate payment references: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -132,7 +187,12 @@ func Migrate() error {
 		return fmt.Errorf("failed to migrate feature_flags index: %w", err)
 	}
 
-	err = DB.AutoMigrate(&models.User{}, &models.Employee{}, &models.Product{}, &models.Order{}, &models.OrderItem{}, &models.BusinessDocument{}, &models.Message{}, &models.Rating{}, &models.AuditLog{}, &models.BugReport{}, &models.ScheduleException{}, &models.FeatureFlag{}, &models.StockHistory{})
+	err = migratePaymentVerificationReferenceIndex()
+	if err != nil {
+		return fmt.Errorf("failed to migrate payment_verifications index: %w", err)
+	}
+
+	err = DB.AutoMigrate(&models.User{}, &models.Employee{}, &models.Product{}, &models.Order{}, &models.OrderItem{}, &models.BusinessDocument{}, &models.Message{}, &models.Rating{}, &models.AuditLog{}, &models.BugReport{}, &models.ScheduleException{}, &models.FeatureFlag{}, &models.StockHistory{}, &models.OrderStatusHistory{}, &models.StockReservation{}, &models.StandingOrder{}, &models.StandingOrderItem{}, &models.SupplierDeliverySettings{}, &models.DeliveryFeeBand{}, &models.DeliveryFeeZone{}, &models.ServiceAreaPlace{}, &models.ReturnRequest{}, &models.ReturnItem{}, &models.CreditMemo{}, &models.RFQ{}, &models.RFQLine{}, &models.RFQRecipient{}, &models.Quote{}, &models.QuoteLine{}, &models.DeliverySlot{}, &models.DeliveryProof{}, &models.DeliveryLocationPing{}, &models.Shipment{}, &models.PaymentVerification{}, &models.PaymentReference{}, &models.Payment{}, &models.CreditAccount{}, &models.StatementDelivery{}, &models.PaymentIntent{})
 	if err != nil {
 		return err
 	}
@@ -257,6 +317,120 @@ func migrateFeatureFlagsIndex() error {
 	return nil
 }
 
+// migratePaymentVerificationReferenceIndex drops the unique reference index
+// so every decision on an order can be kept; AutoMigrate adds a plain one.
+func migratePaymentVerificationReferenceIndex() error {
+	if DB == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+
+	if err := DB.Exec("DROP INDEX IF EXISTS idx_payment_verifications_reference_number").Error; err != nil {
+		return fmt.Errorf("failed to drop unique payment_verifications reference index: %w", err)
+	}
+
+	return nil
+}
+
+// migrateDraftStockToReservations converts drafts created before stock
+// reservations existed: their items were taken straight off stock_quantity,
+// so the stock is put back and held by an active reservation instead.
+func migrateDraftStockToReservations(cfg *config.Config) error {
+	if DB == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+
+	var items []models.OrderItem
+	err := DB.Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.status = ? AND orders.deleted_at IS NULL", models.OrderStatusDraft).
+		Find(&items).Error
+	if err != nil {
+		return fmt.Errorf("failed to load draft order items: %w", err)
+	}
+
+	ttl := cfg.StockReservationTTL
+	if ttl <= 0 {
+		ttl = config.DefaultStockReservationTTL
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for _, item := range items {
+			if err := tx.Unscoped().Model(&models.Product{}).Where("id = ?", item.ProductID).
+				UpdateColumn("stock_quantity", gorm.Expr("stock_quantity + ?", item.Quantity)).Error; err != nil {
+				return err
+			}
+			reservation := models.StockReservation{
+				OrderID:   item.OrderID,
+				ProductID: item.ProductID,
+				Quantity:  item.Quantity,
+				Status:    models.ReservationStatusActive,
+				ExpiresAt: expiresAt,
+			}
+			if err := tx.Omit("Product", "Order").Create(&reservation).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// migratePaymentReferences claims the reference numbers of verifications made
+// before payment_references existed, each for the order it was first used on.
+func migratePaymentReferences() error {
+	if DB == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+
+	var verifications []models.PaymentVerification
+	if err := DB.Order("verified_at ASC, id ASC").Find(&verifications).Error; err != nil {
+		return fmt.Errorf("failed to load payment verifications: %w", err)
+	}
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for _, verification := range verifications {
+			claim := models.PaymentReference{ReferenceNumber: verification.ReferenceNumber, OrderID: verification.OrderID}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&claim).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// migrateOrderPaymentsToLedger converts orders from before the payment
+// ledger: a paid order gets one entry for its total, and pending becomes
+// unpaid.
+func migrateOrderPaymentsToLedger() error {
+	if DB == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+
+	var orders []models.Order
+	if err := DB.Where("payment_status = ?", models.PaymentStatusPaid).Find(&orders).Error; err != nil {
+		return fmt.Errorf("failed to load paid orders: %w", err)
+	}
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for _, order := range orders {
+			payment := models.Payment{
+				OrderID: order.ID,
+				Method:  order.PaymentMethod,
+				Amount:  order.TotalAmount,
+				Note:    "recorded before the payment ledger",
+				PaidAt:  order.UpdatedAt,
+			}
+			if err := tx.Create(&payment).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Order{}).Where("id = ?", order.ID).UpdateColumn("amount_paid", order.TotalAmount).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Order{}).Where("payment_status IN ?", []string{"pending", ""}).
+			UpdateColumn("payment_status", models.PaymentStatusUnpaid).Error
+	})
+}
+
 func SeedAdmin() error {
 	adminEmail := getEnv("ADMIN_EMAIL", "admin@siargaotradingroad.com")
 	adminPassword := getEnv("ADMIN_PASSWORD", "admin123")
@@ -320,7 +494,7 @@ func FixDatabaseTables() error {
 		return fmt.Errorf("database connection not initialized")
 	}
 
-	tableNames := []string{"users", "employees", "products", "orders", "order_items", "business_documents", "messages", "ratings", "audit_logs", "bug_reports", "schedule_exceptions", "feature_flags", "products_stocks_history"}
+	tableNames := []string{"users", "employees", "products", "orders", "order_items", "business_documents", "messages", "ratings", "audit_logs", "bug_reports", "schedule_exceptions", "feature_flags", "products_stocks_history", "order_status_history", "stock_reservations", "standing_orders", "standing_order_items", "supplier_delivery_settings", "delivery_fee_bands", "delivery_fee_zones", "service_area_places", "return_requests", "return_items", "credit_memos", "rfqs", "rfq_lines", "rfq_recipients", "quotes", "quote_lines", "delivery_slots", "delivery_proofs", "delivery_location_pings", "shipments", "payment_verifications", "payment_references", "payments", "credit_accounts", "statement_deliveries", "payment_intents"}
 
 	fmt.Println("Dropping problematic tables to allow clean recreation...")
 	for _, tableName := range tableNames {
@@ -352,7 +526,12 @@ func FixDatabaseTables() error {
 		return fmt.Errorf("failed to migrate feature_flags index: %w", err)
 	}
 
-	err = DB.AutoMigrate(&models.User{}, &models.Employee{}, &models.Product{}, &models.Order{}, &models.OrderItem{}, &models.BusinessDocument{}, &models.Message{}, &models.Rating{}, &models.AuditLog{}, &models.BugReport{}, &models.ScheduleException{}, &models.FeatureFlag{}, &models.StockHistory{})
+	err = migratePaymentVerificationReferenceIndex()
+	if err != nil {
+		return fmt.Errorf("failed to migrate payment_verifications index: %w", err)
+	}
+
+	err = DB.AutoMigrate(&models.User{}, &models.Employee{}, &models.Product{}, &models.Order{}, &models.OrderItem{}, &models.BusinessDocument{}, &models.Message{}, &models.Rating{}, &models.AuditLog{}, &models.BugReport{}, &models.ScheduleException{}, &models.FeatureFlag{}, &models.StockHistory{}, &models.OrderStatusHistory{}, &models.StockReservation{}, &models.StandingOrder{}, &models.StandingOrderItem{}, &models.SupplierDeliverySettings{}, &models.DeliveryFeeBand{}, &models.DeliveryFeeZone{}, &models.ServiceAreaPlace{}, &models.ReturnRequest{}, &models.ReturnItem{}, &models.CreditMemo{}, &models.RFQ{}, &models.RFQLine{}, &models.RFQRecipient{}, &models.Quote{}, &models.QuoteLine{}, &models.DeliverySlot{}, &models.DeliveryProof{}, &models.DeliveryLocationPing{}, &models.Shipment{}, &models.PaymentVerification{}, &models.PaymentReference{}, &models.Payment{}, &models.CreditAccount{}, &models.StatementDelivery{}, &models.PaymentIntent{})
 	if err != nil {
 		return fmt.Errorf("failed to migrate models after dropping tables: %w", err)
 	}
@@ -443,6 +443,7 @@ func generateEmployeeToken(owner models.User, employee models.Employee, cfg *con
 		"can_chat":             employee.CanChat,
 		"can_change_status":    employee.CanChangeStatus,
 		"can_rate":             employee.CanRate,
+		"is_driver":            employee.IsDriver(),
 		"exp":                  time.Now().Add(time.Hour * 24 * 7).Unix(),
 	}
 
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// checkoutError reports why one supplier's draft could not be checked out.
+type checkoutError struct {
+	OrderID      uint   `json:"order_id"`
+	SupplierID   uint   `json:"supplier_id"`
+	SupplierName string `json:"supplier_name"`
+	Error        string `json:"error"`
+}
+
+// checkoutSlot is the delivery slot requested for one supplier's draft.
+type checkoutSlot struct {
+	SupplierID     uint   `json:"supplier_id" binding:"required"`
+	DeliverySlotID uint   `json:"delivery_slot_id" binding:"required"`
+	DeliveryDate   string `json:"delivery_date" binding:"required"`
+}
+
+// errCheckoutRejected rolls back a checkout that produced checkoutErrors.
+var errCheckoutRejected = errors.New("checkout rejected")
+
+// CheckoutDrafts submits every non-empty draft of the store in one
+// transaction, using the same payment and delivery choices for all of them.
+// Delivery slots belong to one supplier, so they are given per supplier.
+// Every draft is validated before any is submitted; if one fails, none are
+// submitted and the errors are reported per supplier.
+func CheckoutDrafts(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+
+	role, _ := c.Get("role")
+	if role != "store" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only stores can check out"})
+		return
+	}
+
+	var req struct {
+		orderSubmission
+		DeliverySlots []checkoutSlot `json:"delivery_slots" binding:"dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	slots := make(map[uint]checkoutSlot, len(req.DeliverySlots))
+	for _, slot := range req.DeliverySlots {
+		slots[slot.SupplierID] = slot
+	}
+
+	actor := getOrderActor(c)
+	var drafts []models.Order
+	var failures []checkoutError
+	transitions := make(map[uint]models.OrderTransition)
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Locking the drafts keeps a concurrent checkout from submitting them twice.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("OrderItems").
+			Where("store_id = ? AND status = ?", userID, models.OrderStatusDraft).
+			Order("id ASC").Find(&drafts).Error; err != nil {
+			return err
+		}
+		nonEmpty := drafts[:0]
+		for _, draft := range drafts {
+			if len(draft.OrderItems) > 0 {
+				nonEmpty = append(nonEmpty, draft)
+			}
+		}
+		drafts = nonEmpty
+		if len(drafts) == 0 {
+			return &orderError{Code: http.StatusBadRequest, Message: "there are no draft orders with items to check out"}
+		}
+
+		supplierIDs := make([]uint, 0, len(drafts))
+		for _, draft := range drafts {
+			supplierIDs = append(supplierIDs, draft.SupplierID)
+		}
+		var supplierList []models.User
+		if err := tx.Where("id IN ?", supplierIDs).Find(&supplierList).Error; err != nil {
+			return err
+		}
+		suppliers := make(map[uint]models.User, len(supplierList))
+		for _, supplier := range supplierList {
+			suppliers[supplier.ID] = supplier
+		}
+
+		fail := func(order models.Order, err error) error {
+			var orderErr *orderError
+			if !errors.As(err, &orderErr) {
+				return err
+			}
+			failures = append(failures, checkoutError{
+				OrderID:      order.ID,
+				SupplierID:   order.SupplierID,
+				SupplierName: suppliers[order.SupplierID].Name,
+				Error:        orderErr.Message,
+			})
+			return nil
+		}
+
+		for i := range drafts {
+			draft := &drafts[i]
+			submission := req.orderSubmission
+			submission.DeliverySlotID, submission.DeliveryDate = nil, ""
+			if slot, ok := slots[draft.SupplierID]; ok {
+				slotID := slot.DeliverySlotID
+				submission.DeliverySlotID, submission.DeliveryDate = &slotID, slot.DeliveryDate
+			}
+			if err := prepareOrderSubmission(tx, draft, submission, true); err != nil {
+				if err := fail(*draft, err); err != nil {
+					return err
+				}
+			}
+		}
+		if len(failures) > 0 {
+			return errCheckoutRejected
+		}
+
+		for i := range drafts {
+			transition, err := submitOrderTx(tx, &drafts[i], actor, "")
+			if err != nil {
+				if err := fail(drafts[i], err); err != nil {
+					return err
+				}
+				continue
+			}
+			transitions[drafts[i].ID] = transition
+		}
+		if len(failures) > 0 {
+			return errCheckoutRejected
+		}
+		return nil
+	})
+	if errors.Is(err, errCheckoutRejected) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "some orders could not be submitted; nothing was submitted", "errors": failures})
+		return
+	}
+	if err != nil {
+		log.Printf("CheckoutDrafts: failed for store %d: %v", userID, err)
+		writeOrderError(c, err, "failed to check out")
+		return
+	}
+
+	orders := make([]models.Order, 0, len(drafts))
+	emailService := getEmailService(c)
+	for _, draft := range drafts {
+		var order models.Order
+		if err := database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product").First(&order, draft.ID).Error; err != nil {
+			log.Printf("CheckoutDrafts: failed to load order %d: %v", draft.ID, err)
+			continue
+		}
+		runOrderNotifications(emailService, order, models.OrderStatusDraft, transitions[draft.ID])
+		orders = append(orders, order)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"orders": orders})
+}
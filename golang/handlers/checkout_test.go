@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupCheckoutTestDB(t *testing.T) (models.User, models.User) {
+	t.Helper()
+	store, supplier := setupOrderTestDB(t)
+	migrateTestModels(t, &models.StockReservation{}, &models.SupplierDeliverySettings{}, &models.DeliveryFeeBand{}, &models.DeliveryFeeZone{}, &models.ServiceAreaPlace{}, &models.ScheduleException{})
+	return store, supplier
+}
+
+func buildCheckoutRouter(user models.User) *gin.Engine {
+	r := buildOrderRouter(user)
+	r.POST("/orders/:id/items", AddOrderItem)
+	r.POST("/orders/:id/submit", SubmitOrder)
+	r.POST("/orders/checkout", CheckoutDrafts)
+	return r
+}
+
+func TestCheckoutDraftsIsAllOrNothing(t *testing.T) {
+	store, supplier := setupCheckoutTestDB(t)
+	other := models.User{Email: "other-supplier@example.com", Password: "x", Name: "Fish Port", Role: models.RoleSupplier}
+	database.DB.Create(&other)
+	rice := models.Product{SupplierID: supplier.ID, Name: "Rice", SKU: "RICE-1", Price: 50, StockQuantity: 10}
+	fish := models.Product{SupplierID: other.ID, Name: "Tuna", SKU: "FISH-1", Price: 200, StockQuantity: 10}
+	database.DB.Create(&rice)
+	database.DB.Create(&fish)
+	database.DB.Create(&models.SupplierDeliverySettings{SupplierID: supplier.ID, MinimumOrderAmount: 100})
+	database.DB.Create(&models.SupplierDeliverySettings{SupplierID: other.ID, MinimumOrderAmount: 1000})
+	closed := models.User{Email: "closed@example.com", Password: "x", Name: "Closed", Role: models.RoleSupplier}
+	database.DB.Create(&closed)
+	database.DB.Model(&closed).Update("is_open", false)
+
+	router := buildCheckoutRouter(store)
+	riceDraft := createTestOrder(t, store, supplier, models.OrderStatusDraft)
+	fishDraft := createTestOrder(t, store, other, models.OrderStatusDraft)
+	emptyDraft := createTestOrder(t, store, closed, models.OrderStatusDraft)
+	doOrderRequest(router, http.MethodPost, fmt.Sprintf("/orders/%d/items", riceDraft.ID), fmt.Sprintf(`{"product_id":%d,"quantity":2}`, rice.ID))
+	doOrderRequest(router, http.MethodPost, fmt.Sprintf("/orders/%d/items", fishDraft.ID), fmt.Sprintf(`{"product_id":%d,"quantity":2}`, fish.ID))
+
+	body := `{"payment_method":"cash_on_delivery","delivery_option":"pickup"}`
+	w := doOrderRequest(router, http.MethodPost, "/orders/checkout", body)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+	var failed struct {
+		Errors []checkoutError `json:"errors"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &failed)
+	if len(failed.Errors) != 1 || failed.Errors[0].SupplierID != other.ID {
+		t.Fatalf("expected one error for the fish supplier, got %+v", failed.Errors)
+	}
+
+	var count int64
+	database.DB.Model(&models.Order{}).Where("store_id = ? AND status = ?", store.ID, models.OrderStatusPreparing).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected nothing submitted, got %d orders", count)
+	}
+	var reloaded models.Product
+	database.DB.First(&reloaded, rice.ID)
+	if reloaded.StockQuantity != 10 {
+		t.Fatalf("expected rice stock untouched, got %d", reloaded.StockQuantity)
+	}
+
+	doOrderRequest(router, http.MethodPost, fmt.Sprintf("/orders/%d/items", fishDraft.ID), fmt.Sprintf(`{"product_id":%d,"quantity":5}`, fish.ID))
+	w = doOrderRequest(router, http.MethodPost, "/orders/checkout", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result struct {
+		Orders []models.Order `json:"orders"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if len(result.Orders) != 2 {
+		t.Fatalf("expected 2 submitted orders, got %d", len(result.Orders))
+	}
+	var empty models.Order
+	database.DB.First(&empty, emptyDraft.ID)
+	if empty.Status != models.OrderStatusDraft {
+		t.Fatalf("expected empty draft to be left alone, got %s", empty.Status)
+	}
+	for _, order := range result.Orders {
+		if order.Status != models.OrderStatusPreparing {
+			t.Fatalf("order %d is %s", order.ID, order.Status)
+		}
+	}
+	reloaded = models.Product{}
+	database.DB.First(&reloaded, fish.ID)
+	if reloaded.StockQuantity != 3 {
+		t.Fatalf("expected fish stock 3, got %d", reloaded.StockQuantity)
+	}
+
+	database.DB.Create(&models.ScheduleException{UserID: supplier.ID, Date: storedDeliveryDate(nowInPH()), IsClosed: true})
+	lateDraft := createTestOrder(t, store, supplier, models.OrderStatusDraft)
+	doOrderRequest(router, http.MethodPost, fmt.Sprintf("/orders/%d/items", lateDraft.ID), fmt.Sprintf(`{"product_id":%d,"quantity":2}`, rice.ID))
+	w = doOrderRequest(router, http.MethodPost, fmt.Sprintf("/orders/%d/submit", lateDraft.ID), body)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "is closed") {
+		t.Fatalf("expected a closed supplier to refuse a single submit, got %d: %s", w.Code, w.Body.String())
+	}
+}
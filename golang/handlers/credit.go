@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+	"siargao-trading-road/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// overdueReminderRepeat is how long to wait before reminding a store about
+// the same overdue order again.
+const overdueReminderRepeat = 7 * 24 * time.Hour
+
+// receivableBalance is what is still owed on an order once payments and
+// the credit memos of approved returns are taken off.
+const receivableBalance = "orders.total_amount - orders.amount_paid - " +
+	"COALESCE((SELECT SUM(credit_memos.amount) FROM credit_memos WHERE credit_memos.order_id = orders.id), 0)"
+
+// creditAccountSummary is a credit account with what the store currently
+// owes on it.
+type creditAccountSummary struct {
+	models.CreditAccount
+	Outstanding float64 `json:"outstanding"`
+	Available   float64 `json:"available"`
+}
+
+// agingBuckets splits unpaid balances by how far past due they are.
+type agingBuckets struct {
+	Current    float64 `json:"current"`
+	Days1To30  float64 `json:"days_1_30"`
+	Days31To60 float64 `json:"days_31_60"`
+	Over60     float64 `json:"days_over_60"`
+	Total      float64 `json:"total"`
+}
+
+func (b *agingBuckets) add(balance float64, daysOverdue int) {
+	switch {
+	case daysOverdue <= 0:
+		b.Current = roundTo2(b.Current + balance)
+	case daysOverdue <= 30:
+		b.Days1To30 = roundTo2(b.Days1To30 + balance)
+	case daysOverdue <= 60:
+		b.Days31To60 = roundTo2(b.Days31To60 + balance)
+	default:
+		b.Over60 = roundTo2(b.Over60 + balance)
+	}
+	b.Total = roundTo2(b.Total + balance)
+}
+
+type receivableOrder struct {
+	OrderID     uint    `json:"order_id"`
+	DueDate     string  `json:"due_date"`
+	TotalAmount float64 `json:"total_amount"`
+	Balance     float64 `json:"balance"`
+	DaysOverdue int     `json:"days_overdue"`
+}
+
+type storeReceivables struct {
+	StoreID   uint              `json:"store_id"`
+	StoreName string            `json:"store_name"`
+	Aging     agingBuckets      `json:"aging"`
+	Orders    []receivableOrder `json:"orders"`
+}
+
+// GetMyCreditAccounts lists the credit a supplier has granted, or for a store
+// the credit it has been granted, with the outstanding balance on each.
+func GetMyCreditAccounts(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+	role, _ := c.Get("role")
+
+	query := database.DB.Preload("Store").Preload("Supplier").Order("created_at ASC")
+	switch role {
+	case "supplier":
+		query = query.Where("supplier_id = ?", userID)
+	case "store":
+		query = query.Where("store_id = ?", userID)
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "only suppliers and stores have credit accounts"})
+		return
+	}
+
+	var accounts []models.CreditAccount
+	if err := query.Find(&accounts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch credit accounts"})
+		return
+	}
+
+	summaries := make([]creditAccountSummary, 0, len(accounts))
+	for _, account := range accounts {
+		summary, err := summarizeCreditAccount(database.DB, account)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to calculate credit balance"})
+			return
+		}
+		summaries = append(summaries, summary)
+	}
+	c.JSON(http.StatusOK, summaries)
+}
+
+// SetCreditAccount grants a store credit, or changes its limit and terms.
+// Only the supplier owner can do this.
+func SetCreditAccount(c *gin.Context) {
+	userID, ok := requireCreditOwner(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		CreditLimit float64 `json:"credit_limit" binding:"required,gt=0"`
+		TermsDays   int     `json:"terms_days" binding:"required,oneof=15 30"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var store models.User
+	if err := database.DB.Where("id = ? AND role = ?", c.Param("store_id"), models.RoleStore).First(&store).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "store not found"})
+		return
+	}
+
+	var account models.CreditAccount
+	err := database.DB.Where("supplier_id = ? AND store_id = ?", userID, store.ID).First(&account).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load credit account"})
+		return
+	}
+	status := http.StatusOK
+	if account.ID == 0 {
+		status = http.StatusCreated
+	}
+	account.SupplierID = userID
+	account.StoreID = store.ID
+	account.CreditLimit = roundTo2(req.CreditLimit)
+	account.TermsDays = req.TermsDays
+	if err := database.DB.Omit("Store", "Supplier").Save(&account).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save credit account"})
+		return
+	}
+
+	database.DB.Preload("Store").Preload("Supplier").First(&account, account.ID)
+	summary, err := summarizeCreditAccount(database.DB, account)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to calculate credit balance"})
+		return
+	}
+	c.JSON(status, summary)
+}
+
+// DeleteCreditAccount stops a store buying on credit. Orders already placed
+// on credit stay due.
+func DeleteCreditAccount(c *gin.Context) {
+	userID, ok := requireCreditOwner(c)
+	if !ok {
+		return
+	}
+
+	result := database.DB.Where("supplier_id = ? AND store_id = ?", userID, c.Param("store_id")).Delete(&models.CreditAccount{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete credit account"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "credit account not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "credit account deleted"})
+}
+
+// GetReceivablesAging buckets a supplier's unpaid orders with due dates by
+// days past due, per store. as_of defaults to today in the Philippines.
+func GetReceivablesAging(c *gin.Context) {
+	userID, ok := requireSupplier(c)
+	if !ok {
+		return
+	}
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+
+	asOf := nowInPH()
+	if value := c.Query("as_of"); value != "" {
+		day, err := parseDeliveryDate(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "as_of must be YYYY-MM-DD"})
+			return
+		}
+		asOf = day
+	}
+	today := storedDeliveryDate(asOf)
+
+	var orders []models.Order
+	err := openReceivables(database.DB).Preload("Store").Preload("CreditMemos").Where("supplier_id = ?", userID).
+		Order("store_id ASC, due_date ASC, id ASC").Find(&orders).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch receivables"})
+		return
+	}
+
+	var totals agingBuckets
+	stores := []storeReceivables{}
+	for _, order := range orders {
+		if len(stores) == 0 || stores[len(stores)-1].StoreID != order.StoreID {
+			stores = append(stores, storeReceivables{StoreID: order.StoreID, StoreName: order.Store.Name, Orders: []receivableOrder{}})
+		}
+		entry := &stores[len(stores)-1]
+		balance := orderReceivableBalance(order)
+		overdue := daysOverdue(*order.DueDate, today)
+		entry.Aging.add(balance, overdue)
+		totals.add(balance, overdue)
+		entry.Orders = append(entry.Orders, receivableOrder{
+			OrderID:     order.ID,
+			DueDate:     order.DueDate.Format(dateLayout),
+			TotalAmount: order.TotalAmount,
+			Balance:     balance,
+			DaysOverdue: overdue,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"as_of":  today.Format(dateLayout),
+		"totals": totals,
+		"stores": stores,
+	})
+}
+
+// applyCreditTerms checks a credit order against the store's limit and sets
+// its due date. order.TotalAmount must already be final.
+func applyCreditTerms(tx *gorm.DB, order *models.Order, now time.Time) error {
+	// Locking the account serialises credit orders from the same store, so
+	// two submissions cannot both fit under the limit.
+	var account models.CreditAccount
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("supplier_id = ? AND store_id = ?", order.SupplierID, order.StoreID).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &orderError{Code: http.StatusBadRequest, Message: "you do not have credit terms with this supplier"}
+		}
+		return err
+	}
+
+	outstanding, err := outstandingCredit(tx, order.SupplierID, order.StoreID)
+	if err != nil {
+		return err
+	}
+	available := roundTo2(account.CreditLimit - outstanding)
+	if order.TotalAmount > available+0.005 {
+		return &orderError{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("order total ₱%.2f exceeds your available credit of ₱%.2f", order.TotalAmount, available),
+		}
+	}
+
+	due := storedDeliveryDate(now.AddDate(0, 0, account.TermsDays))
+	order.DueDate = &due
+	return nil
+}
+
+// outstandingCredit is what a store still owes a supplier on credit orders.
+func outstandingCredit(db *gorm.DB, supplierID, storeID uint) (float64, error) {
+	var outstanding float64
+	err := openReceivables(db).Model(&models.Order{}).
+		Where("supplier_id = ? AND store_id = ? AND payment_method = ?", supplierID, storeID, models.PaymentMethodCredit).
+		Select("COALESCE(SUM(" + receivableBalance + "), 0)").Scan(&outstanding).Error
+	return roundTo2(outstanding), err
+}
+
+// openReceivables scopes a query to submitted orders with a due date that
+// are not fully paid or credited.
+func openReceivables(db *gorm.DB) *gorm.DB {
+	return db.Where("orders.due_date IS NOT NULL AND "+receivableBalance+" > 0.005 AND orders.status NOT IN ?",
+		[]models.OrderStatus{models.OrderStatusDraft, models.OrderStatusCancelled})
+}
+
+// orderReceivableBalance is receivableBalance for an order loaded with its
+// CreditMemos.
+func orderReceivableBalance(order models.Order) float64 {
+	balance := order.TotalAmount - order.AmountPaid
+	for _, memo := range order.CreditMemos {
+		balance -= memo.Amount
+	}
+	return roundTo2(balance)
+}
+
+func summarizeCreditAccount(db *gorm.DB, account models.CreditAccount) (creditAccountSummary, error) {
+	outstanding, err := outstandingCredit(db, account.SupplierID, account.StoreID)
+	if err != nil {
+		return creditAccountSummary{}, err
+	}
+	return creditAccountSummary{
+		CreditAccount: account,
+		Outstanding:   outstanding,
+		Available:     roundTo2(account.CreditLimit - outstanding),
+	}, nil
+}
+
+// daysOverdue counts whole days from a due date to today; zero or less is
+// not yet overdue.
+func daysOverdue(due, today time.Time) int {
+	return int(today.Sub(storedDeliveryDate(due)).Hours() / 24)
+}
+
+func requireCreditOwner(c *gin.Context) (uint, bool) {
+	userID, ok := requireSupplier(c)
+	if !ok {
+		return 0, false
+	}
+	if getEmployeeContext(c).IsEmployee {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the supplier owner can change credit terms"})
+		return 0, false
+	}
+	return userID, true
+}
+
+// sendOverdueReminders emails stores about credit orders past their due
+// date, at most once every overdueReminderRepeat per order.
+func sendOverdueReminders(now time.Time, emailService *services.EmailService) {
+	if emailService == nil {
+		return
+	}
+	today := storedDeliveryDate(now.In(philippineTZ))
+
+	var orders []models.Order
+	err := openReceivables(database.DB).Preload("Store").Preload("Supplier").
+		Where("due_date < ?", today).
+		Where("last_reminder_at IS NULL OR last_reminder_at < ?", now.Add(-overdueReminderRepeat)).
+		Find(&orders).Error
+	if err != nil {
+		log.Printf("overdue reminders: failed to load overdue orders: %v", err)
+		return
+	}
+
+	for _, order := range orders {
+		// Only a reminder that went out starts the wait; a failed send is
+		// retried on the next run.
+		if err := emailService.SendPaymentOverdueEmail(order, daysOverdue(*order.DueDate, today)); err != nil {
+			log.Printf("overdue reminders: failed to email order %d: %v", order.ID, err)
+			continue
+		}
+		if err := database.DB.Model(&models.Order{}).Where("id = ?", order.ID).UpdateColumn("last_reminder_at", now).Error; err != nil {
+			log.Printf("overdue reminders: failed to mark order %d: %v", order.ID, err)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"siargao-trading-road/config"
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+	"siargao-trading-road/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupCreditTestDB(t *testing.T) (models.User, models.User) {
+	t.Helper()
+	store, supplier := setupOrderTestDB(t)
+	migrateTestModels(t, &models.StockReservation{}, &models.SupplierDeliverySettings{}, &models.DeliveryFeeBand{}, &models.DeliveryFeeZone{}, &models.ServiceAreaPlace{}, &models.ScheduleException{}, &models.CreditAccount{}, &models.CreditMemo{}, &models.Payment{})
+	return store, supplier
+}
+
+func buildCreditRouter(user models.User) *gin.Engine {
+	r := buildOrderRouter(user)
+	r.POST("/orders/:id/items", AddOrderItem)
+	r.POST("/orders/:id/submit", SubmitOrder)
+	r.GET("/me/credit-accounts", GetMyCreditAccounts)
+	r.PUT("/me/credit-accounts/:store_id", SetCreditAccount)
+	r.GET("/me/receivables/aging", GetReceivablesAging)
+	return r
+}
+
+func TestCreditOrdersRespectLimitAndAgeByDueDate(t *testing.T) {
+	store, supplier := setupCreditTestDB(t)
+	rice := models.Product{SupplierID: supplier.ID, Name: "Rice", SKU: "RICE-1", Price: 50, StockQuantity: 100}
+	database.DB.Create(&rice)
+	database.DB.Create(&models.SupplierDeliverySettings{SupplierID: supplier.ID, MinimumOrderAmount: 100})
+	supplierRouter := buildCreditRouter(supplier)
+	storeRouter := buildCreditRouter(store)
+
+	creditPath := fmt.Sprintf("/me/credit-accounts/%d", store.ID)
+	if w := doOrderRequest(supplierRouter, http.MethodPut, creditPath, `{"credit_limit":1000,"terms_days":45}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected unsupported terms to be refused, got %d", w.Code)
+	}
+	if w := doOrderRequest(supplierRouter, http.MethodPut, creditPath, `{"credit_limit":1000,"terms_days":30}`); w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	submit := func(quantity int) (int, models.Order) {
+		draft := createTestOrder(t, store, supplier, models.OrderStatusDraft)
+		doOrderRequest(storeRouter, http.MethodPost, fmt.Sprintf("/orders/%d/items", draft.ID), fmt.Sprintf(`{"product_id":%d,"quantity":%d}`, rice.ID, quantity))
+		w := doOrderRequest(storeRouter, http.MethodPost, fmt.Sprintf("/orders/%d/submit", draft.ID), `{"payment_method":"credit","delivery_option":"pickup"}`)
+		var order models.Order
+		json.Unmarshal(w.Body.Bytes(), &order)
+		return w.Code, order
+	}
+
+	code, first := submit(12)
+	if code != http.StatusOK || first.DueDate == nil {
+		t.Fatalf("expected credit order with a due date, got %d %+v", code, first.DueDate)
+	}
+	if want := storedDeliveryDate(nowInPH().AddDate(0, 0, 30)).Format(dateLayout); first.DueDate.Format(dateLayout) != want {
+		t.Fatalf("expected due date %s, got %s", want, first.DueDate.Format(dateLayout))
+	}
+	if code, _ := submit(10); code != http.StatusBadRequest {
+		t.Fatalf("expected order over the available credit to be refused, got %d", code)
+	}
+
+	var accounts []creditAccountSummary
+	w := doOrderRequest(storeRouter, http.MethodGet, "/me/credit-accounts", "")
+	json.Unmarshal(w.Body.Bytes(), &accounts)
+	if len(accounts) != 1 || accounts[0].Outstanding != 600 || accounts[0].Available != 400 {
+		t.Fatalf("unexpected credit accounts: %s", w.Body.String())
+	}
+
+	overdue := storedDeliveryDate(nowInPH().AddDate(0, 0, -40))
+	database.DB.Model(&models.Order{}).Where("id = ?", first.ID).Update("due_date", overdue)
+	var aging struct {
+		Totals agingBuckets       `json:"totals"`
+		Stores []storeReceivables `json:"stores"`
+	}
+	w = doOrderRequest(supplierRouter, http.MethodGet, "/me/receivables/aging", "")
+	json.Unmarshal(w.Body.Bytes(), &aging)
+	if aging.Totals.Days31To60 != 600 || aging.Totals.Total != 600 || len(aging.Stores) != 1 || aging.Stores[0].Orders[0].DaysOverdue != 40 {
+		t.Fatalf("unexpected aging report: %s", w.Body.String())
+	}
+
+	database.DB.Create(&models.CreditMemo{MemoNumber: "CM-000001", ReturnRequestID: 1, OrderID: first.ID, StoreID: store.ID, SupplierID: supplier.ID, Amount: 150})
+	w = doOrderRequest(storeRouter, http.MethodGet, "/me/credit-accounts", "")
+	json.Unmarshal(w.Body.Bytes(), &accounts)
+	if len(accounts) != 1 || accounts[0].Outstanding != 450 || accounts[0].Available != 550 {
+		t.Fatalf("expected the credit memo to reduce the balance: %s", w.Body.String())
+	}
+	w = doOrderRequest(supplierRouter, http.MethodGet, "/me/receivables/aging", "")
+	json.Unmarshal(w.Body.Bytes(), &aging)
+	if aging.Totals.Days31To60 != 450 || aging.Stores[0].Orders[0].Balance != 450 {
+		t.Fatalf("expected the credit memo in the aging report: %s", w.Body.String())
+	}
+
+	now := time.Now()
+	var reminded models.Order
+	unreachable := services.NewEmailService(&config.Config{SMTPHost: "127.0.0.1", SMTPPort: "1", SMTPUser: "user", SMTPPassword: "secret"})
+	for _, emailService := range []*services.EmailService{nil, unreachable} {
+		sendOverdueReminders(now, emailService)
+		database.DB.First(&reminded, first.ID)
+		if reminded.LastReminderAt != nil {
+			t.Fatalf("expected an unsent reminder to be retried, got %v", reminded.LastReminderAt)
+		}
+	}
+	emailService := services.NewEmailService(&config.Config{})
+	sendOverdueReminders(now, emailService)
+	sendOverdueReminders(now.Add(time.Hour), emailService)
+	database.DB.First(&reminded, first.ID)
+	if reminded.LastReminderAt == nil || !reminded.LastReminderAt.Equal(now) {
+		t.Fatalf("expected one reminder at %v, got %v", now, reminded.LastReminderAt)
+	}
+}
@@ -0,0 +1,487 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// deliveryQuote is the server-computed minimum, distance and fee for an order.
+type deliveryQuote struct {
+	MinimumOrderAmount    float64 `json:"minimum_order_amount"`
+	FreeDeliveryThreshold float64 `json:"free_delivery_threshold"`
+	Subtotal              float64 `json:"subtotal"`
+	DistanceKm            float64 `json:"distance_km"`
+	DeliveryFee           float64 `json:"delivery_fee"`
+	FreeDelivery          bool    `json:"free_delivery"`
+}
+
+// loadDeliverySettings returns the supplier's delivery settings, or the
+// defaults if the supplier never set any.
+func loadDeliverySettings(tx *gorm.DB, supplierID uint) (models.SupplierDeliverySettings, error) {
+	var settings models.SupplierDeliverySettings
+	err := tx.Preload("DistanceBands", func(db *gorm.DB) *gorm.DB { return db.Order("up_to_km ASC") }).
+		Preload("Zones").
+		Preload("ServiceAreaPlaces").
+		Where("supplier_id = ?", supplierID).First(&settings).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.SupplierDeliverySettings{
+			SupplierID:         supplierID,
+			MinimumOrderAmount: models.DefaultMinimumOrderAmount,
+			FeeType:            models.DeliveryFeeTypeFlat,
+			DistanceBands:      []models.DeliveryFeeBand{},
+			Zones:              []models.DeliveryFeeZone{},
+			ServiceAreaPolygon: []models.GeoPoint{},
+			ServiceAreaPlaces:  []models.ServiceAreaPlace{},
+		}, nil
+	}
+	return settings, err
+}
+
+// loadServiceAreas loads the service areas of several suppliers at once,
+// keyed by supplier ID. Suppliers without settings are missing from the map,
+// and the zero value delivers anywhere.
+func loadServiceAreas(suppliers []models.User) map[uint]models.SupplierDeliverySettings {
+	areas := make(map[uint]models.SupplierDeliverySettings, len(suppliers))
+	if len(suppliers) == 0 {
+		return areas
+	}
+	ids := make([]uint, 0, len(suppliers))
+	for _, supplier := range suppliers {
+		ids = append(ids, supplier.ID)
+	}
+
+	var settings []models.SupplierDeliverySettings
+	database.DB.Preload("ServiceAreaPlaces").Where("supplier_id IN ?", ids).Find(&settings)
+	for _, s := range settings {
+		areas[s.SupplierID] = s
+	}
+	return areas
+}
+
+// quoteDelivery works out the delivery fee and distance for an order from
+// store to supplier. Deliveries the fee schedule does not cover are refused
+// with an orderError.
+func quoteDelivery(settings models.SupplierDeliverySettings, supplier, store models.User, option models.DeliveryOption, subtotal float64) (deliveryQuote, error) {
+	quote := deliveryQuote{
+		MinimumOrderAmount:    settings.MinimumOrderAmount,
+		FreeDeliveryThreshold: settings.FreeDeliveryThreshold,
+		Subtotal:              subtotal,
+	}
+	distance, hasDistance := userDistanceKm(supplier, store)
+	quote.DistanceKm = roundTo2(distance)
+
+	if option != models.DeliveryOptionDeliver {
+		return quote, nil
+	}
+	if !deliversTo(settings, supplier, store) {
+		return quote, &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("%s does not deliver to your location. Please choose pickup instead.", supplier.Name)}
+	}
+
+	switch settings.FeeType {
+	case models.DeliveryFeeTypeDistance:
+		if !hasDistance {
+			return quote, &orderError{Code: http.StatusBadRequest, Message: "store and supplier locations are required to compute the delivery fee. Please set your location in your profile."}
+		}
+		covered := false
+		for _, band := range settings.DistanceBands {
+			if distance <= band.UpToKm {
+				quote.DeliveryFee = band.Fee
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return quote, &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("%s does not deliver as far as %.1f km", supplier.Name, distance)}
+		}
+	case models.DeliveryFeeTypeZone:
+		zone, ok := findDeliveryZone(settings.Zones, store)
+		if !ok {
+			if strings.TrimSpace(store.Barangay) == "" {
+				return quote, &orderError{Code: http.StatusBadRequest, Message: "barangay is required to compute the delivery fee. Please update your profile."}
+			}
+			return quote, &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("%s does not deliver to %s", supplier.Name, store.Barangay)}
+		}
+		quote.DeliveryFee = zone.Fee
+	default:
+		quote.DeliveryFee = settings.FlatFee
+	}
+
+	if settings.FreeDeliveryThreshold > 0 && subtotal >= settings.FreeDeliveryThreshold {
+		quote.DeliveryFee = 0
+		quote.FreeDelivery = true
+	}
+	return quote, nil
+}
+
+// deliversTo reports whether the store lies inside the supplier's service
+// area. Stores without the location data an area needs are treated as outside.
+func deliversTo(settings models.SupplierDeliverySettings, supplier, store models.User) bool {
+	switch settings.ServiceAreaType {
+	case models.ServiceAreaRadius:
+		distance, ok := userDistanceKm(supplier, store)
+		return ok && distance <= settings.ServiceRadiusKm
+	case models.ServiceAreaPolygon:
+		if store.Latitude == nil || store.Longitude == nil {
+			return false
+		}
+		return pointInPolygon(*store.Latitude, *store.Longitude, settings.ServiceAreaPolygon)
+	case models.ServiceAreaNamed:
+		barangay := strings.TrimSpace(store.Barangay)
+		municipality := strings.TrimSpace(store.Municipality)
+		for _, place := range settings.ServiceAreaPlaces {
+			if place.Municipality != "" && !strings.EqualFold(place.Municipality, municipality) {
+				continue
+			}
+			if place.Barangay != "" && !strings.EqualFold(place.Barangay, barangay) {
+				continue
+			}
+			return true
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+func findDeliveryZone(zones []models.DeliveryFeeZone, store models.User) (models.DeliveryFeeZone, bool) {
+	barangay := strings.TrimSpace(store.Barangay)
+	municipality := strings.TrimSpace(store.Municipality)
+	for _, zone := range zones {
+		if !strings.EqualFold(zone.Barangay, barangay) {
+			continue
+		}
+		if zone.Municipality != "" && !strings.EqualFold(zone.Municipality, municipality) {
+			continue
+		}
+		return zone, true
+	}
+	return models.DeliveryFeeZone{}, false
+}
+
+func GetMyDeliverySettings(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	role, _ := c.Get("role")
+	if role != "supplier" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only suppliers have delivery settings"})
+		return
+	}
+
+	settings, err := loadDeliverySettings(database.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch delivery settings"})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+func GetSupplierDeliverySettings(c *gin.Context) {
+	var supplier models.User
+	if err := database.DB.Where("id = ? AND role = ?", c.Param("id"), "supplier").First(&supplier).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "supplier not found"})
+		return
+	}
+
+	settings, err := loadDeliverySettings(database.DB, supplier.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch delivery settings"})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+func UpdateMyDeliverySettings(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	empCtx := getEmployeeContext(c)
+	if empCtx.IsEmployee {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the supplier owner can change delivery settings"})
+		return
+	}
+	role, _ := c.Get("role")
+	if role != "supplier" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only suppliers have delivery settings"})
+		return
+	}
+
+	var req struct {
+		MinimumOrderAmount    *float64 `json:"minimum_order_amount"`
+		FreeDeliveryThreshold float64  `json:"free_delivery_threshold"`
+		FeeType               string   `json:"fee_type" binding:"required"`
+		FlatFee               float64  `json:"flat_fee"`
+		DistanceBands         []struct {
+			UpToKm float64 `json:"up_to_km"`
+			Fee    float64 `json:"fee"`
+		} `json:"distance_bands"`
+		Zones []struct {
+			Barangay     string  `json:"barangay"`
+			Municipality string  `json:"municipality"`
+			Fee          float64 `json:"fee"`
+		} `json:"zones"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := loadDeliverySettings(database.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch delivery settings"})
+		return
+	}
+
+	if req.MinimumOrderAmount != nil {
+		settings.MinimumOrderAmount = *req.MinimumOrderAmount
+	}
+	if settings.MinimumOrderAmount < 0 || req.FreeDeliveryThreshold < 0 || req.FlatFee < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amounts cannot be negative"})
+		return
+	}
+	settings.FreeDeliveryThreshold = req.FreeDeliveryThreshold
+	settings.FlatFee = req.FlatFee
+	settings.FeeType = models.DeliveryFeeType(req.FeeType)
+
+	bands := make([]models.DeliveryFeeBand, 0, len(req.DistanceBands))
+	for _, band := range req.DistanceBands {
+		if band.UpToKm <= 0 || band.Fee < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "distance bands need a positive up_to_km and a fee of zero or more"})
+			return
+		}
+		bands = append(bands, models.DeliveryFeeBand{UpToKm: band.UpToKm, Fee: band.Fee})
+	}
+	sort.Slice(bands, func(i, j int) bool { return bands[i].UpToKm < bands[j].UpToKm })
+
+	zones := make([]models.DeliveryFeeZone, 0, len(req.Zones))
+	for _, zone := range req.Zones {
+		if strings.TrimSpace(zone.Barangay) == "" || zone.Fee < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "delivery zones need a barangay and a fee of zero or more"})
+			return
+		}
+		zones = append(zones, models.DeliveryFeeZone{
+			Barangay:     strings.TrimSpace(zone.Barangay),
+			Municipality: strings.TrimSpace(zone.Municipality),
+			Fee:          zone.Fee,
+		})
+	}
+
+	switch settings.FeeType {
+	case models.DeliveryFeeTypeFlat:
+	case models.DeliveryFeeTypeDistance:
+		if len(bands) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "distance fees need at least one band"})
+			return
+		}
+	case models.DeliveryFeeTypeZone:
+		if len(zones) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "zone fees need at least one zone"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "fee_type must be flat, distance or zone"})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("DistanceBands", "Zones", "ServiceAreaPlaces", "Supplier").Save(&settings).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("settings_id = ?", settings.ID).Delete(&models.DeliveryFeeBand{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("settings_id = ?", settings.ID).Delete(&models.DeliveryFeeZone{}).Error; err != nil {
+			return err
+		}
+		for i := range bands {
+			bands[i].SettingsID = settings.ID
+		}
+		for i := range zones {
+			zones[i].SettingsID = settings.ID
+		}
+		if len(bands) > 0 {
+			if err := tx.Create(&bands).Error; err != nil {
+				return err
+			}
+		}
+		if len(zones) > 0 {
+			if err := tx.Create(&zones).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update delivery settings", "details": err.Error()})
+		return
+	}
+
+	settings, _ = loadDeliverySettings(database.DB, userID)
+	c.JSON(http.StatusOK, settings)
+}
+
+func UpdateMyServiceArea(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	empCtx := getEmployeeContext(c)
+	if empCtx.IsEmployee {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the supplier owner can change the service area"})
+		return
+	}
+	role, _ := c.Get("role")
+	if role != "supplier" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only suppliers have a service area"})
+		return
+	}
+
+	var req struct {
+		Type     string            `json:"type"`
+		RadiusKm float64           `json:"radius_km"`
+		Polygon  []models.GeoPoint `json:"polygon"`
+		Places   []struct {
+			Barangay     string `json:"barangay"`
+			Municipality string `json:"municipality"`
+		} `json:"places"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := loadDeliverySettings(database.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch delivery settings"})
+		return
+	}
+
+	settings.ServiceAreaType = models.ServiceAreaType(req.Type)
+	settings.ServiceRadiusKm = 0
+	settings.ServiceAreaPolygon = []models.GeoPoint{}
+	places := make([]models.ServiceAreaPlace, 0, len(req.Places))
+
+	switch settings.ServiceAreaType {
+	case models.ServiceAreaAnywhere:
+	case models.ServiceAreaRadius:
+		var supplier models.User
+		if err := database.DB.First(&supplier, userID).Error; err != nil || supplier.Latitude == nil || supplier.Longitude == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "set your location before using a delivery radius"})
+			return
+		}
+		if req.RadiusKm <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "radius_km must be greater than zero"})
+			return
+		}
+		settings.ServiceRadiusKm = req.RadiusKm
+	case models.ServiceAreaPolygon:
+		if len(req.Polygon) < 3 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "a polygon needs at least three points"})
+			return
+		}
+		for _, p := range req.Polygon {
+			if p.Lat < -90 || p.Lat > 90 || p.Lng < -180 || p.Lng > 180 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "polygon points must be valid coordinates"})
+				return
+			}
+		}
+		settings.ServiceAreaPolygon = req.Polygon
+	case models.ServiceAreaNamed:
+		for _, place := range req.Places {
+			barangay := strings.TrimSpace(place.Barangay)
+			municipality := strings.TrimSpace(place.Municipality)
+			if barangay == "" && municipality == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "each place needs a barangay or a municipality"})
+				return
+			}
+			places = append(places, models.ServiceAreaPlace{Barangay: barangay, Municipality: municipality})
+		}
+		if len(places) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "list at least one barangay or municipality"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be empty, radius, polygon or named"})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("DistanceBands", "Zones", "ServiceAreaPlaces", "Supplier").Save(&settings).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("settings_id = ?", settings.ID).Delete(&models.ServiceAreaPlace{}).Error; err != nil {
+			return err
+		}
+		for i := range places {
+			places[i].SettingsID = settings.ID
+		}
+		if len(places) > 0 {
+			return tx.Create(&places).Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update service area", "details": err.Error()})
+		return
+	}
+
+	settings, _ = loadDeliverySettings(database.DB, userID)
+	c.JSON(http.StatusOK, settings)
+}
+
+// GetDeliveryQuote previews the minimum order and delivery fee for a draft.
+func GetDeliveryQuote(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	role, _ := c.Get("role")
+	if role != "store" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only stores can request delivery quotes"})
+		return
+	}
+
+	var order models.Order
+	if err := database.DB.Preload("Store").Preload("Supplier").
+		Where("id = ? AND store_id = ? AND status = ?", c.Param("id"), userID, models.OrderStatusDraft).
+		First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "draft order not found"})
+		return
+	}
+
+	option := models.DeliveryOption(c.DefaultQuery("delivery_option", string(models.DeliveryOptionDeliver)))
+	if option != models.DeliveryOptionDeliver && option != models.DeliveryOptionPickup {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delivery option"})
+		return
+	}
+
+	var subtotal float64
+	database.DB.Model(&models.OrderItem{}).Where("order_id = ?", order.ID).Select("COALESCE(SUM(subtotal), 0)").Scan(&subtotal)
+
+	settings, err := loadDeliverySettings(database.DB, order.SupplierID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch delivery settings"})
+		return
+	}
+	quote, err := quoteDelivery(settings, order.Supplier, order.Store, option, subtotal)
+	if err != nil {
+		writeOrderError(c, err, "failed to compute delivery fee")
+		return
+	}
+	c.JSON(http.StatusOK, quote)
+}
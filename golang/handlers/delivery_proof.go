@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"siargao-trading-road/config"
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/gin-gonic/gin"
+	"github.com/jung-kurt/gofpdf"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ConfirmDelivery marks an in-transit order delivered together with its
+// proof of delivery: who received it, their signature, photos and where and
+// when it was handed over. Drivers may confirm the orders assigned to them
+// without holding the order permissions.
+func ConfirmDelivery(c *gin.Context) {
+	id := c.Param("id")
+	userID, _ := getUserID(c)
+	role, _ := c.Get("role")
+	empCtx := getEmployeeContext(c)
+	driverID, isDriver := supplierDriverID(c, empCtx)
+	if !isDriver {
+		if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+			return
+		}
+		if !ensureEmployeePermission(c, empCtx.CanChangeStatus, "change_status") {
+			return
+		}
+	}
+
+	var req struct {
+		ReceiverName string     `json:"receiver_name" binding:"required"`
+		SignatureURL string     `json:"signature_url" binding:"required"`
+		PhotoURLs    []string   `json:"photo_urls"`
+		Latitude     *float64   `json:"latitude"`
+		Longitude    *float64   `json:"longitude"`
+		DeliveredAt  *time.Time `json:"delivered_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "latitude and longitude must be given together"})
+		return
+	}
+	if req.Latitude != nil && !validCoordinates(*req.Latitude, *req.Longitude) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid coordinates"})
+		return
+	}
+	deliveredAt := time.Now()
+	if req.DeliveredAt != nil {
+		// Proof captured offline is uploaded later, but never from the future.
+		if req.DeliveredAt.After(deliveredAt.Add(5 * time.Minute)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "delivered_at cannot be in the future"})
+			return
+		}
+		deliveredAt = *req.DeliveredAt
+	}
+	// Only files uploaded to our bucket are kept; the invoice fetches the
+	// signature later and must never be pointed at another host.
+	cfgVal, _ := c.Get("config")
+	cfg, _ := cfgVal.(*config.Config)
+	signatureKey, ok := bucketObjectKey(cfg, req.SignatureURL)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "signature_url must be an uploaded file"})
+		return
+	}
+	signatureURL := bucketObjectURL(cfg, signatureKey)
+	photoURLs := make([]string, 0, len(req.PhotoURLs))
+	for _, photo := range req.PhotoURLs {
+		key, ok := bucketObjectKey(cfg, photo)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "photo_urls must be uploaded files"})
+			return
+		}
+		photoURLs = append(photoURLs, bucketObjectURL(cfg, key))
+	}
+
+	actor := getOrderActor(c)
+	var order models.Order
+	var transition models.OrderTransition
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id)
+		if role == "supplier" {
+			query = query.Where("supplier_id = ?", userID)
+		}
+		if isDriver {
+			query = query.Where("driver_id = ?", driverID)
+		}
+		if err := query.First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &orderError{Code: http.StatusNotFound, Message: "order not found"}
+			}
+			return err
+		}
+
+		var err error
+		transition, err = transitionOrder(tx, &order, models.OrderStatusDelivered, actor, "", false)
+		if err != nil {
+			return err
+		}
+
+		proof := models.DeliveryProof{
+			OrderID:      order.ID,
+			ReceiverName: strings.TrimSpace(req.ReceiverName),
+			SignatureURL: signatureURL,
+			PhotoURLs:    photoURLs,
+			Latitude:     req.Latitude,
+			Longitude:    req.Longitude,
+			DeliveredAt:  deliveredAt,
+			RecordedByID: actor.userIDPtr(),
+			EmployeeID:   actor.EmployeeID,
+		}
+		if err := tx.Create(&proof).Error; err != nil {
+			return err
+		}
+		// The invoice is regenerated so it carries the proof.
+		return tx.Model(&models.Order{}).Where("id = ?", order.ID).Update("invoice_url", "").Error
+	})
+	if err != nil {
+		writeOrderError(c, err, "failed to confirm delivery")
+		return
+	}
+
+	if err := database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product").Preload("DeliveryProof").First(&order, order.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load order details"})
+		return
+	}
+
+	runOrderNotifications(getEmailService(c), order, models.OrderStatusInTransit, transition)
+
+	c.JSON(http.StatusOK, order)
+}
+
+// writeDeliveryProofPDF adds the proof of delivery block to an invoice. The
+// signature is embedded when it can be fetched from the bucket, otherwise its
+// link is shown.
+func writeDeliveryProofPDF(pdf *gofpdf.Fpdf, cfg *config.Config, proof *models.DeliveryProof) {
+	if proof == nil {
+		return
+	}
+
+	pdf.Ln(6)
+	pdf.SetFont("Arial", "B", 12)
+	pdf.CellFormat(0, 8, "Proof of Delivery", "", 1, "L", false, 0, "")
+	pdf.SetFont("Arial", "", 10)
+	pdf.CellFormat(0, 6, fmt.Sprintf("Received by: %s", proof.ReceiverName), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, fmt.Sprintf("Delivered at: %s", proof.DeliveredAt.In(philippineTZ).Format("2006-01-02 15:04")), "", 1, "L", false, 0, "")
+	if proof.Latitude != nil && proof.Longitude != nil {
+		pdf.CellFormat(0, 6, fmt.Sprintf("Location: %.6f, %.6f", *proof.Latitude, *proof.Longitude), "", 1, "L", false, 0, "")
+	}
+	if len(proof.PhotoURLs) > 0 {
+		pdf.CellFormat(0, 6, fmt.Sprintf("Photos: %d on file", len(proof.PhotoURLs)), "", 1, "L", false, 0, "")
+	}
+
+	if data, imageType, err := fetchBucketImage(cfg, proof.SignatureURL); err == nil {
+		name := fmt.Sprintf("signature-%d", proof.ID)
+		if info := pdf.RegisterImageOptionsReader(name, gofpdf.ImageOptions{ImageType: imageType}, bytes.NewReader(data)); info != nil && pdf.Ok() {
+			pdf.CellFormat(0, 6, "Signature:", "", 1, "L", false, 0, "")
+			pdf.ImageOptions(name, pdf.GetX(), pdf.GetY(), 50, 0, true, gofpdf.ImageOptions{ImageType: imageType}, 0, "")
+			return
+		}
+		pdf.ClearError()
+	}
+	pdf.CellFormat(0, 6, fmt.Sprintf("Signature: %s", proof.SignatureURL), "", 1, "L", false, 0, "")
+}
+
+// maxProofImageSize caps how much of a proof image is read into memory.
+const maxProofImageSize = 5 << 20
+
+// bucketObjectKey returns the object key of an upload in the configured S3
+// bucket, given either the key or the URL the upload endpoint returned for it.
+// URLs on any other host are refused.
+func bucketObjectKey(cfg *config.Config, ref string) (string, bool) {
+	if cfg == nil || cfg.S3Bucket == "" || cfg.AWSRegion == "" {
+		return "", false
+	}
+	key := strings.TrimSpace(ref)
+	if strings.Contains(key, "://") {
+		u, err := url.Parse(key)
+		if err != nil || u.Scheme != "https" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+			return "", false
+		}
+		if !strings.EqualFold(u.Host, fmt.Sprintf("%s.s3.%s.amazonaws.com", cfg.S3Bucket, cfg.AWSRegion)) {
+			return "", false
+		}
+		key = strings.TrimPrefix(u.Path, "/")
+	}
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "..") || strings.ContainsAny(key, "?#\\:") {
+		return "", false
+	}
+	return key, true
+}
+
+// bucketObjectURL is the public URL of an object in the configured bucket, in
+// the form the upload endpoint returns.
+func bucketObjectURL(cfg *config.Config, key string) string {
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", cfg.S3Bucket, cfg.AWSRegion, key)
+}
+
+// fetchBucketImage reads a PNG or JPEG from the configured bucket for
+// embedding in a PDF. Only objects in the bucket are fetched, through the S3
+// client, and anything over maxProofImageSize is refused.
+func fetchBucketImage(cfg *config.Config, ref string) ([]byte, string, error) {
+	key, ok := bucketObjectKey(cfg, ref)
+	if !ok {
+		return nil, "", fmt.Errorf("%s is not in the upload bucket", ref)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	svc, err := newS3Client(ctx, cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	out, err := svc.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(cfg.S3Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("get object %s: %w", key, err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(out.Body, maxProofImageSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxProofImageSize {
+		return nil, "", fmt.Errorf("get object %s: larger than %d bytes", key, maxProofImageSize)
+	}
+	switch http.DetectContentType(data) {
+	case "image/png":
+		return data, "PNG", nil
+	case "image/jpeg":
+		return data, "JPG", nil
+	}
+	return nil, "", fmt.Errorf("get object %s: unsupported image type", key)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"siargao-trading-road/config"
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupDeliveryProofTestDB(t *testing.T) (models.User, models.User) {
+	t.Helper()
+	store, supplier := setupOrderTestDB(t)
+	migrateTestModels(t, &models.DeliveryProof{})
+	return store, supplier
+}
+
+// proofTestConfig points uploads at a bucket so proof URLs can be checked.
+var proofTestConfig = &config.Config{S3Bucket: "road-uploads", AWSRegion: "ap-southeast-1"}
+
+func buildDeliveryProofRouter(user models.User) *gin.Engine {
+	r := buildOrderRouter(user)
+	r.POST("/orders/:id/deliver", func(c *gin.Context) { c.Set("config", proofTestConfig) }, ConfirmDelivery)
+	return r
+}
+
+func TestConfirmDeliveryRequiresProof(t *testing.T) {
+	store, supplier := setupDeliveryProofTestDB(t)
+	order := createTestOrder(t, store, supplier, models.OrderStatusInTransit)
+	database.DB.Model(&order).Update("invoice_url", "https://example.com/old.pdf")
+	router := buildDeliveryProofRouter(supplier)
+
+	if w := putOrderStatus(router, order.ID, `{"status":"delivered"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected delivering without proof to be refused, got %d", w.Code)
+	}
+	path := fmt.Sprintf("/orders/%d/deliver", order.ID)
+	if w := doOrderRequest(router, http.MethodPost, path, `{"receiver_name":"Ana"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected missing signature to be refused, got %d", w.Code)
+	}
+	if w := doOrderRequest(buildDeliveryProofRouter(store), http.MethodPost, path, `{"receiver_name":"Ana","signature_url":"uploads/supplier/2/sig.png"}`); w.Code != http.StatusForbidden {
+		t.Fatalf("expected store to be refused, got %d", w.Code)
+	}
+
+	foreign := []string{
+		`{"receiver_name":"Ana","signature_url":"https://example.com/sig.png"}`,
+		`{"receiver_name":"Ana","signature_url":"http://169.254.169.254/latest/meta-data"}`,
+		`{"receiver_name":"Ana","signature_url":"https://road-uploads.s3.ap-southeast-1.amazonaws.com.example.com/sig.png"}`,
+		`{"receiver_name":"Ana","signature_url":"uploads/../sig.png"}`,
+		`{"receiver_name":"Ana","signature_url":"uploads/supplier/2/sig.png","photo_urls":["https://example.com/door.jpg"]}`,
+	}
+	for _, body := range foreign {
+		if w := doOrderRequest(router, http.MethodPost, path, body); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected %s to be refused, got %d", body, w.Code)
+		}
+	}
+
+	body := `{"receiver_name":"Ana Cruz","signature_url":"uploads/supplier/2/sig.png","photo_urls":["https://road-uploads.s3.ap-southeast-1.amazonaws.com/uploads/supplier/2/door.jpg"],"latitude":9.78,"longitude":126.16}`
+	w := doOrderRequest(router, http.MethodPost, path, body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var delivered models.Order
+	json.Unmarshal(w.Body.Bytes(), &delivered)
+	if delivered.Status != models.OrderStatusDelivered || delivered.DeliveryProof == nil {
+		t.Fatalf("unexpected order: %s, proof %v", delivered.Status, delivered.DeliveryProof)
+	}
+	proof := delivered.DeliveryProof
+	if proof.ReceiverName != "Ana Cruz" || len(proof.PhotoURLs) != 1 || proof.Latitude == nil || *proof.Latitude != 9.78 {
+		t.Fatalf("unexpected proof: %+v", proof)
+	}
+	if proof.SignatureURL != "https://road-uploads.s3.ap-southeast-1.amazonaws.com/uploads/supplier/2/sig.png" {
+		t.Fatalf("expected signature key to be stored as its bucket URL, got %q", proof.SignatureURL)
+	}
+	if delivered.InvoiceURL != "" {
+		t.Fatalf("expected cached invoice to be cleared, got %q", delivered.InvoiceURL)
+	}
+
+	var history models.OrderStatusHistory
+	database.DB.Where("order_id = ? AND to_status = ?", order.ID, models.OrderStatusDelivered).First(&history)
+	if history.ID == 0 {
+		t.Fatalf("expected delivered status history")
+	}
+
+	if w := doOrderRequest(router, http.MethodPost, path, body); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected second confirmation to be refused, got %d", w.Code)
+	}
+}
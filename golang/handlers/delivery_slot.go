@@ -0,0 +1,443 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Delivery dates are calendar days in Philippine time. They are stored as
+// UTC midnight so DATE(delivery_date) gives the same day on every database,
+// matching how schedule exceptions are stored.
+
+const dateLayout = "2006-01-02"
+
+type deliverySlotRequest struct {
+	Weekday   *int   `json:"weekday" binding:"required,min=0,max=6"`
+	StartTime string `json:"start_time" binding:"required"`
+	EndTime   string `json:"end_time" binding:"required"`
+	Capacity  int    `json:"capacity" binding:"required,min=1"`
+	Active    *bool  `json:"active"`
+}
+
+// slotAvailability is one bookable date of a delivery slot.
+type slotAvailability struct {
+	Date      string              `json:"date"`
+	Slot      models.DeliverySlot `json:"slot"`
+	Booked    int                 `json:"booked"`
+	Remaining int                 `json:"remaining"`
+}
+
+// slotGroup collects the orders to deliver in one slot on one date.
+type slotGroup struct {
+	Date     string              `json:"date"`
+	Slot     models.DeliverySlot `json:"slot"`
+	Capacity int                 `json:"capacity"`
+	Orders   []models.Order      `json:"orders"`
+}
+
+func parseDeliveryDate(value string) (time.Time, error) {
+	return time.ParseInLocation(dateLayout, value, philippineTZ)
+}
+
+// storedDeliveryDate is the value saved in orders.delivery_date for a day.
+func storedDeliveryDate(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func validateSlotTimes(start, end string) error {
+	startTime, err := time.Parse("15:04", start)
+	if err != nil {
+		return fmt.Errorf("start_time must be HH:MM")
+	}
+	endTime, err := time.Parse("15:04", end)
+	if err != nil {
+		return fmt.Errorf("end_time must be HH:MM")
+	}
+	if !endTime.After(startTime) {
+		return fmt.Errorf("end_time must be after start_time")
+	}
+	return nil
+}
+
+// bookedSlotOrders counts the live orders booked into a slot on a day,
+// ignoring excludeOrderID.
+func bookedSlotOrders(tx *gorm.DB, slotID uint, day time.Time, excludeOrderID uint) (int64, error) {
+	var count int64
+	err := tx.Model(&models.Order{}).
+		Where("delivery_slot_id = ? AND DATE(delivery_date) = ? AND id != ? AND status NOT IN ?",
+			slotID, day.Format(dateLayout), excludeOrderID, []models.OrderStatus{models.OrderStatusDraft, models.OrderStatusCancelled}).
+		Count(&count).Error
+	return count, err
+}
+
+// applyDeliverySlot checks a requested slot and date for a delivery and
+// records them on order. The slot row is locked so two orders cannot take the
+// last place in it.
+func applyDeliverySlot(tx *gorm.DB, order *models.Order, slotID *uint, date string, now time.Time) error {
+	if slotID == nil && date == "" {
+		return nil
+	}
+	if slotID == nil || date == "" {
+		return &orderError{Code: http.StatusBadRequest, Message: "delivery_slot_id and delivery_date must be given together"}
+	}
+	if order.DeliveryOption != models.DeliveryOptionDeliver {
+		return &orderError{Code: http.StatusBadRequest, Message: "delivery slots only apply to orders for delivery"}
+	}
+
+	day, err := parseDeliveryDate(date)
+	if err != nil {
+		return &orderError{Code: http.StatusBadRequest, Message: "delivery_date must be YYYY-MM-DD"}
+	}
+
+	var slot models.DeliverySlot
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND supplier_id = ? AND active = ?", *slotID, order.SupplierID, true).
+		First(&slot).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &orderError{Code: http.StatusBadRequest, Message: "delivery slot not found"}
+		}
+		return err
+	}
+	if int(day.Weekday()) != slot.Weekday {
+		return &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("this slot is only available on %s", time.Weekday(slot.Weekday))}
+	}
+	if start, ok := parseTimeToday(slot.StartTime, day); ok && !start.After(now) {
+		return &orderError{Code: http.StatusBadRequest, Message: "this delivery slot has already started"}
+	}
+
+	var supplier models.User
+	if err := tx.First(&supplier, order.SupplierID).Error; err != nil {
+		return err
+	}
+	if isSupplierClosedOn(tx, supplier, day) {
+		return &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("supplier is closed on %s", date)}
+	}
+
+	booked, err := bookedSlotOrders(tx, slot.ID, day, order.ID)
+	if err != nil {
+		return err
+	}
+	if booked >= int64(slot.Capacity) {
+		return &orderError{Code: http.StatusConflict, Message: "this delivery slot is full, please choose another"}
+	}
+
+	deliveryDate := storedDeliveryDate(day)
+	order.DeliverySlotID = &slot.ID
+	order.DeliveryDate = &deliveryDate
+	return nil
+}
+
+func GetMyDeliverySlots(c *gin.Context) {
+	userID, ok := requireSupplier(c)
+	if !ok {
+		return
+	}
+
+	var slots []models.DeliverySlot
+	if err := database.DB.Where("supplier_id = ?", userID).Order("weekday ASC, start_time ASC").Find(&slots).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch delivery slots"})
+		return
+	}
+	c.JSON(http.StatusOK, slots)
+}
+
+func CreateDeliverySlot(c *gin.Context) {
+	userID, ok := requireSupplier(c)
+	if !ok {
+		return
+	}
+
+	var req deliverySlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateSlotTimes(req.StartTime, req.EndTime); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slot := models.DeliverySlot{
+		SupplierID: userID,
+		Weekday:    *req.Weekday,
+		StartTime:  req.StartTime,
+		EndTime:    req.EndTime,
+		Capacity:   req.Capacity,
+		Active:     req.Active == nil || *req.Active,
+	}
+	// gorm replaces false with the schema default of true on insert, so
+	// active is written again once the row exists.
+	active := slot.Active
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&slot).Error; err != nil {
+			return err
+		}
+		return tx.Model(&slot).Update("active", active).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create delivery slot"})
+		return
+	}
+	c.JSON(http.StatusCreated, slot)
+}
+
+// UpdateDeliverySlot changes a slot. Orders already booked into it keep
+// their place even if the new capacity is lower.
+func UpdateDeliverySlot(c *gin.Context) {
+	userID, ok := requireSupplier(c)
+	if !ok {
+		return
+	}
+
+	var slot models.DeliverySlot
+	if err := database.DB.Where("id = ? AND supplier_id = ?", c.Param("id"), userID).First(&slot).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "delivery slot not found"})
+		return
+	}
+
+	var req deliverySlotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateSlotTimes(req.StartTime, req.EndTime); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{
+		"weekday":    *req.Weekday,
+		"start_time": req.StartTime,
+		"end_time":   req.EndTime,
+		"capacity":   req.Capacity,
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+	if err := database.DB.Model(&slot).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update delivery slot"})
+		return
+	}
+
+	database.DB.First(&slot, slot.ID)
+	c.JSON(http.StatusOK, slot)
+}
+
+func DeleteDeliverySlot(c *gin.Context) {
+	userID, ok := requireSupplier(c)
+	if !ok {
+		return
+	}
+
+	result := database.DB.Where("id = ? AND supplier_id = ?", c.Param("id"), userID).Delete(&models.DeliverySlot{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete delivery slot"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "delivery slot not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "delivery slot deleted"})
+}
+
+// GetSupplierDeliverySlots lists the supplier's bookable slot dates from
+// ?from= (default today) for ?days= days, skipping closed days and slots that
+// have already started.
+func GetSupplierDeliverySlots(c *gin.Context) {
+	var supplier models.User
+	if err := database.DB.Where("id = ? AND role = ?", c.Param("id"), "supplier").First(&supplier).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "supplier not found"})
+		return
+	}
+
+	now := nowInPH()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, philippineTZ)
+	if value := c.Query("from"); value != "" {
+		parsed, err := parseDeliveryDate(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be YYYY-MM-DD"})
+			return
+		}
+		if parsed.After(from) {
+			from = parsed
+		}
+	}
+	days := 14
+	if value := c.Query("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 60 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 60"})
+			return
+		}
+		days = parsed
+	}
+
+	var slots []models.DeliverySlot
+	database.DB.Where("supplier_id = ? AND active = ?", supplier.ID, true).Order("start_time ASC").Find(&slots)
+
+	availability := []slotAvailability{}
+	for i := 0; i < days; i++ {
+		day := from.AddDate(0, 0, i)
+		if isSupplierClosedOn(database.DB, supplier, day) {
+			continue
+		}
+		for _, slot := range slots {
+			if slot.Weekday != int(day.Weekday()) {
+				continue
+			}
+			if start, ok := parseTimeToday(slot.StartTime, day); ok && !start.After(now) {
+				continue
+			}
+			booked, err := bookedSlotOrders(database.DB, slot.ID, day, 0)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch delivery slots"})
+				return
+			}
+			remaining := slot.Capacity - int(booked)
+			if remaining < 0 {
+				remaining = 0
+			}
+			availability = append(availability, slotAvailability{
+				Date:      day.Format(dateLayout),
+				Slot:      slot,
+				Booked:    int(booked),
+				Remaining: remaining,
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, availability)
+}
+
+// GetMyOrdersBySlot groups the supplier's open orders by delivery date and
+// slot between ?from= and ?to= (default the next seven days), for planning
+// truck runs. Slots with no orders are included; orders without a slot are
+// listed separately.
+func GetMyOrdersBySlot(c *gin.Context) {
+	userID, ok := requireSupplier(c)
+	if !ok {
+		return
+	}
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+
+	now := nowInPH()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, philippineTZ)
+	if value := c.Query("from"); value != "" {
+		parsed, err := parseDeliveryDate(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be YYYY-MM-DD"})
+			return
+		}
+		from = parsed
+	}
+	to := from.AddDate(0, 0, 6)
+	if value := c.Query("to"); value != "" {
+		parsed, err := parseDeliveryDate(value)
+		if err != nil || parsed.Before(from) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be YYYY-MM-DD and not before from"})
+			return
+		}
+		to = parsed
+	}
+	if to.Sub(from) > 62*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date range cannot exceed 62 days"})
+		return
+	}
+
+	openStatuses := []models.OrderStatus{models.OrderStatusPreparing, models.OrderStatusInTransit}
+	var orders []models.Order
+	if err := database.DB.Preload("Store").Preload("OrderItems").Preload("OrderItems.Product").
+		Where("supplier_id = ? AND status IN ? AND delivery_slot_id IS NOT NULL AND DATE(delivery_date) BETWEEN ? AND ?",
+			userID, openStatuses, from.Format(dateLayout), to.Format(dateLayout)).
+		Order("id ASC").Find(&orders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch orders"})
+		return
+	}
+
+	var unscheduled []models.Order
+	if err := database.DB.Preload("Store").Preload("OrderItems").Preload("OrderItems.Product").
+		Where("supplier_id = ? AND status IN ? AND delivery_option = ? AND delivery_slot_id IS NULL", userID, openStatuses, models.DeliveryOptionDeliver).
+		Order("id ASC").Find(&unscheduled).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch orders"})
+		return
+	}
+
+	var slots []models.DeliverySlot
+	database.DB.Unscoped().Where("supplier_id = ?", userID).Find(&slots)
+	slotsByID := make(map[uint]models.DeliverySlot, len(slots))
+	for _, slot := range slots {
+		slotsByID[slot.ID] = slot
+	}
+
+	groups := make(map[string]*slotGroup)
+	key := func(date string, slotID uint) string { return fmt.Sprintf("%s/%d", date, slotID) }
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		for _, slot := range slots {
+			if slot.Active && !slot.DeletedAt.Valid && slot.Weekday == int(day.Weekday()) {
+				date := day.Format(dateLayout)
+				groups[key(date, slot.ID)] = &slotGroup{Date: date, Slot: slot, Capacity: slot.Capacity, Orders: []models.Order{}}
+			}
+		}
+	}
+	for _, order := range orders {
+		date := order.DeliveryDate.UTC().Format(dateLayout)
+		k := key(date, *order.DeliverySlotID)
+		group, ok := groups[k]
+		if !ok {
+			slot := slotsByID[*order.DeliverySlotID]
+			group = &slotGroup{Date: date, Slot: slot, Capacity: slot.Capacity, Orders: []models.Order{}}
+			groups[k] = group
+		}
+		group.Orders = append(group.Orders, order)
+	}
+
+	result := make([]slotGroup, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Date != result[j].Date {
+			return result[i].Date < result[j].Date
+		}
+		return result[i].Slot.StartTime < result[j].Slot.StartTime
+	})
+	if unscheduled == nil {
+		unscheduled = []models.Order{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":        from.Format(dateLayout),
+		"to":          to.Format(dateLayout),
+		"slots":       result,
+		"unscheduled": unscheduled,
+	})
+}
+
+func requireSupplier(c *gin.Context) (uint, bool) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return 0, false
+	}
+	role, _ := c.Get("role")
+	if role != "supplier" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only suppliers can access this resource"})
+		return 0, false
+	}
+	return userID, true
+}
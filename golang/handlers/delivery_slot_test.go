@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupDeliverySlotTestDB(t *testing.T) (models.User, models.User) {
+	t.Helper()
+	store, supplier := setupOrderTestDB(t)
+	migrateTestModels(t, &models.StockReservation{}, &models.SupplierDeliverySettings{}, &models.DeliveryFeeBand{}, &models.DeliveryFeeZone{}, &models.ServiceAreaPlace{}, &models.ScheduleException{}, &models.DeliverySlot{})
+	return store, supplier
+}
+
+func buildDeliverySlotRouter(user models.User) *gin.Engine {
+	r := buildOrderRouter(user)
+	r.POST("/orders/:id/items", AddOrderItem)
+	r.POST("/orders/:id/submit", SubmitOrder)
+	r.POST("/me/delivery-slots", CreateDeliverySlot)
+	r.GET("/me/orders/by-slot", GetMyOrdersBySlot)
+	return r
+}
+
+func TestSubmitOrderBooksDeliverySlot(t *testing.T) {
+	store, supplier := setupDeliverySlotTestDB(t)
+	otherStore := models.User{Email: "other@example.com", Password: "x", Name: "Other", Role: models.RoleStore, Address: "Dapa"}
+	database.DB.Create(&otherStore)
+	database.DB.Model(&store).Update("address", "General Luna")
+	database.DB.Create(&models.SupplierDeliverySettings{SupplierID: supplier.ID, MinimumOrderAmount: 100})
+	product := models.Product{SupplierID: supplier.ID, Name: "Rice", SKU: "RICE-1", Price: 100, StockQuantity: 50}
+	database.DB.Create(&product)
+
+	day := nowInPH().AddDate(0, 0, 3)
+	slot := models.DeliverySlot{SupplierID: supplier.ID, Weekday: int(day.Weekday()), StartTime: "08:00", EndTime: "12:00", Capacity: 1, Active: true}
+	database.DB.Create(&slot)
+	closedDay := day.AddDate(0, 0, 7)
+	database.DB.Create(&models.ScheduleException{UserID: supplier.ID, Date: storedDeliveryDate(closedDay), IsClosed: true})
+
+	submit := func(user models.User, date string) (int, models.Order) {
+		draft := createTestOrder(t, user, supplier, models.OrderStatusDraft)
+		router := buildDeliverySlotRouter(user)
+		doOrderRequest(router, http.MethodPost, fmt.Sprintf("/orders/%d/items", draft.ID), fmt.Sprintf(`{"product_id":%d,"quantity":2}`, product.ID))
+		body := fmt.Sprintf(`{"payment_method":"cash_on_delivery","delivery_option":"deliver","delivery_slot_id":%d,"delivery_date":%q}`, slot.ID, date)
+		w := doOrderRequest(router, http.MethodPost, fmt.Sprintf("/orders/%d/submit", draft.ID), body)
+		var order models.Order
+		json.Unmarshal(w.Body.Bytes(), &order)
+		return w.Code, order
+	}
+
+	if code, _ := submit(store, day.AddDate(0, 0, 1).Format(dateLayout)); code != http.StatusBadRequest {
+		t.Fatalf("wrong weekday: expected 400, got %d", code)
+	}
+	if code, _ := submit(store, closedDay.Format(dateLayout)); code != http.StatusBadRequest {
+		t.Fatalf("closed day: expected 400, got %d", code)
+	}
+
+	code, order := submit(store, day.Format(dateLayout))
+	if code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	if order.DeliverySlotID == nil || *order.DeliverySlotID != slot.ID || order.DeliveryDate == nil || order.DeliveryDate.Format(dateLayout) != day.Format(dateLayout) {
+		t.Fatalf("slot not recorded: %v %v", order.DeliverySlotID, order.DeliveryDate)
+	}
+
+	if code, _ := submit(otherStore, day.Format(dateLayout)); code != http.StatusConflict {
+		t.Fatalf("full slot: expected 409, got %d", code)
+	}
+
+	w := doOrderRequest(buildDeliverySlotRouter(supplier), http.MethodGet, "/me/orders/by-slot?from="+day.Format(dateLayout)+"&to="+day.Format(dateLayout), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("by-slot: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var grouped struct {
+		Slots []slotGroup `json:"slots"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &grouped)
+	if len(grouped.Slots) != 1 || len(grouped.Slots[0].Orders) != 1 || grouped.Slots[0].Orders[0].ID != order.ID {
+		t.Fatalf("unexpected grouping: %+v", grouped.Slots)
+	}
+}
+
+func TestCreateDeliverySlotKeepsInactive(t *testing.T) {
+	_, supplier := setupDeliverySlotTestDB(t)
+
+	w := doOrderRequest(buildDeliverySlotRouter(supplier), http.MethodPost, "/me/delivery-slots", `{"weekday":1,"start_time":"08:00","end_time":"12:00","capacity":3,"active":false}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created models.DeliverySlot
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	var stored models.DeliverySlot
+	database.DB.First(&stored, created.ID)
+	if created.Active || stored.Active {
+		t.Fatalf("expected slot to be inactive, got response %v and stored %v", created.Active, stored.Active)
+	}
+}
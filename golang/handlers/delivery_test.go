@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+
+	"siargao-trading-road/models"
+)
+
+func TestHaversineKm(t *testing.T) {
+	// General Luna to Dapa, Siargao: roughly 7 km apart.
+	got := haversineKm(9.7833, 126.1567, 9.7594, 126.0989)
+	if math.Abs(got-6.8) > 0.5 {
+		t.Fatalf("unexpected distance %.2f km", got)
+	}
+	if haversineKm(9.78, 126.15, 9.78, 126.15) != 0 {
+		t.Fatal("expected zero distance for the same point")
+	}
+}
+
+func TestQuoteDelivery(t *testing.T) {
+	lat, lng := 9.7833, 126.1567
+	farLat, farLng := 9.7594, 126.0989
+	supplier := models.User{Name: "Supplier", Latitude: &lat, Longitude: &lng}
+	store := models.User{Name: "Store", Latitude: &farLat, Longitude: &farLng, Barangay: "Poblacion", Municipality: "Dapa"}
+	noLocation := models.User{Name: "Store", Barangay: "Malinao"}
+
+	bands := models.SupplierDeliverySettings{
+		FeeType:               models.DeliveryFeeTypeDistance,
+		FreeDeliveryThreshold: 10000,
+		DistanceBands:         []models.DeliveryFeeBand{{UpToKm: 3, Fee: 50}, {UpToKm: 10, Fee: 120}},
+	}
+	zones := models.SupplierDeliverySettings{
+		FeeType: models.DeliveryFeeTypeZone,
+		Zones:   []models.DeliveryFeeZone{{Barangay: "poblacion", Municipality: "Dapa", Fee: 80}},
+	}
+
+	tests := []struct {
+		name     string
+		settings models.SupplierDeliverySettings
+		store    models.User
+		option   models.DeliveryOption
+		subtotal float64
+		fee      float64
+		wantErr  bool
+	}{
+		{name: "flat", settings: models.SupplierDeliverySettings{FeeType: models.DeliveryFeeTypeFlat, FlatFee: 100}, store: store, option: models.DeliveryOptionDeliver, fee: 100},
+		{name: "pickup is free", settings: bands, store: store, option: models.DeliveryOptionPickup, fee: 0},
+		{name: "distance band", settings: bands, store: store, option: models.DeliveryOptionDeliver, subtotal: 6000, fee: 120},
+		{name: "free above threshold", settings: bands, store: store, option: models.DeliveryOptionDeliver, subtotal: 10000, fee: 0},
+		{name: "beyond last band", settings: models.SupplierDeliverySettings{FeeType: models.DeliveryFeeTypeDistance, DistanceBands: []models.DeliveryFeeBand{{UpToKm: 3, Fee: 50}}}, store: store, option: models.DeliveryOptionDeliver, wantErr: true},
+		{name: "distance needs coordinates", settings: bands, store: noLocation, option: models.DeliveryOptionDeliver, wantErr: true},
+		{name: "zone", settings: zones, store: store, option: models.DeliveryOptionDeliver, fee: 80},
+		{name: "zone not covered", settings: zones, store: noLocation, option: models.DeliveryOptionDeliver, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := quoteDelivery(tt.settings, supplier, tt.store, tt.option, tt.subtotal)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got fee %.2f", quote.DeliveryFee)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if quote.DeliveryFee != tt.fee {
+				t.Fatalf("expected fee %.2f, got %.2f", tt.fee, quote.DeliveryFee)
+			}
+		})
+	}
+}
+
+func TestDeliversTo(t *testing.T) {
+	lat, lng := 9.7833, 126.1567
+	nearLat, nearLng := 9.79, 126.16
+	farLat, farLng := 9.7594, 126.0989
+	supplier := models.User{Latitude: &lat, Longitude: &lng}
+	near := models.User{Latitude: &nearLat, Longitude: &nearLng, Barangay: "Catangnan", Municipality: "General Luna"}
+	far := models.User{Latitude: &farLat, Longitude: &farLng, Barangay: "Poblacion", Municipality: "Dapa"}
+
+	radius := models.SupplierDeliverySettings{ServiceAreaType: models.ServiceAreaRadius, ServiceRadiusKm: 3}
+	polygon := models.SupplierDeliverySettings{
+		ServiceAreaType:    models.ServiceAreaPolygon,
+		ServiceAreaPolygon: []models.GeoPoint{{Lat: 9.77, Lng: 126.14}, {Lat: 9.80, Lng: 126.14}, {Lat: 9.80, Lng: 126.17}, {Lat: 9.77, Lng: 126.17}},
+	}
+	named := models.SupplierDeliverySettings{
+		ServiceAreaType:   models.ServiceAreaNamed,
+		ServiceAreaPlaces: []models.ServiceAreaPlace{{Municipality: "general luna"}},
+	}
+
+	tests := []struct {
+		name     string
+		settings models.SupplierDeliverySettings
+		store    models.User
+		want     bool
+	}{
+		{name: "anywhere", settings: models.SupplierDeliverySettings{}, store: far, want: true},
+		{name: "inside radius", settings: radius, store: near, want: true},
+		{name: "outside radius", settings: radius, store: far, want: false},
+		{name: "radius without coordinates", settings: radius, store: models.User{}, want: false},
+		{name: "inside polygon", settings: polygon, store: near, want: true},
+		{name: "outside polygon", settings: polygon, store: far, want: false},
+		{name: "named municipality", settings: named, store: near, want: true},
+		{name: "other municipality", settings: named, store: far, want: false},
+	}
+	for _, tt := range tests {
+		if got := deliversTo(tt.settings, supplier, tt.store); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	if _, err := quoteDelivery(radius, supplier, far, models.DeliveryOptionDeliver, 6000); err == nil {
+		t.Error("expected delivery outside the service area to be refused")
+	}
+	if _, err := quoteDelivery(radius, supplier, far, models.DeliveryOptionPickup, 6000); err != nil {
+		t.Errorf("expected pickup outside the service area to be allowed: %v", err)
+	}
+}
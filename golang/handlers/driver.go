@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/http"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AssignOrderDriver assigns one of the supplier's drivers to an in-transit
+// order, or clears the assignment when driver_id is null.
+func AssignOrderDriver(c *gin.Context) {
+	id := c.Param("id")
+	userID, ok := requireSupplier(c)
+	if !ok {
+		return
+	}
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+
+	var req struct {
+		DriverID *uint `json:"driver_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var order models.Order
+	if err := database.DB.Where("id = ? AND supplier_id = ?", id, userID).First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		return
+	}
+	if order.Status != models.OrderStatusInTransit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "drivers can only be assigned to in_transit orders"})
+		return
+	}
+
+	if req.DriverID != nil {
+		var driver models.Employee
+		if err := database.DB.Where("id = ? AND owner_user_id = ? AND role = ?", *req.DriverID, userID, models.EmployeeRoleDriver).First(&driver).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "driver not found"})
+			return
+		}
+		if !driver.StatusActive {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "driver account is inactive"})
+			return
+		}
+	}
+
+	if err := database.DB.Model(&order).Update("driver_id", req.DriverID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to assign driver"})
+		return
+	}
+
+	if err := database.DB.Preload("Store").Preload("Driver").First(&order, order.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load order details"})
+		return
+	}
+	if order.Driver != nil {
+		order.Driver.Password = ""
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// GetMyDeliveries lists the orders assigned to the signed-in driver. Only
+// in-transit orders are returned unless another status is asked for.
+func GetMyDeliveries(c *gin.Context) {
+	userID, employeeID, ok := requireDriver(c)
+	if !ok {
+		return
+	}
+
+	status := models.OrderStatusInTransit
+	if value := c.Query("status"); value != "" {
+		status = models.OrderStatus(value)
+	}
+
+	var orders []models.Order
+	if err := database.DB.Preload("Store").Preload("OrderItems").Preload("OrderItems.Product").Preload("OrderItems.SubstituteProduct").Preload("DeliverySlot").
+		Where("supplier_id = ? AND driver_id = ? AND status = ?", userID, employeeID, status).
+		Order("delivery_date ASC, id ASC").Find(&orders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+// requireDriver returns the owning supplier and employee ID of a signed-in
+// driver.
+func requireDriver(c *gin.Context) (uint, uint, bool) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return 0, 0, false
+	}
+	driverID, ok := supplierDriverID(c, getEmployeeContext(c))
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only drivers can access deliveries"})
+		return 0, 0, false
+	}
+	return userID, driverID, true
+}
+
+// supplierDriverID returns the employee ID of a signed-in driver who works
+// for the signed-in supplier. Only such a driver may act on the orders
+// assigned to them without the order permissions; the is_driver claim alone
+// grants nothing.
+func supplierDriverID(c *gin.Context, empCtx employeeContext) (uint, bool) {
+	role, _ := c.Get("role")
+	if role != "supplier" || !empCtx.IsDriver || empCtx.EmployeeID == 0 {
+		return 0, false
+	}
+	userID, err := getUserID(c)
+	if err != nil {
+		return 0, false
+	}
+	var employee models.Employee
+	if err := database.DB.Where("id = ? AND owner_user_id = ?", empCtx.EmployeeID, userID).First(&employee).Error; err != nil {
+		return 0, false
+	}
+	return employee.ID, employee.IsDriver()
+}
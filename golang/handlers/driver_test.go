@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupDriverTestDB(t *testing.T) (models.User, models.User) {
+	t.Helper()
+	store, supplier := setupOrderTestDB(t)
+	migrateTestModels(t, &models.DeliveryProof{}, &models.DeliveryLocationPing{})
+	return store, supplier
+}
+
+func buildDriverRouter(supplier models.User, driver models.Employee) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", supplier.ID)
+		c.Set("role", string(supplier.Role))
+		c.Set("is_employee", true)
+		c.Set("employee_id", driver.ID)
+		c.Set("can_manage_orders", driver.CanManageOrders)
+		c.Set("can_chat", driver.CanChat)
+		c.Set("can_change_status", driver.CanChangeStatus)
+		c.Set("is_driver", driver.IsDriver())
+		c.Set("config", proofTestConfig)
+	})
+	r.GET("/me/deliveries", GetMyDeliveries)
+	r.PUT("/orders/:id/status", UpdateOrderStatus)
+	r.POST("/orders/:id/location", RecordDeliveryLocation)
+	r.GET("/orders/:id/tracking", GetOrderTracking)
+	r.POST("/orders/:id/deliver", ConfirmDelivery)
+	return r
+}
+
+func TestDriverDeliversAssignedOrder(t *testing.T) {
+	store, supplier := setupDriverTestDB(t)
+	supplierRouter := buildOrderRouter(supplier)
+	supplierRouter.POST("/employees", CreateEmployee)
+	supplierRouter.PUT("/orders/:id/driver", AssignOrderDriver)
+
+	w := doOrderRequest(supplierRouter, http.MethodPost, "/employees", `{"username":"rico","password":"secret1","name":"Rico","role":"driver"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var driver models.Employee
+	json.Unmarshal(w.Body.Bytes(), &driver)
+	if !driver.IsDriver() || driver.CanManageOrders || driver.CanChat {
+		t.Fatalf("expected driver without order or chat permissions, got %+v", driver)
+	}
+	var stored models.Employee
+	database.DB.First(&stored, driver.ID)
+	if stored.CanManageOrders || stored.CanChat {
+		t.Fatalf("expected permissions to be stored as false, got %+v", stored)
+	}
+
+	order := createTestOrder(t, store, supplier, models.OrderStatusInTransit)
+	other := createTestOrder(t, store, supplier, models.OrderStatusInTransit)
+	driverRouter := buildDriverRouter(supplier, driver)
+
+	pingPath := fmt.Sprintf("/orders/%d/location", order.ID)
+	ping := `{"latitude":9.79,"longitude":126.15,"accuracy":12}`
+	if w := doOrderRequest(driverRouter, http.MethodPost, pingPath, ping); w.Code != http.StatusNotFound {
+		t.Fatalf("expected unassigned order to be hidden, got %d", w.Code)
+	}
+
+	preparing := createTestOrder(t, store, supplier, models.OrderStatusPreparing)
+	assign := fmt.Sprintf(`{"driver_id":%d}`, driver.ID)
+	if w := doOrderRequest(supplierRouter, http.MethodPut, fmt.Sprintf("/orders/%d/driver", preparing.ID), assign); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected assigning a preparing order to be refused, got %d", w.Code)
+	}
+	if w := doOrderRequest(supplierRouter, http.MethodPut, fmt.Sprintf("/orders/%d/driver", order.ID), assign); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := doOrderRequest(driverRouter, http.MethodPost, pingPath, ping); w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doOrderRequest(driverRouter, http.MethodPost, pingPath, `{"latitude":91,"longitude":126.15}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected invalid coordinates to be refused, got %d", w.Code)
+	}
+	var pings int64
+	database.DB.Model(&models.DeliveryLocationPing{}).Where("order_id = ?", order.ID).Count(&pings)
+	if pings != 1 {
+		t.Fatalf("expected 1 ping, got %d", pings)
+	}
+
+	w = doOrderRequest(driverRouter, http.MethodGet, "/me/deliveries", "")
+	var deliveries []models.Order
+	json.Unmarshal(w.Body.Bytes(), &deliveries)
+	if w.Code != http.StatusOK || len(deliveries) != 1 || deliveries[0].ID != order.ID {
+		t.Fatalf("expected only the assigned order, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := putOrderStatus(driverRouter, order.ID, `{"status":"cancelled","reason":"no"}`); w.Code != http.StatusForbidden {
+		t.Fatalf("expected driver to lack order management, got %d", w.Code)
+	}
+
+	proof := `{"receiver_name":"Ana","signature_url":"uploads/supplier/2/sig.png"}`
+	if w := doOrderRequest(driverRouter, http.MethodPost, fmt.Sprintf("/orders/%d/deliver", other.ID), proof); w.Code != http.StatusNotFound {
+		t.Fatalf("expected unassigned order to be refused, got %d", w.Code)
+	}
+	if w := doOrderRequest(driverRouter, http.MethodPost, fmt.Sprintf("/orders/%d/deliver", order.ID), proof); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var recorded models.DeliveryProof
+	database.DB.Where("order_id = ?", order.ID).First(&recorded)
+	if recorded.EmployeeID == nil || *recorded.EmployeeID != driver.ID {
+		t.Fatalf("expected proof recorded by the driver, got %+v", recorded)
+	}
+
+	if w := doOrderRequest(driverRouter, http.MethodPost, pingPath, ping); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected pings after delivery to be refused, got %d", w.Code)
+	}
+}
+
+func TestDriverClaimOutsideSupplierIsRefused(t *testing.T) {
+	store, supplier := setupDriverTestDB(t)
+	// A store employee carrying the driver claim, with the order pointed at
+	// them, must not skip the order permissions.
+	impostor := models.Employee{OwnerUserID: store.ID, Username: "lito", Password: "x", Name: "Lito", Role: models.EmployeeRoleDriver}
+	if err := database.DB.Create(&impostor).Error; err != nil {
+		t.Fatalf("create employee: %v", err)
+	}
+	impostor.CanManageOrders = false
+	impostor.CanChangeStatus = false
+	order := createTestOrder(t, store, supplier, models.OrderStatusInTransit)
+	database.DB.Model(&order).Update("driver_id", impostor.ID)
+
+	requests := []struct{ method, path, body string }{
+		{http.MethodGet, fmt.Sprintf("/orders/%d/tracking", order.ID), ""},
+		{http.MethodPost, fmt.Sprintf("/orders/%d/location", order.ID), `{"latitude":9.79,"longitude":126.15}`},
+		{http.MethodPost, fmt.Sprintf("/orders/%d/deliver", order.ID), `{"receiver_name":"Ana","signature_url":"uploads/store/1/sig.png"}`},
+		{http.MethodGet, "/me/deliveries", ""},
+	}
+	for _, owner := range []models.User{store, supplier} {
+		router := buildDriverRouter(owner, impostor)
+		for _, req := range requests {
+			if w := doOrderRequest(router, req.method, req.path, req.body); w.Code != http.StatusForbidden {
+				t.Fatalf("expected %s %s as %s to be refused, got %d: %s", req.method, req.path, owner.Role, w.Code, w.Body.String())
+			}
+		}
+	}
+
+	database.DB.First(&order, order.ID)
+	var pings int64
+	database.DB.Model(&models.DeliveryLocationPing{}).Where("order_id = ?", order.ID).Count(&pings)
+	if order.Status != models.OrderStatusInTransit || pings != 0 {
+		t.Fatalf("expected order untouched, got %s with %d pings", order.Status, pings)
+	}
+}
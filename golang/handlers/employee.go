@@ -9,6 +9,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type EmployeeCreateRequest struct {
@@ -119,6 +120,14 @@ func CreateEmployee(c *gin.Context) {
 		CanRate:            false,
 		StatusActive:       true,
 	}
+	if employee.IsDriver() {
+		// Drivers work from their assigned deliveries, so they start without
+		// the order, inventory and chat permissions unless granted explicitly.
+		employee.CanManageInventory = false
+		employee.CanManageOrders = false
+		employee.CanChat = false
+		employee.CanChangeStatus = false
+	}
 
 	if req.CanManageInventory != nil {
 		employee.CanManageInventory = *req.CanManageInventory
@@ -136,7 +145,22 @@ func CreateEmployee(c *gin.Context) {
 		employee.StatusActive = *req.StatusActive
 	}
 
-	if err := database.DB.Create(&employee).Error; err != nil {
+	// gorm replaces false with the schema default of true on insert, so the
+	// permissions are written again once the row exists.
+	permissions := map[string]interface{}{
+		"can_manage_inventory": employee.CanManageInventory,
+		"can_manage_orders":    employee.CanManageOrders,
+		"can_chat":             employee.CanChat,
+		"can_change_status":    employee.CanChangeStatus,
+		"status_active":        employee.StatusActive,
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&employee).Error; err != nil {
+			return err
+		}
+		return tx.Model(&employee).Updates(permissions).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create employee"})
 		return
 	}
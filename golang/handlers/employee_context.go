@@ -15,6 +15,7 @@ type employeeContext struct {
 	CanChat            bool
 	CanChangeStatus    bool
 	CanRate            bool
+	IsDriver           bool
 }
 
 func getEmployeeContext(c *gin.Context) employeeContext {
@@ -45,6 +46,7 @@ func getEmployeeContext(c *gin.Context) employeeContext {
 		ctx.CanChat = getBoolClaim(c, "can_chat")
 		ctx.CanChangeStatus = getBoolClaim(c, "can_change_status")
 		ctx.CanRate = getBoolClaim(c, "can_rate")
+		ctx.IsDriver = getBoolClaim(c, "is_driver")
 	}
 
 	return ctx
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type fulfilmentLine struct {
+	ItemID              uint                       `json:"item_id" binding:"required"`
+	Status              models.OrderItemFulfilment `json:"status" binding:"required"`
+	ShippedQuantity     *int                       `json:"shipped_quantity"`
+	SubstituteProductID *uint                      `json:"substitute_product_id"`
+	Note                string                     `json:"note"`
+}
+
+// UpdateOrderFulfilment lets the supplier record, line by line, what will
+// actually be shipped on a preparing order. Stock not shipped goes back on
+// the product, a substitute's stock is taken, and the total is recomputed.
+func UpdateOrderFulfilment(c *gin.Context) {
+	id := c.Param("id")
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+
+	role, _ := c.Get("role")
+	if role != "supplier" && role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only suppliers can update order fulfilment"})
+		return
+	}
+
+	var req struct {
+		Items []fulfilmentLine `json:"items" binding:"required,min=1,dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor := getOrderActor(c)
+	var order models.Order
+	changed := false
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id)
+		if role == "supplier" {
+			query = query.Where("supplier_id = ?", userID)
+		}
+		if err := query.First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &orderError{Code: http.StatusNotFound, Message: "order not found"}
+			}
+			return err
+		}
+		if order.Status != models.OrderStatusPreparing {
+			return &orderError{Code: http.StatusBadRequest, Message: "fulfilment can only be changed while the order is preparing"}
+		}
+
+		var items []models.OrderItem
+		if err := tx.Where("order_id = ?", order.ID).Find(&items).Error; err != nil {
+			return err
+		}
+		itemsByID := make(map[uint]models.OrderItem, len(items))
+		for _, item := range items {
+			itemsByID[item.ID] = item
+		}
+
+		seen := make(map[uint]bool, len(req.Items))
+		for _, line := range req.Items {
+			item, ok := itemsByID[line.ItemID]
+			if !ok {
+				return &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("item %d is not on this order", line.ItemID)}
+			}
+			if seen[line.ItemID] {
+				return &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("item %d is listed more than once", line.ItemID)}
+			}
+			seen[line.ItemID] = true
+
+			lineChanged, err := applyFulfilmentLine(tx, &order, item, line, actor)
+			if err != nil {
+				return err
+			}
+			changed = changed || lineChanged
+		}
+
+		var subtotal float64
+		if err := tx.Model(&models.OrderItem{}).Where("order_id = ?", order.ID).
+			Select("COALESCE(SUM(subtotal), 0)").Scan(&subtotal).Error; err != nil {
+			return err
+		}
+		// The cached invoice no longer matches; it is regenerated on next download.
+		order.TotalAmount = subtotal + order.DeliveryFee
+		if err := tx.Model(&models.Order{}).Where("id = ?", order.ID).Updates(map[string]interface{}{
+			"total_amount": order.TotalAmount,
+			"invoice_url":  "",
+		}).Error; err != nil {
+			return err
+		}
+		return syncPaymentStatus(tx, &order)
+	})
+	if err != nil {
+		writeOrderError(c, err, "failed to update order fulfilment")
+		return
+	}
+
+	if err := database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product").Preload("OrderItems.SubstituteProduct").First(&order, order.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load order details"})
+		return
+	}
+
+	if changed {
+		if emailService := getEmailService(c); emailService != nil {
+			go emailService.SendOrderFulfilmentChangedEmail(order)
+		}
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// applyFulfilmentLine validates one line, moves the stock difference between
+// what the item held before and what it holds now, and saves the item. It
+// reports whether the line ends up short-shipped or substituted differently
+// from before, which the store is told about.
+func applyFulfilmentLine(tx *gorm.DB, order *models.Order, item models.OrderItem, line fulfilmentLine, actor orderActor) (bool, error) {
+	before := item
+
+	item.FulfilmentStatus = line.Status
+	item.FulfilmentNote = line.Note
+	switch line.Status {
+	case models.ItemFulfilmentPending, models.ItemFulfilmentFulfilled:
+		item.ShippedQuantity = nil
+		item.SubstituteProductID = nil
+		item.SubstituteUnitPrice = nil
+	case models.ItemFulfilmentShortShipped:
+		if line.ShippedQuantity == nil || *line.ShippedQuantity < 0 || *line.ShippedQuantity >= item.Quantity {
+			return false, &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("item %d: shipped_quantity must be between 0 and %d", item.ID, item.Quantity-1)}
+		}
+		item.ShippedQuantity = line.ShippedQuantity
+		item.SubstituteProductID = nil
+		item.SubstituteUnitPrice = nil
+	case models.ItemFulfilmentSubstituted:
+		if line.SubstituteProductID == nil || *line.SubstituteProductID == item.ProductID {
+			return false, &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("item %d: a different substitute_product_id is required", item.ID)}
+		}
+		var substitute models.Product
+		if err := tx.Where("id = ? AND supplier_id = ?", *line.SubstituteProductID, order.SupplierID).First(&substitute).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return false, &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("item %d: substitute product not found", item.ID)}
+			}
+			return false, err
+		}
+		shipped := item.Quantity
+		if line.ShippedQuantity != nil {
+			shipped = *line.ShippedQuantity
+		}
+		if shipped < 1 || shipped > item.Quantity {
+			return false, &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("item %d: shipped_quantity must be between 1 and %d", item.ID, item.Quantity)}
+		}
+		// Re-saving the same substitute keeps the price the store was told.
+		price := substitute.Price
+		if before.FulfilmentStatus == models.ItemFulfilmentSubstituted && before.SubstituteUnitPrice != nil &&
+			before.SubstituteProductID != nil && *before.SubstituteProductID == substitute.ID {
+			price = *before.SubstituteUnitPrice
+		}
+		item.ShippedQuantity = &shipped
+		item.SubstituteProductID = &substitute.ID
+		item.SubstituteUnitPrice = &price
+	default:
+		return false, &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("item %d: invalid fulfilment status %q", item.ID, line.Status)}
+	}
+
+	notes := fmt.Sprintf("order item %d %s", item.ID, item.FulfilmentStatus)
+	if before.ShippedProductID() == item.ShippedProductID() {
+		if err := adjustOrderStock(tx, order.ID, item.ShippedProductID(), item.BilledQuantity()-before.BilledQuantity(), "fulfilment_adjusted", actor, notes); err != nil {
+			return false, err
+		}
+	} else {
+		if err := adjustOrderStock(tx, order.ID, before.ShippedProductID(), -before.BilledQuantity(), "fulfilment_adjusted", actor, notes); err != nil {
+			return false, err
+		}
+		if err := adjustOrderStock(tx, order.ID, item.ShippedProductID(), item.BilledQuantity(), "fulfilment_adjusted", actor, notes); err != nil {
+			return false, err
+		}
+	}
+
+	item.Subtotal = float64(item.BilledQuantity()) * item.BilledUnitPrice()
+	if err := tx.Model(&models.OrderItem{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+		"fulfilment_status":     item.FulfilmentStatus,
+		"shipped_quantity":      item.ShippedQuantity,
+		"substitute_product_id": item.SubstituteProductID,
+		"substitute_unit_price": item.SubstituteUnitPrice,
+		"fulfilment_note":       item.FulfilmentNote,
+		"subtotal":              item.Subtotal,
+	}).Error; err != nil {
+		return false, err
+	}
+
+	differs := item.FulfilmentStatus == models.ItemFulfilmentShortShipped || item.FulfilmentStatus == models.ItemFulfilmentSubstituted
+	return differs && (before.ShippedProductID() != item.ShippedProductID() || before.BilledQuantity() != item.BilledQuantity() || before.BilledUnitPrice() != item.BilledUnitPrice()), nil
+}
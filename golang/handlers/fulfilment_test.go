@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupFulfilmentTestDB(t *testing.T) (models.User, models.User) {
+	t.Helper()
+	store, supplier := setupOrderTestDB(t)
+	migrateTestModels(t, &models.Payment{}, &models.PaymentVerification{})
+	return store, supplier
+}
+
+func buildFulfilmentRouter(user models.User) *gin.Engine {
+	r := buildOrderRouter(user)
+	r.PUT("/orders/:id/fulfilment", UpdateOrderFulfilment)
+	return r
+}
+
+func TestUpdateOrderFulfilmentShortShipAndSubstitute(t *testing.T) {
+	store, supplier := setupFulfilmentTestDB(t)
+	rice := models.Product{SupplierID: supplier.ID, Name: "Rice", SKU: "RICE-1", Price: 50, StockQuantity: 5}
+	brown := models.Product{SupplierID: supplier.ID, Name: "Brown Rice", SKU: "RICE-2", Price: 60, StockQuantity: 10}
+	eggs := models.Product{SupplierID: supplier.ID, Name: "Eggs", SKU: "EGG-1", Price: 8, StockQuantity: 20}
+	for _, p := range []*models.Product{&rice, &brown, &eggs} {
+		if err := database.DB.Create(p).Error; err != nil {
+			t.Fatalf("create product: %v", err)
+		}
+	}
+
+	order := createTestOrder(t, store, supplier, models.OrderStatusPreparing)
+	database.DB.Model(&order).Updates(map[string]interface{}{"delivery_fee": 30, "total_amount": 330, "invoice_url": "https://example.com/old.pdf"})
+	riceItem := models.OrderItem{OrderID: order.ID, ProductID: rice.ID, Quantity: 4, UnitPrice: 50, Subtotal: 200}
+	eggItem := models.OrderItem{OrderID: order.ID, ProductID: eggs.ID, Quantity: 10, UnitPrice: 10, Subtotal: 100}
+	for _, item := range []*models.OrderItem{&riceItem, &eggItem} {
+		if err := database.DB.Create(item).Error; err != nil {
+			t.Fatalf("create item: %v", err)
+		}
+	}
+
+	body := fmt.Sprintf(`{"items":[{"item_id":%d,"status":"substituted","substitute_product_id":%d,"shipped_quantity":3},{"item_id":%d,"status":"short_shipped","shipped_quantity":6,"note":"only six trays left"}]}`,
+		riceItem.ID, brown.ID, eggItem.ID)
+	w := doOrderRequest(buildFulfilmentRouter(supplier), http.MethodPut, fmt.Sprintf("/orders/%d/fulfilment", order.ID), body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	stock := func(id uint) int {
+		var p models.Product
+		database.DB.First(&p, id)
+		return p.StockQuantity
+	}
+	if got := stock(rice.ID); got != 9 {
+		t.Fatalf("expected rice stock 9 after substitution, got %d", got)
+	}
+	if got := stock(brown.ID); got != 7 {
+		t.Fatalf("expected brown rice stock 7, got %d", got)
+	}
+	if got := stock(eggs.ID); got != 24 {
+		t.Fatalf("expected egg stock 24 after short-ship, got %d", got)
+	}
+
+	var history models.StockHistory
+	if err := database.DB.Where("product_id = ? AND change_type = ?", eggs.ID, "fulfilment_adjusted").First(&history).Error; err != nil {
+		t.Fatalf("stock history not written: %v", err)
+	}
+	if history.ChangeAmount != 4 || history.OrderID == nil || *history.OrderID != order.ID {
+		t.Fatalf("unexpected stock history: %+v", history)
+	}
+
+	var reloaded models.Order
+	database.DB.First(&reloaded, order.ID)
+	if reloaded.TotalAmount != 3*60+6*10+30 {
+		t.Fatalf("expected total 270, got %.2f", reloaded.TotalAmount)
+	}
+	if reloaded.InvoiceURL != "" {
+		t.Fatalf("expected cached invoice to be cleared, got %q", reloaded.InvoiceURL)
+	}
+
+	// Back to fulfilled in full: the substitute's stock returns and the original is taken again.
+	body = fmt.Sprintf(`{"items":[{"item_id":%d,"status":"fulfilled"}]}`, riceItem.ID)
+	w = doOrderRequest(buildFulfilmentRouter(supplier), http.MethodPut, fmt.Sprintf("/orders/%d/fulfilment", order.ID), body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if stock(rice.ID) != 5 || stock(brown.ID) != 10 {
+		t.Fatalf("unexpected stock after revert: rice %d, brown rice %d", stock(rice.ID), stock(brown.ID))
+	}
+
+	// Cancelling returns what each line still holds.
+	w = putOrderStatus(buildFulfilmentRouter(supplier), order.ID, `{"status":"cancelled","reason":"store closed"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if stock(rice.ID) != 9 || stock(eggs.ID) != 30 {
+		t.Fatalf("unexpected stock after cancel: rice %d, eggs %d", stock(rice.ID), stock(eggs.ID))
+	}
+}
+
+func TestUpdateOrderFulfilmentRejectsInvalidLines(t *testing.T) {
+	store, supplier := setupFulfilmentTestDB(t)
+	product := models.Product{SupplierID: supplier.ID, Name: "Rice", SKU: "RICE-1", Price: 50, StockQuantity: 5}
+	if err := database.DB.Create(&product).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	order := createTestOrder(t, store, supplier, models.OrderStatusPreparing)
+	item := models.OrderItem{OrderID: order.ID, ProductID: product.ID, Quantity: 4, UnitPrice: 50, Subtotal: 200}
+	if err := database.DB.Create(&item).Error; err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	path := fmt.Sprintf("/orders/%d/fulfilment", order.ID)
+	cases := []string{
+		fmt.Sprintf(`{"items":[{"item_id":%d,"status":"short_shipped","shipped_quantity":4}]}`, item.ID),
+		fmt.Sprintf(`{"items":[{"item_id":%d,"status":"substituted","substitute_product_id":%d}]}`, item.ID, product.ID),
+		fmt.Sprintf(`{"items":[{"item_id":%d,"status":"lost"}]}`, item.ID),
+		`{"items":[{"item_id":9999,"status":"fulfilled"}]}`,
+	}
+	for _, body := range cases {
+		if w := doOrderRequest(buildFulfilmentRouter(supplier), http.MethodPut, path, body); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status 400, got %d", body, w.Code)
+		}
+	}
+
+	body := fmt.Sprintf(`{"items":[{"item_id":%d,"status":"fulfilled"}]}`, item.ID)
+	if w := doOrderRequest(buildFulfilmentRouter(store), http.MethodPut, path, body); w.Code != http.StatusForbidden {
+		t.Fatalf("store: expected status 403, got %d", w.Code)
+	}
+}
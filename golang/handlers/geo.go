@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"siargao-trading-road/models"
+)
+
+const earthRadiusKm = 6371.0
+
+// haversineKm returns the great-circle distance between two points in km.
+func haversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// userDistanceKm returns the distance between two users' saved locations,
+// and false if either has no coordinates.
+func userDistanceKm(a, b models.User) (float64, bool) {
+	if a.Latitude == nil || a.Longitude == nil || b.Latitude == nil || b.Longitude == nil {
+		return 0, false
+	}
+	return haversineKm(*a.Latitude, *a.Longitude, *b.Latitude, *b.Longitude), true
+}
+
+func roundTo2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// pointInPolygon reports whether a point lies inside a polygon, using ray
+// casting. The polygon is closed implicitly.
+func pointInPolygon(lat, lng float64, polygon []models.GeoPoint) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Lat > lat) != (b.Lat > lat) &&
+			lng < (b.Lng-a.Lng)*(lat-a.Lat)/(b.Lat-a.Lat)+a.Lng {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// parseLatLng reads a "lat,lng" pair.
+func parseLatLng(value string) (models.GeoPoint, bool) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return models.GeoPoint{}, false
+	}
+	lat, errLat := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	lng, errLng := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if errLat != nil || errLng != nil || !validCoordinates(lat, lng) {
+		return models.GeoPoint{}, false
+	}
+	return models.GeoPoint{Lat: lat, Lng: lng}, true
+}
+
+func validCoordinates(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
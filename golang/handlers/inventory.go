@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// orderItemChange describes one change to a draft order line. Quantity is the
+// new line quantity, or the amount to add when Add is set; a resulting
+// quantity of zero removes the line.
+type orderItemChange struct {
+	OrderID   uint
+	ProductID uint
+	Quantity  int
+	Add       bool
+}
+
+// applyOrderItemChange runs an order item change in its own transaction.
+func applyOrderItemChange(change orderItemChange, actor orderActor, ttl time.Duration) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		return applyOrderItemChangeTx(tx, change, actor, ttl)
+	})
+}
+
+// applyOrderItemChangeTx is the only place draft order lines and their stock
+// reservations are changed. The order and product rows are locked first, so
+// concurrent carts competing for the same stock are serialised; the item, the
+// reservation, the stock history row and the order total all move together.
+func applyOrderItemChangeTx(tx *gorm.DB, change orderItemChange, actor orderActor, ttl time.Duration) error {
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND status = ?", change.OrderID, models.OrderStatusDraft).
+		First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &orderError{Code: http.StatusNotFound, Message: "draft order not found"}
+		}
+		return err
+	}
+
+	var product models.Product
+	if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND supplier_id = ?", change.ProductID, order.SupplierID).
+		First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &orderError{Code: http.StatusNotFound, Message: "product not found"}
+		}
+		return err
+	}
+
+	var item models.OrderItem
+	err := tx.Where("order_id = ? AND product_id = ?", order.ID, product.ID).First(&item).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	hasItem := err == nil
+
+	quantity := change.Quantity
+	if change.Add && hasItem {
+		quantity += item.Quantity
+	}
+
+	var previousAvailable, newAvailable int
+	var changeType, notes string
+	switch {
+	case quantity <= 0:
+		if !hasItem {
+			return &orderError{Code: http.StatusNotFound, Message: "order item not found"}
+		}
+		previousAvailable, newAvailable, err = releaseStock(tx, order.ID, product)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&item).Error; err != nil {
+			return err
+		}
+		changeType, notes = "order_item_removed", "released from draft order"
+	default:
+		if product.DeletedAt.Valid {
+			return &orderError{Code: http.StatusNotFound, Message: "product not found"}
+		}
+		previousAvailable, newAvailable, err = reserveStock(tx, order.ID, product, quantity, ttl)
+		if err != nil {
+			return err
+		}
+		if hasItem {
+			item.Quantity = quantity
+			item.Subtotal = item.UnitPrice * float64(quantity)
+			changeType = "order_item_updated"
+		} else {
+			item = models.OrderItem{
+				OrderID:   order.ID,
+				ProductID: product.ID,
+				Quantity:  quantity,
+				UnitPrice: product.Price,
+				Subtotal:  product.Price * float64(quantity),
+			}
+			changeType = "order_item_added"
+		}
+		if err := tx.Omit("Order", "Product").Save(&item).Error; err != nil {
+			return err
+		}
+		notes = "reserved for draft order"
+	}
+
+	orderID := order.ID
+	if err := logStockChangeTx(tx, product.ID, previousAvailable, newAvailable, changeType, actor.userIDPtr(), actor.EmployeeID, &orderID, notes); err != nil {
+		return err
+	}
+	if err := extendOrderReservations(tx, order.ID, ttl); err != nil {
+		return err
+	}
+	return recomputeOrderTotal(tx, order.ID)
+}
+
+// recomputeOrderTotal sets the order total to the sum of its item subtotals.
+func recomputeOrderTotal(tx *gorm.DB, orderID uint) error {
+	var totalAmount float64
+	if err := tx.Model(&models.OrderItem{}).Where("order_id = ?", orderID).
+		Select("COALESCE(SUM(subtotal), 0)").Scan(&totalAmount).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Order{}).Where("id = ?", orderID).Update("total_amount", totalAmount).Error
+}
+
+// adjustOrderStock moves on-hand stock for a submitted order: a positive
+// quantity takes it from the product, a negative one gives it back. Taking
+// never drives stock below zero.
+func adjustOrderStock(tx *gorm.DB, orderID, productID uint, quantity int, changeType string, actor orderActor, notes string) error {
+	if quantity == 0 {
+		return nil
+	}
+
+	var product models.Product
+	if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, productID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) && quantity < 0 {
+			return nil
+		}
+		return err
+	}
+
+	result := tx.Unscoped().Model(&models.Product{}).Where("id = ? AND stock_quantity >= ?", product.ID, quantity).
+		UpdateColumn("stock_quantity", gorm.Expr("stock_quantity - ?", quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &orderError{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("insufficient stock for %s: only %d %s on hand", product.Name, product.StockQuantity, product.Unit),
+		}
+	}
+
+	orderIDCopy := orderID
+	return logStockChangeTx(tx, product.ID, product.StockQuantity, product.StockQuantity-quantity, changeType, actor.userIDPtr(), actor.EmployeeID, &orderIDCopy, notes)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+)
+
+func TestConcurrentOrderItemChangesNeverOversell(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "inventory.db") + "?_busy_timeout=10000&_txlock=immediate"
+	_, supplier := openOrderTestDB(t, dsn)
+	migrateTestModels(t, &models.StockReservation{})
+
+	const stock = 5
+	const carts = 20
+	product := models.Product{SupplierID: supplier.ID, Name: "Rice", SKU: "RICE-1", Price: 50, StockQuantity: stock}
+	if err := database.DB.Create(&product).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+
+	orders := make([]models.Order, carts)
+	for i := range orders {
+		store := models.User{Email: fmt.Sprintf("store%d@example.com", i), Password: "x", Name: "Store", Role: models.RoleStore}
+		database.DB.Create(&store)
+		orders[i] = createTestOrder(t, store, supplier, models.OrderStatusDraft)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, carts)
+	for i := range orders {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			actor := orderActor{UserID: orders[i].StoreID, Role: models.RoleStore}
+			errs[i] = applyOrderItemChange(orderItemChange{OrderID: orders[i].ID, ProductID: product.ID, Quantity: 1, Add: true}, actor, time.Hour)
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+		}
+	}
+	if succeeded != stock {
+		t.Fatalf("expected %d carts to get stock, got %d (errors: %v)", stock, succeeded, errs)
+	}
+
+	var reserved int
+	database.DB.Model(&models.StockReservation{}).Where("product_id = ? AND status = ?", product.ID, models.ReservationStatusActive).
+		Select("COALESCE(SUM(quantity), 0)").Scan(&reserved)
+	if reserved != stock {
+		t.Fatalf("expected %d units reserved, got %d", stock, reserved)
+	}
+
+	var items, history int64
+	database.DB.Model(&models.OrderItem{}).Count(&items)
+	database.DB.Model(&models.StockHistory{}).Where("product_id = ?", product.ID).Count(&history)
+	if items != stock || history != stock {
+		t.Fatalf("expected %d items and history rows, got %d and %d", stock, items, history)
+	}
+
+	var totals float64
+	database.DB.Model(&models.Order{}).Select("COALESCE(SUM(total_amount), 0)").Scan(&totals)
+	if totals != stock*product.Price {
+		t.Fatalf("order totals out of sync with items: %.2f", totals)
+	}
+}
+
+func TestConcurrentCancelsRestoreStockOnce(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "cancel.db") + "?_busy_timeout=10000&_txlock=immediate"
+	store, supplier := openOrderTestDB(t, dsn)
+	migrateTestModels(t, &models.StockReservation{})
+
+	product := models.Product{SupplierID: supplier.ID, Name: "Rice", SKU: "RICE-1", Price: 50, StockQuantity: 7}
+	if err := database.DB.Create(&product).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	order := createTestOrder(t, store, supplier, models.OrderStatusPreparing)
+	item := models.OrderItem{OrderID: order.ID, ProductID: product.ID, Quantity: 3, UnitPrice: 50, Subtotal: 150}
+	if err := database.DB.Create(&item).Error; err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	const cancels = 10
+	router := buildOrderRouter(store)
+	var wg sync.WaitGroup
+	codes := make([]int, cancels)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = putOrderStatus(router, order.ID, `{"status":"cancelled","reason":"ordered twice"}`).Code
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, code := range codes {
+		if code == http.StatusOK {
+			succeeded++
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("expected exactly one cancel to succeed, got %d (codes: %v)", succeeded, codes)
+	}
+
+	var reloaded models.Product
+	database.DB.First(&reloaded, product.ID)
+	if reloaded.StockQuantity != 10 {
+		t.Fatalf("expected stock 10, got %d", reloaded.StockQuantity)
+	}
+
+	var history, transitions int64
+	database.DB.Model(&models.StockHistory{}).Where("product_id = ? AND change_type = ?", product.ID, "order_cancelled").Count(&history)
+	database.DB.Model(&models.OrderStatusHistory{}).Where("order_id = ? AND to_status = ?", order.ID, models.OrderStatusCancelled).Count(&transitions)
+	if history != 1 || transitions != 1 {
+		t.Fatalf("expected one stock history and status history row, got %d and %d", history, transitions)
+	}
+}
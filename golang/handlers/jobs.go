@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"siargao-trading-road/services"
+)
+
+const (
+	reservationSweepInterval  = time.Minute
+	standingOrderInterval     = 5 * time.Minute
+	deliveryPingPruneInterval = time.Hour
+	overdueReminderInterval   = time.Hour
+	monthlyStatementInterval  = time.Hour
+)
+
+// StartBackgroundJobs launches the periodic in-process jobs and returns
+// immediately. Jobs stop when ctx is cancelled.
+func StartBackgroundJobs(ctx context.Context, emailService *services.EmailService) {
+	go runPeriodically(ctx, "reservation sweeper", reservationSweepInterval, releaseExpiredReservations)
+	go runPeriodically(ctx, "standing orders", standingOrderInterval, func(now time.Time) {
+		runStandingOrders(now, emailService)
+	})
+	go runPeriodically(ctx, "delivery ping pruner", deliveryPingPruneInterval, pruneDeliveryPings)
+	go runPeriodically(ctx, "overdue reminders", overdueReminderInterval, func(now time.Time) {
+		sendOverdueReminders(now, emailService)
+	})
+	go runPeriodically(ctx, "monthly statements", monthlyStatementInterval, func(now time.Time) {
+		sendMonthlyStatements(now, emailService)
+	})
+}
+
+func runPeriodically(ctx context.Context, name string, interval time.Duration, job func(now time.Time)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			runJob(name, job, now)
+		}
+	}
+}
+
+func runJob(name string, job func(now time.Time), now time.Time) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("background job %s panicked: %v", name, r)
+		}
+	}()
+	job(now)
+}
@@ -8,8 +8,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strconv"
 	"time"
 
 	"siargao-trading-road/config"
@@ -22,7 +21,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gin-gonic/gin"
-	"github.com/jung-kurt/gofpdf"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 //go:embed assets/splash.png
@@ -60,6 +60,9 @@ func getEmailService(c *gin.Context) *services.EmailService {
 	return emailService
 }
 
+// GetOrders lists the user's orders. Passing page or limit returns one page
+// with pagination details; without them every matching order is returned as
+// before. summary=true skips the product details of each line.
 func GetOrders(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	role, _ := c.Get("role")
@@ -67,17 +70,8 @@ func GetOrders(c *gin.Context) {
 	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
 		return
 	}
-	status := c.Query("status")
-
-	var orders []models.Order
-	query := database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product")
-
-	if status != "" {
-		query = query.Where("status = ?", status)
-	} else {
-		query = query.Where("status != ?", "draft")
-	}
 
+	query := database.DB.Model(&models.Order{})
 	switch role {
 	case "supplier":
 		query = query.Where("supplier_id = ?", userID)
@@ -85,12 +79,64 @@ func GetOrders(c *gin.Context) {
 		query = query.Where("store_id = ?", userID)
 	}
 
-	if err := query.Order("created_at DESC").Find(&orders).Error; err != nil {
+	query, err := applyOrderListFilters(c, query, role)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sort, err := orderListSort(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paginate := c.Query("page") != "" || c.Query("limit") != ""
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultOrderPageSize)))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > maxOrderPageSize {
+		limit = defaultOrderPageSize
+	}
+
+	var total int64
+	if paginate {
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch orders"})
+			return
+		}
+	}
+
+	find := query.Preload("Store").Preload("Supplier").Preload("OrderItems")
+	if c.Query("summary") != "true" {
+		find = find.Preload("OrderItems.Product").Preload("OrderItems.SubstituteProduct")
+	}
+	find = find.Order(sort)
+	if paginate {
+		find = find.Limit(limit).Offset((page - 1) * limit)
+	}
+
+	orders := []models.Order{}
+	if err := find.Find(&orders).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch orders"})
 		return
 	}
 
-	c.JSON(http.StatusOK, orders)
+	if !paginate {
+		c.JSON(http.StatusOK, orders)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": orders,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
 }
 
 func GetOrder(c *gin.Context) {
@@ -103,7 +149,7 @@ func GetOrder(c *gin.Context) {
 	}
 
 	var order models.Order
-	query := database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product").Where("id = ?", id)
+	query := database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product").Preload("OrderItems.SubstituteProduct").Preload("CreditMemos").Preload("DeliverySlot").Preload("DeliveryProof").Preload("Driver").Preload("PaymentVerifications").Preload("Payments").Where("id = ?", id)
 
 	switch role {
 	case "supplier":
@@ -126,25 +172,39 @@ func GetOrder(c *gin.Context) {
 	log.Printf("GetOrder: order %d has %d ratings", order.ID, len(ratings))
 
 	orderResponse := gin.H{
-		"id":                order.ID,
-		"store_id":          order.StoreID,
-		"supplier_id":       order.SupplierID,
-		"store":             order.Store,
-		"supplier":          order.Supplier,
-		"status":            order.Status,
-		"total_amount":      order.TotalAmount,
-		"payment_method":    order.PaymentMethod,
-		"payment_status":    order.PaymentStatus,
-		"payment_proof_url": order.PaymentProofURL,
-		"delivery_option":   order.DeliveryOption,
-		"delivery_fee":      order.DeliveryFee,
-		"distance":          order.Distance,
-		"shipping_address":  order.ShippingAddress,
-		"notes":             order.Notes,
-		"order_items":       order.OrderItems,
-		"created_at":        order.CreatedAt,
-		"updated_at":        order.UpdatedAt,
-		"ratings":           ratings,
+		"id":                    order.ID,
+		"store_id":              order.StoreID,
+		"supplier_id":           order.SupplierID,
+		"store":                 order.Store,
+		"supplier":              order.Supplier,
+		"status":                order.Status,
+		"total_amount":          order.TotalAmount,
+		"payment_method":        order.PaymentMethod,
+		"payment_status":        order.PaymentStatus,
+		"amount_paid":           order.AmountPaid,
+		"balance":               roundTo2(order.TotalAmount - order.AmountPaid),
+		"payment_proof_url":     order.PaymentProofURL,
+		"due_date":              order.DueDate,
+		"delivery_option":       order.DeliveryOption,
+		"delivery_fee":          order.DeliveryFee,
+		"distance":              order.Distance,
+		"shipping_address":      order.ShippingAddress,
+		"notes":                 order.Notes,
+		"cancellation_reason":   order.CancellationReason,
+		"order_items":           order.OrderItems,
+		"delivery_date":         order.DeliveryDate,
+		"delivery_slot":         order.DeliverySlot,
+		"credit_memos":          order.CreditMemos,
+		"delivery_proof":        order.DeliveryProof,
+		"driver_id":             order.DriverID,
+		"driver":                order.Driver,
+		"shipment_id":           order.ShipmentID,
+		"payment_verifications": order.PaymentVerifications,
+		"payments":              order.Payments,
+		"created_at":            order.CreatedAt,
+		"updated_at":            order.UpdatedAt,
+		"ratings":               ratings,
+		"status_history":        loadOrderStatusHistory(order.ID),
 	}
 
 	c.JSON(http.StatusOK, orderResponse)
@@ -179,6 +239,7 @@ func UpdateOrderStatus(c *gin.Context) {
 
 	var req struct {
 		Status string `json:"status" binding:"required"`
+		Reason string `json:"reason"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -186,34 +247,23 @@ func UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	validStatuses := map[string]bool{
-		"draft":      true,
-		"preparing":  true,
-		"in_transit": true,
-		"delivered":  true,
-		"cancelled":  true,
-	}
-
-	if !validStatuses[req.Status] {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
-		return
-	}
+	actor := getOrderActor(c)
+	var oldStatus models.OrderStatus
+	var transition models.OrderTransition
 
-	if req.Status == "delivered" && order.Status != "in_transit" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "order must be in transit before it can be marked as delivered"})
-		return
-	}
-
-	if req.Status == "in_transit" && order.Status != "preparing" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "order must be preparing before it can be marked as in transit"})
-		return
-	}
-
-	oldStatus := order.Status
-	order.Status = models.OrderStatus(req.Status)
-
-	if err := database.DB.Save(&order).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update order status"})
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Re-read under lock so the transition is checked against the
+		// status a concurrent request may have just written.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, order.ID).Error; err != nil {
+			return err
+		}
+		oldStatus = order.Status
+		var err error
+		transition, err = transitionOrder(tx, &order, models.OrderStatus(req.Status), actor, req.Reason, true)
+		return err
+	})
+	if err != nil {
+		writeOrderError(c, err, "failed to update order status")
 		return
 	}
 
@@ -222,14 +272,7 @@ func UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	emailService := getEmailService(c)
-	if emailService != nil && oldStatus != order.Status {
-		if order.Status == models.OrderStatusDelivered {
-			go emailService.SendOrderDeliveredEmail(order)
-		} else {
-			go emailService.SendOrderStatusChangeEmail(order, oldStatus)
-		}
-	}
+	runOrderNotifications(getEmailService(c), order, oldStatus, transition)
 
 	c.JSON(http.StatusOK, order)
 }
@@ -268,29 +311,23 @@ func CreateDraftOrder(c *gin.Context) {
 		return
 	}
 
-	var existingOrder models.Order
-	if err := database.DB.Where("store_id = ? AND supplier_id = ? AND status = ?", userID, req.SupplierID, "draft").First(&existingOrder).Error; err == nil {
-		database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product").First(&existingOrder, existingOrder.ID)
-		c.JSON(http.StatusOK, existingOrder)
-		return
-	}
-
-	order := models.Order{
-		StoreID:     userID,
-		SupplierID:  req.SupplierID,
-		Status:      models.OrderStatusDraft,
-		TotalAmount: 0,
-	}
-
-	log.Printf("Creating draft order: store_id=%d, supplier_id=%d", userID, req.SupplierID)
-
-	if err := database.DB.Create(&order).Error; err != nil {
+	var order models.Order
+	var created bool
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, created, err = findOrCreateDraftOrder(tx, userID, req.SupplierID, getOrderActor(c))
+		return err
+	})
+	if err != nil {
 		log.Printf("Error creating order: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create order", "details": err.Error()})
 		return
 	}
-
-	log.Printf("Order created successfully: id=%d", order.ID)
+	if !created {
+		database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product").First(&order, order.ID)
+		c.JSON(http.StatusOK, order)
+		return
+	}
 
 	if err := database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product").First(&order, order.ID).Error; err != nil {
 		log.Printf("Error loading order details: %v", err)
@@ -301,6 +338,38 @@ func CreateDraftOrder(c *gin.Context) {
 	c.JSON(http.StatusCreated, order)
 }
 
+// findOrCreateDraftOrder returns the store's open draft with a supplier,
+// creating one if there is none. A store only ever has one draft per supplier.
+func findOrCreateDraftOrder(tx *gorm.DB, storeID, supplierID uint, actor orderActor) (models.Order, bool, error) {
+	var order models.Order
+	err := tx.Where("store_id = ? AND supplier_id = ? AND status = ?", storeID, supplierID, models.OrderStatusDraft).First(&order).Error
+	if err == nil {
+		return order, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return order, false, err
+	}
+
+	order = models.Order{
+		StoreID:     storeID,
+		SupplierID:  supplierID,
+		Status:      models.OrderStatusDraft,
+		TotalAmount: 0,
+	}
+
+	log.Printf("Creating draft order: store_id=%d, supplier_id=%d", storeID, supplierID)
+
+	if err := tx.Create(&order).Error; err != nil {
+		return order, false, err
+	}
+	if err := recordOrderStatusHistory(tx, order.ID, "", models.OrderStatusDraft, actor, ""); err != nil {
+		return order, false, err
+	}
+
+	log.Printf("Order created successfully: id=%d", order.ID)
+	return order, true, nil
+}
+
 func AddOrderItem(c *gin.Context) {
 	orderID := c.Param("id")
 	userID, err := getUserID(c)
@@ -337,66 +406,12 @@ func AddOrderItem(c *gin.Context) {
 		return
 	}
 
-	var product models.Product
-	if err := database.DB.Where("id = ? AND supplier_id = ?", req.ProductID, order.SupplierID).First(&product).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
-		return
-	}
-
-	var existingItem models.OrderItem
-	var totalQuantity int
-	if err := database.DB.Where("order_id = ? AND product_id = ?", orderID, req.ProductID).First(&existingItem).Error; err == nil {
-		totalQuantity = existingItem.Quantity + req.Quantity
-	} else {
-		totalQuantity = req.Quantity
-	}
-
-	if totalQuantity > product.StockQuantity {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("insufficient stock: only %d %s available", product.StockQuantity, product.Unit),
-		})
-		return
-	}
-
-	previousStock := product.StockQuantity
-
-	if err := database.DB.Where("order_id = ? AND product_id = ?", orderID, req.ProductID).First(&existingItem).Error; err == nil {
-		existingItem.Quantity += req.Quantity
-		existingItem.Subtotal = float64(existingItem.Quantity) * existingItem.UnitPrice
-		database.DB.Save(&existingItem)
-	} else {
-		orderItem := models.OrderItem{
-			OrderID:   order.ID,
-			ProductID: req.ProductID,
-			Quantity:  req.Quantity,
-			UnitPrice: product.Price,
-			Subtotal:  product.Price * float64(req.Quantity),
-		}
-		database.DB.Create(&orderItem)
-	}
-
-	product.StockQuantity -= req.Quantity
-	if err := database.DB.Save(&product).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update product stock"})
+	err = applyOrderItemChange(orderItemChange{OrderID: order.ID, ProductID: req.ProductID, Quantity: req.Quantity, Add: true}, getOrderActor(c), reservationTTL(c))
+	if err != nil {
+		writeOrderError(c, err, "failed to add order item")
 		return
 	}
 
-	var userIDPtr *uint
-	if userID > 0 {
-		userIDPtr = &userID
-	}
-	var employeeIDPtr *uint
-	if empCtx.IsEmployee {
-		employeeIDPtr = &empCtx.EmployeeID
-	}
-	orderIDUint := uint(order.ID)
-	logStockChange(product.ID, previousStock, product.StockQuantity, "order_item_added", userIDPtr, employeeIDPtr, &orderIDUint, "")
-
-	var totalAmount float64
-	database.DB.Model(&models.OrderItem{}).Where("order_id = ?", orderID).Select("COALESCE(SUM(subtotal), 0)").Scan(&totalAmount)
-	order.TotalAmount = totalAmount
-	database.DB.Save(&order)
-
 	database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product").First(&order, order.ID)
 
 	c.JSON(http.StatusOK, order)
@@ -440,49 +455,12 @@ func UpdateOrderItem(c *gin.Context) {
 		return
 	}
 
-	var product models.Product
-	if err := database.DB.First(&product, orderItem.ProductID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
-		return
-	}
-
-	previousStock := product.StockQuantity
-	quantityDiff := req.Quantity - orderItem.Quantity
-	newStock := product.StockQuantity - quantityDiff
-
-	if newStock < 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("insufficient stock: only %d %s available", product.StockQuantity+orderItem.Quantity, product.Unit),
-		})
-		return
-	}
-
-	product.StockQuantity = newStock
-	if err := database.DB.Save(&product).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update product stock"})
+	err = applyOrderItemChange(orderItemChange{OrderID: orderItem.OrderID, ProductID: orderItem.ProductID, Quantity: req.Quantity}, getOrderActor(c), reservationTTL(c))
+	if err != nil {
+		writeOrderError(c, err, "failed to update order item")
 		return
 	}
 
-	var userIDPtr *uint
-	if userID > 0 {
-		userIDPtr = &userID
-	}
-	var employeeIDPtr *uint
-	if empCtx.IsEmployee {
-		employeeIDPtr = &empCtx.EmployeeID
-	}
-	orderIDUint := uint(orderItem.OrderID)
-	logStockChange(product.ID, previousStock, product.StockQuantity, "order_item_updated", userIDPtr, employeeIDPtr, &orderIDUint, "")
-
-	orderItem.Quantity = req.Quantity
-	orderItem.Subtotal = orderItem.UnitPrice * float64(req.Quantity)
-	database.DB.Save(&orderItem)
-
-	var totalAmount float64
-	database.DB.Model(&models.OrderItem{}).Where("order_id = ?", orderItem.OrderID).Select("COALESCE(SUM(subtotal), 0)").Scan(&totalAmount)
-	orderItem.Order.TotalAmount = totalAmount
-	database.DB.Save(&orderItem.Order)
-
 	database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product").First(&orderItem.Order, orderItem.OrderID)
 
 	c.JSON(http.StatusOK, orderItem.Order)
@@ -519,38 +497,12 @@ func RemoveOrderItem(c *gin.Context) {
 		return
 	}
 
-	var product models.Product
-	if err := database.DB.First(&product, orderItem.ProductID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
-		return
-	}
-
-	previousStock := product.StockQuantity
-	product.StockQuantity += orderItem.Quantity
-	if err := database.DB.Save(&product).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore product stock"})
+	err = applyOrderItemChange(orderItemChange{OrderID: orderItem.OrderID, ProductID: orderItem.ProductID}, getOrderActor(c), reservationTTL(c))
+	if err != nil {
+		writeOrderError(c, err, "failed to remove order item")
 		return
 	}
 
-	var userIDPtr *uint
-	if userID > 0 {
-		userIDPtr = &userID
-	}
-	var employeeIDPtr *uint
-	if empCtx.IsEmployee {
-		employeeIDPtr = &empCtx.EmployeeID
-	}
-	orderIDUint := uint(orderItem.OrderID)
-	logStockChange(product.ID, previousStock, product.StockQuantity, "order_item_removed", userIDPtr, employeeIDPtr, &orderIDUint, "")
-
-	orderID := orderItem.OrderID
-	database.DB.Delete(&orderItem)
-
-	var totalAmount float64
-	database.DB.Model(&models.OrderItem{}).Where("order_id = ?", orderID).Select("COALESCE(SUM(subtotal), 0)").Scan(&totalAmount)
-	orderItem.Order.TotalAmount = totalAmount
-	database.DB.Save(&orderItem.Order)
-
 	c.JSON(http.StatusOK, gin.H{"message": "item removed"})
 }
 
@@ -580,29 +532,76 @@ func SubmitOrder(c *gin.Context) {
 		return
 	}
 
-	if len(order.OrderItems) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot submit order with no items"})
+	var req orderSubmission
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var req struct {
-		PaymentMethod   string  `json:"payment_method" binding:"required"`
-		DeliveryOption  string  `json:"delivery_option" binding:"required"`
-		DeliveryFee     float64 `json:"delivery_fee"`
-		Distance        float64 `json:"distance"`
-		ShippingAddress string  `json:"shipping_address"`
-		PaymentProofURL string  `json:"payment_proof_url"`
-		Notes           string  `json:"notes"`
+	var transition models.OrderTransition
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Locking the draft keeps a concurrent submit or checkout from
+		// submitting it twice.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("OrderItems").
+			Where("id = ? AND status = ?", order.ID, models.OrderStatusDraft).
+			First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &orderError{Code: http.StatusConflict, Message: "order has already been submitted"}
+			}
+			return err
+		}
+		if err := prepareOrderSubmission(tx, &order, req, true); err != nil {
+			return err
+		}
+		var err error
+		transition, err = submitOrderTx(tx, &order, getOrderActor(c), "")
+		return err
+	})
+	if err != nil {
+		log.Printf("Error submitting order: %v", err)
+		writeOrderError(c, err, "failed to submit order")
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	log.Printf("Order submitted successfully: id=%d", order.ID)
+
+	if err := database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product").First(&order, order.ID).Error; err != nil {
+		log.Printf("Error loading order details: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load order details", "details": err.Error()})
 		return
 	}
 
+	runOrderNotifications(getEmailService(c), order, models.OrderStatusDraft, transition)
+
+	c.JSON(http.StatusOK, order)
+}
+
+// orderSubmission holds the checkout choices made when a draft is submitted.
+type orderSubmission struct {
+	PaymentMethod   string `json:"payment_method" binding:"required"`
+	DeliveryOption  string `json:"delivery_option" binding:"required"`
+	ShippingAddress string `json:"shipping_address"`
+	PaymentProofURL string `json:"payment_proof_url"`
+	Notes           string `json:"notes"`
+	DeliverySlotID  *uint  `json:"delivery_slot_id"`
+	DeliveryDate    string `json:"delivery_date"` // YYYY-MM-DD, required with DeliverySlotID
+}
+
+// prepareOrderSubmission runs the checks every submitted order must pass and
+// applies the checkout choices to order, which must have OrderItems loaded.
+// The minimum, delivery fee and distance come from the supplier's delivery
+// settings, never the client. Unless requireOpen is false, a supplier that is
+// closed right now refuses the order. Nothing is saved; failures are returned
+// as an orderError.
+func prepareOrderSubmission(tx *gorm.DB, order *models.Order, req orderSubmission, requireOpen bool) error {
+	if len(order.OrderItems) == 0 {
+		return &orderError{Code: http.StatusBadRequest, Message: "cannot submit order with no items"}
+	}
+
 	validPaymentMethods := map[string]bool{
 		"cash_on_delivery": true,
 		"gcash":            true,
+		"credit":           true,
 	}
 
 	validDeliveryOptions := map[string]bool{
@@ -610,14 +609,12 @@ func SubmitOrder(c *gin.Context) {
 		"deliver": true,
 	}
 
-	if !validPaymentMethods[req.PaymentMethod] {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment method"})
-		return
+	if _, online := paymentProviders.Get(req.PaymentMethod); !validPaymentMethods[req.PaymentMethod] && !online {
+		return &orderError{Code: http.StatusBadRequest, Message: "invalid payment method"}
 	}
 
 	if !validDeliveryOptions[req.DeliveryOption] {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delivery option"})
-		return
+		return &orderError{Code: http.StatusBadRequest, Message: "invalid delivery option"}
 	}
 
 	var subtotal float64
@@ -625,77 +622,82 @@ func SubmitOrder(c *gin.Context) {
 		subtotal += item.Subtotal
 	}
 
-	const minimumOrderAmount = 5000.0
-	if subtotal < minimumOrderAmount {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("minimum order amount is ₱%.2f. Current total: ₱%.2f", minimumOrderAmount, subtotal),
-		})
-		return
+	settings, err := loadDeliverySettings(tx, order.SupplierID)
+	if err != nil {
+		return err
+	}
+	if subtotal < settings.MinimumOrderAmount {
+		return &orderError{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("minimum order amount is ₱%.2f. Current total: ₱%.2f", settings.MinimumOrderAmount, subtotal),
+		}
 	}
 
 	var store models.User
-	if err := database.DB.First(&store, order.StoreID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "store not found"})
-		return
+	if err := tx.First(&store, order.StoreID).Error; err != nil {
+		return &orderError{Code: http.StatusNotFound, Message: "store not found"}
+	}
+	var supplier models.User
+	if err := tx.First(&supplier, order.SupplierID).Error; err != nil {
+		return &orderError{Code: http.StatusNotFound, Message: "supplier not found"}
+	}
+	if now := nowInPH(); requireOpen && (!isOpenNow(supplier, now) || isSupplierClosedOn(tx, supplier, now)) {
+		return &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("%s is closed and not accepting orders", supplier.Name)}
 	}
 
-	if req.PaymentMethod == "gcash" {
-		order.PaymentStatus = models.PaymentStatusPending
-		if req.PaymentProofURL != "" {
-			order.PaymentProofURL = req.PaymentProofURL
-		}
-	} else {
-		order.PaymentStatus = models.PaymentStatusPaid
+	quote, err := quoteDelivery(settings, supplier, store, models.DeliveryOption(req.DeliveryOption), subtotal)
+	if err != nil {
+		return err
+	}
+
+	// Payment status follows the ledger; cash is recorded when collected.
+	if req.PaymentMethod == "gcash" && req.PaymentProofURL != "" {
+		order.PaymentProofURL = req.PaymentProofURL
 	}
 
-	order.Status = models.OrderStatusPreparing
 	order.PaymentMethod = models.PaymentMethod(req.PaymentMethod)
 	order.DeliveryOption = models.DeliveryOption(req.DeliveryOption)
-	order.DeliveryFee = req.DeliveryFee
-	order.Distance = req.Distance
-	order.TotalAmount = subtotal + req.DeliveryFee
+	order.DeliveryFee = quote.DeliveryFee
+	order.Distance = quote.DistanceKm
+	order.TotalAmount = subtotal + quote.DeliveryFee
 	if req.DeliveryOption == "deliver" {
 		if req.ShippingAddress != "" {
 			order.ShippingAddress = req.ShippingAddress
 		} else {
-			var storeUser models.User
-			if err := database.DB.First(&storeUser, userID).Error; err != nil {
-				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
-				return
+			if store.Address == "" {
+				return &orderError{Code: http.StatusBadRequest, Message: "shipping address is required when delivery option is 'deliver'. Please update your address in your profile."}
 			}
-			if storeUser.Address == "" {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "shipping address is required when delivery option is 'deliver'. Please update your address in your profile."})
-				return
-			}
-			order.ShippingAddress = storeUser.Address
+			order.ShippingAddress = store.Address
 		}
 	}
 	if req.Notes != "" {
 		order.Notes = req.Notes
 	}
 
-	if err := database.DB.Save(&order).Error; err != nil {
-		log.Printf("Error submitting order: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to submit order", "details": err.Error()})
-		return
+	if order.PaymentMethod == models.PaymentMethodCredit {
+		if err := applyCreditTerms(tx, order, nowInPH()); err != nil {
+			return err
+		}
 	}
 
-	log.Printf("Order submitted successfully: id=%d", order.ID)
+	return applyDeliverySlot(tx, order, req.DeliverySlotID, req.DeliveryDate, nowInPH())
+}
 
-	if err := database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product").First(&order, order.ID).Error; err != nil {
-		log.Printf("Error loading order details: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load order details", "details": err.Error()})
-		return
+// submitOrderTx moves a prepared draft to preparing, turning its stock
+// reservations into deductions, and saves it.
+func submitOrderTx(tx *gorm.DB, order *models.Order, actor orderActor, reason string) (models.OrderTransition, error) {
+	transition, err := transitionOrder(tx, order, models.OrderStatusPreparing, actor, reason, false)
+	if err != nil {
+		return transition, err
 	}
-
-	emailService := getEmailService(c)
-	if emailService != nil {
-		go emailService.SendOrderSuccessEmail(order)
+	if err := consumeOrderReservations(tx, order, actor); err != nil {
+		return transition, err
 	}
-
-	c.JSON(http.StatusOK, order)
+	return transition, tx.Omit("OrderItems").Save(order).Error
 }
 
+// MarkPaymentAsPaid records a ledger entry for a GCash order's outstanding
+// balance. VerifyPayment also keeps the reference number that was checked.
 func MarkPaymentAsPaid(c *gin.Context) {
 	orderID := c.Param("id")
 	userID, err := getUserID(c)
@@ -718,26 +720,30 @@ func MarkPaymentAsPaid(c *gin.Context) {
 		return
 	}
 
+	actor := getOrderActor(c)
 	var order models.Order
-	if err := database.DB.Where("id = ? AND supplier_id = ?", orderID, userID).First(&order).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "order not found or access denied"})
-		return
-	}
-
-	if order.PaymentMethod != models.PaymentMethodGCash {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "payment confirmation is only applicable for GCash orders"})
-		return
-	}
-
-	if order.PaymentStatus == models.PaymentStatusPaid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "payment is already marked as paid"})
-		return
-	}
-
-	order.PaymentStatus = models.PaymentStatusPaid
-	if err := database.DB.Save(&order).Error; err != nil {
-		log.Printf("MarkPaymentAsPaid: failed to update payment status. orderID=%s, userID=%d, error=%v", orderID, userID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update payment status", "details": err.Error()})
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND supplier_id = ?", orderID, userID).First(&order).Error; err != nil {
+			return &orderError{Code: http.StatusNotFound, Message: "order not found or access denied"}
+		}
+		if order.PaymentMethod != models.PaymentMethodGCash {
+			return &orderError{Code: http.StatusBadRequest, Message: "payment confirmation is only applicable for GCash orders"}
+		}
+		balance := roundTo2(order.TotalAmount - order.AmountPaid)
+		if balance <= 0 {
+			return &orderError{Code: http.StatusBadRequest, Message: "payment is already marked as paid"}
+		}
+		return recordPayment(tx, &order, models.Payment{
+			Method:       order.PaymentMethod,
+			Amount:       balance,
+			Note:         "marked as paid",
+			RecordedByID: actor.userIDPtr(),
+			EmployeeID:   actor.EmployeeID,
+		})
+	})
+	if err != nil {
+		log.Printf("MarkPaymentAsPaid: failed to record payment. orderID=%s, userID=%d, error=%v", orderID, userID, err)
+		writeOrderError(c, err, "failed to update payment status")
 		return
 	}
 
@@ -755,6 +761,8 @@ func MarkPaymentAsPaid(c *gin.Context) {
 	c.JSON(http.StatusOK, order)
 }
 
+// MarkPaymentAsPending reverses everything paid on an order with a negative
+// ledger entry, leaving the original entries in place.
 func MarkPaymentAsPending(c *gin.Context) {
 	orderID := c.Param("id")
 	userID, err := getUserID(c)
@@ -777,26 +785,29 @@ func MarkPaymentAsPending(c *gin.Context) {
 		return
 	}
 
+	actor := getOrderActor(c)
 	var order models.Order
-	if err := database.DB.Where("id = ? AND supplier_id = ?", orderID, userID).First(&order).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "order not found or access denied"})
-		return
-	}
-
-	if order.PaymentMethod != models.PaymentMethodGCash && order.PaymentMethod != models.PaymentMethodCashOnDelivery {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "payment revert is only applicable for GCash or cash on delivery orders"})
-		return
-	}
-
-	if order.PaymentStatus == models.PaymentStatusPending {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "payment is already pending"})
-		return
-	}
-
-	order.PaymentStatus = models.PaymentStatusPending
-	if err := database.DB.Save(&order).Error; err != nil {
-		log.Printf("MarkPaymentAsPending: failed to update payment status. orderID=%s, userID=%d, error=%v", orderID, userID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update payment status", "details": err.Error()})
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND supplier_id = ?", orderID, userID).First(&order).Error; err != nil {
+			return &orderError{Code: http.StatusNotFound, Message: "order not found or access denied"}
+		}
+		if order.PaymentMethod == "" {
+			return &orderError{Code: http.StatusBadRequest, Message: "payment revert is only applicable for submitted orders"}
+		}
+		if order.AmountPaid <= 0 {
+			return &orderError{Code: http.StatusBadRequest, Message: "payment is already unpaid"}
+		}
+		return recordPayment(tx, &order, models.Payment{
+			Method:       order.PaymentMethod,
+			Amount:       -order.AmountPaid,
+			Note:         "payment reverted",
+			RecordedByID: actor.userIDPtr(),
+			EmployeeID:   actor.EmployeeID,
+		})
+	})
+	if err != nil {
+		log.Printf("MarkPaymentAsPending: failed to reverse payment. orderID=%s, userID=%d, error=%v", orderID, userID, err)
+		writeOrderError(c, err, "failed to update payment status")
 		return
 	}
 
@@ -899,7 +910,7 @@ func DownloadInvoice(c *gin.Context) {
 	}
 
 	var order models.Order
-	query := database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product").Where("id = ?", id)
+	query := database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product").Preload("OrderItems.SubstituteProduct").Preload("DeliveryProof").Where("id = ?", id)
 
 	switch role {
 	case "supplier":
@@ -918,45 +929,14 @@ func DownloadInvoice(c *gin.Context) {
 		return
 	}
 
-	pdf := gofpdf.New("P", "mm", "A4", "")
-	pdf.SetMargins(12, 15, 12)
-	pdf.SetAutoPageBreak(true, 20)
-	pdf.AddPage()
-	hasLogo := registerLogo(pdf)
-
-	primaryBlue := struct{ r, g, b int }{r: 0, g: 86, b: 155}
-	teal := struct{ r, g, b int }{r: 0, g: 170, b: 190}
-
-	// Header band
-	pdf.SetFillColor(primaryBlue.r, primaryBlue.g, primaryBlue.b)
-	pdf.Rect(0, 0, 210, 24, "F")
-
-	// Curved accent using teal
-	pdf.SetFillColor(teal.r, teal.g, teal.b)
-	pdf.Rect(0, 17, 210, 9, "F")
+	cfgVal, _ := c.Get("config")
+	cfg := cfgVal.(*config.Config)
+	if cfg.S3Bucket == "" || cfg.AWSRegion == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "S3 configuration is missing"})
+		return
+	}
 
-	// Header text
-	pdf.SetTextColor(255, 255, 255)
-	pdf.SetFont("Arial", "B", 18)
-	pdf.SetXY(15, 8)
-	pdf.CellFormat(60, 8, "Invoice", "", 0, "L", false, 0, "")
-	pdf.SetFont("Arial", "B", 12)
-	pdf.SetXY(135, 8)
-	pdf.CellFormat(60, 8, fmt.Sprintf("No: %d", order.ID), "", 0, "R", false, 0, "")
-
-	// Center logo in header if registered
-	if hasLogo {
-		pageW, _ := pdf.GetPageSize()
-		left, _, right, _ := pdf.GetMargins()
-		usable := pageW - left - right
-		logoW := 45.0
-		x := left + (usable-logoW)/2
-		pdf.ImageOptions("app-logo", x, 5, logoW, 0, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
-	}
-
-	// Body start
-	pdf.SetY(26)
-	pdf.SetTextColor(0, 0, 0)
+	pdf := newBrandedPDF("Invoice", fmt.Sprintf("No: %d", order.ID))
 	pdf.SetFont("Arial", "", 11)
 	pdf.CellFormat(0, 7, fmt.Sprintf("Date: %s", order.CreatedAt.Format("2006-01-02")), "", 1, "R", false, 0, "")
 	pdf.Ln(2)
@@ -1023,13 +1003,17 @@ func DownloadInvoice(c *gin.Context) {
 			name = fmt.Sprintf("Product %d", item.ProductID)
 		}
 		unit := item.Product.Unit
+		if item.FulfilmentStatus == models.ItemFulfilmentSubstituted && item.SubstituteProduct != nil {
+			name = fmt.Sprintf("%s (for %s)", item.SubstituteProduct.Name, name)
+			unit = item.SubstituteProduct.Unit
+		}
 		if unit == "" {
 			unit = "-"
 		}
 		pdf.CellFormat(78, 8, name, "1", 0, "L", false, 0, "")
-		pdf.CellFormat(22, 8, fmt.Sprintf("%d", item.Quantity), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(22, 8, fmt.Sprintf("%d", item.BilledQuantity()), "1", 0, "L", false, 0, "")
 		pdf.CellFormat(26, 8, unit, "1", 0, "L", false, 0, "")
-		pdf.CellFormat(30, 8, fmt.Sprintf("PHP %.2f", item.UnitPrice), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(30, 8, fmt.Sprintf("PHP %.2f", item.BilledUnitPrice()), "1", 0, "L", false, 0, "")
 		pdf.CellFormat(30, 8, fmt.Sprintf("PHP %.2f", item.Subtotal), "1", 1, "L", false, 0, "")
 	}
 
@@ -1037,28 +1021,16 @@ func DownloadInvoice(c *gin.Context) {
 	pdf.CellFormat(156, 8, "Total", "1", 0, "R", false, 0, "")
 	pdf.CellFormat(30, 8, fmt.Sprintf("PHP %.2f", order.TotalAmount), "1", 1, "L", false, 0, "")
 
-	var buf bytes.Buffer
-	pageH, _ := pdf.GetPageSize()
-	if pdf.GetY() > pageH-25 {
-		pdf.SetY(pageH - 25)
-	} else {
-		pdf.SetY(pageH - 25)
-	}
-	pdf.SetFont("Arial", "", 9)
-	pdf.CellFormat(0, 6, "Siargao Trading Road | siargaotradingroad.com | info@siargaotradingroad.com", "", 1, "C", false, 0, "")
+	writeDeliveryProofPDF(pdf, cfg, order.DeliveryProof)
+
+	writePDFFooter(pdf)
 
+	var buf bytes.Buffer
 	if err := pdf.Output(&buf); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate invoice"})
 		return
 	}
 
-	cfgVal, _ := c.Get("config")
-	cfg := cfgVal.(*config.Config)
-	if cfg.S3Bucket == "" || cfg.AWSRegion == "" {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "S3 configuration is missing"})
-		return
-	}
-
 	key := fmt.Sprintf("invoices/%d.pdf", order.ID)
 	url, err := uploadInvoiceToS3(cfg, key, buf.Bytes())
 	if err != nil {
@@ -1077,6 +1049,27 @@ func DownloadInvoice(c *gin.Context) {
 
 func uploadInvoiceToS3(cfg *config.Config, key string, content []byte) (string, error) {
 	ctx := context.Background()
+	svc, err := newS3Client(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	_, err = svc.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(cfg.S3Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(content),
+		ContentType: aws.String("application/pdf"),
+	})
+	if err != nil {
+		return "", fmt.Errorf("put object: %w", err)
+	}
+
+	url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", cfg.S3Bucket, cfg.AWSRegion, key)
+	return url, nil
+}
+
+// newS3Client builds a client for the configured region, using the static
+// credentials when they are set and the default chain otherwise.
+func newS3Client(ctx context.Context, cfg *config.Config) (*s3.Client, error) {
 	var awsCfg aws.Config
 	var cfgErr error
 
@@ -1096,69 +1089,9 @@ func uploadInvoiceToS3(cfg *config.Config, key string, content []byte) (string,
 	}
 
 	if cfgErr != nil {
-		return "", fmt.Errorf("create config: %w", cfgErr)
-	}
-
-	svc := s3.NewFromConfig(awsCfg)
-	_, err := svc.PutObject(ctx, &s3.PutObjectInput{
-		Bucket:      aws.String(cfg.S3Bucket),
-		Key:         aws.String(key),
-		Body:        bytes.NewReader(content),
-		ContentType: aws.String("application/pdf"),
-	})
-	if err != nil {
-		return "", fmt.Errorf("put object: %w", err)
+		return nil, fmt.Errorf("create config: %w", cfgErr)
 	}
-
-	url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", cfg.S3Bucket, cfg.AWSRegion, key)
-	return url, nil
-}
-
-func registerLogo(pdf *gofpdf.Fpdf) bool {
-	// Try embedded first
-	if len(embeddedSplash) > 0 {
-		if pdf.RegisterImageOptionsReader("app-logo", gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(embeddedSplash)) == nil {
-			pageW, _ := pdf.GetPageSize()
-			left, _, right, _ := pdf.GetMargins()
-			usable := pageW - left - right
-			logoW := 70.0
-			x := left + (usable-logoW)/2
-			pdf.ImageOptions("app-logo", x, 10, logoW, 0, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
-			return true
-		}
-	}
-
-	// Fallback to file paths
-	candidates := []string{
-		filepath.Join("assets", "splash.png"),
-		filepath.Join("golang", "assets", "splash.png"),
-	}
-	if cwd, err := os.Getwd(); err == nil {
-		candidates = append(candidates, filepath.Join(cwd, "assets", "splash.png"))
-		candidates = append(candidates, filepath.Join(cwd, "golang", "assets", "splash.png"))
-	}
-	if exe, err := os.Executable(); err == nil {
-		exeDir := filepath.Dir(exe)
-		candidates = append(candidates, filepath.Join(exeDir, "assets", "splash.png"))
-		candidates = append(candidates, filepath.Join(exeDir, "..", "assets", "splash.png"))
-	}
-
-	for _, logoPath := range candidates {
-		if logoBytes, err := os.ReadFile(logoPath); err == nil {
-			if pdf.RegisterImageOptionsReader("app-logo", gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(logoBytes)) == nil {
-				pageW, _ := pdf.GetPageSize()
-				left, _, right, _ := pdf.GetMargins()
-				usable := pageW - left - right
-				logoW := 70.0
-				x := left + (usable-logoW)/2
-				pdf.ImageOptions("app-logo", x, 10, logoW, 0, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
-				return true
-			}
-		}
-	}
-
-	log.Printf("DownloadInvoice: logo not found in any candidate path")
-	return false
+	return s3.NewFromConfig(awsCfg), nil
 }
 
 func GetOrderMessages(c *gin.Context) {
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultOrderPageSize = 20
+	maxOrderPageSize     = 100
+)
+
+// orderSortColumns are the columns GetOrders can sort by.
+var orderSortColumns = map[string]string{
+	"created_at":    "created_at",
+	"updated_at":    "updated_at",
+	"total_amount":  "total_amount",
+	"status":        "status",
+	"delivery_date": "delivery_date",
+}
+
+// applyOrderListFilters narrows an order query by the GetOrders query
+// parameters. List parameters take comma separated values or repeat. Only
+// stores can list drafts, and only by asking for status=draft.
+func applyOrderListFilters(c *gin.Context, query *gorm.DB, role interface{}) (*gorm.DB, error) {
+	statuses := queryList(c, "status")
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	if len(statuses) == 0 || role != "store" {
+		query = query.Where("status != ?", models.OrderStatusDraft)
+	}
+	if values := queryList(c, "payment_status"); len(values) > 0 {
+		query = query.Where("payment_status IN ?", values)
+	}
+	if values := queryList(c, "payment_method"); len(values) > 0 {
+		query = query.Where("payment_method IN ?", values)
+	}
+	if values := queryList(c, "delivery_option"); len(values) > 0 {
+		query = query.Where("delivery_option IN ?", values)
+	}
+
+	// Dates are Philippine calendar days and both ends are inclusive.
+	if value := c.Query("from"); value != "" {
+		day, err := parseDeliveryDate(value)
+		if err != nil {
+			return nil, fmt.Errorf("from must be YYYY-MM-DD")
+		}
+		query = query.Where("created_at >= ?", day.UTC())
+	}
+	if value := c.Query("to"); value != "" {
+		day, err := parseDeliveryDate(value)
+		if err != nil {
+			return nil, fmt.Errorf("to must be YYYY-MM-DD")
+		}
+		query = query.Where("created_at < ?", day.AddDate(0, 0, 1).UTC())
+	}
+
+	// The counterpart is the store for suppliers and the supplier for stores;
+	// admins may filter on either.
+	if value := c.Query("store_id"); value != "" && role != "store" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid store_id")
+		}
+		query = query.Where("store_id = ?", uint(id))
+	}
+	if value := c.Query("supplier_id"); value != "" && role != "supplier" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid supplier_id")
+		}
+		query = query.Where("supplier_id = ?", uint(id))
+	}
+
+	if value := c.Query("min_total"); value != "" {
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid min_total")
+		}
+		query = query.Where("total_amount >= ?", amount)
+	}
+	if value := c.Query("max_total"); value != "" {
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_total")
+		}
+		query = query.Where("total_amount <= ?", amount)
+	}
+
+	return query, nil
+}
+
+// orderListSort reads sort_by and order, newest first by default. The id
+// breaks ties so pages do not overlap.
+func orderListSort(c *gin.Context) (string, error) {
+	column, ok := orderSortColumns[c.DefaultQuery("sort_by", "created_at")]
+	if !ok {
+		return "", fmt.Errorf("invalid sort_by")
+	}
+	direction := strings.ToUpper(c.DefaultQuery("order", "desc"))
+	if direction != "ASC" && direction != "DESC" {
+		return "", fmt.Errorf("order must be asc or desc")
+	}
+	return fmt.Sprintf("%s %s, id %s", column, direction, direction), nil
+}
+
+// queryList collects a query parameter given either repeated or comma
+// separated.
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(key) {
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
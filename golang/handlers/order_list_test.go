@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func buildOrderListRouter(user models.User) *gin.Engine {
+	r := buildOrderRouter(user)
+	r.GET("/orders", GetOrders)
+	return r
+}
+
+func TestGetOrdersFiltersAndPaginates(t *testing.T) {
+	store, supplier := setupOrderTestDB(t)
+	other := models.User{Email: "other@example.com", Password: "x", Name: "Other", Role: models.RoleStore}
+	database.DB.Create(&other)
+
+	old := time.Date(2026, 1, 10, 3, 0, 0, 0, time.UTC)
+	orders := []models.Order{
+		{StoreID: store.ID, SupplierID: supplier.ID, Status: models.OrderStatusPreparing, TotalAmount: 100, PaymentMethod: models.PaymentMethodGCash, CreatedAt: old},
+		{StoreID: store.ID, SupplierID: supplier.ID, Status: models.OrderStatusInTransit, TotalAmount: 300, PaymentMethod: models.PaymentMethodCashOnDelivery},
+		{StoreID: other.ID, SupplierID: supplier.ID, Status: models.OrderStatusDelivered, TotalAmount: 500, PaymentMethod: models.PaymentMethodCashOnDelivery},
+		{StoreID: store.ID, SupplierID: supplier.ID, Status: models.OrderStatusDraft, TotalAmount: 50},
+	}
+	for i := range orders {
+		database.DB.Create(&orders[i])
+	}
+	router := buildOrderListRouter(supplier)
+
+	w := doOrderRequest(router, http.MethodGet, "/orders", "")
+	var all []models.Order
+	if err := json.Unmarshal(w.Body.Bytes(), &all); err != nil || len(all) != 3 {
+		t.Fatalf("expected the unpaginated list of 3 orders, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var page struct {
+		Data       []models.Order `json:"data"`
+		Pagination struct {
+			Page  int   `json:"page"`
+			Total int64 `json:"total"`
+			Pages int64 `json:"pages"`
+		} `json:"pagination"`
+	}
+	w = doOrderRequest(router, http.MethodGet, "/orders?status=preparing,in_transit&payment_method=gcash&payment_method=cash_on_delivery&min_total=50&max_total=400&sort_by=total_amount&order=asc&limit=1&page=2", "")
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if w.Code != http.StatusOK || page.Pagination.Total != 2 || page.Pagination.Pages != 2 || len(page.Data) != 1 || page.Data[0].ID != orders[1].ID {
+		t.Fatalf("unexpected page: %s", w.Body.String())
+	}
+
+	w = doOrderRequest(router, http.MethodGet, "/orders?status=draft,preparing&limit=10", "")
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if page.Pagination.Total != 1 || page.Data[0].ID != orders[0].ID {
+		t.Fatalf("expected suppliers not to see drafts, got %s", w.Body.String())
+	}
+	w = doOrderRequest(buildOrderListRouter(store), http.MethodGet, "/orders?status=draft&limit=10", "")
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if page.Pagination.Total != 1 || page.Data[0].ID != orders[3].ID {
+		t.Fatalf("expected the store's draft, got %s", w.Body.String())
+	}
+
+	w = doOrderRequest(router, http.MethodGet, "/orders?from=2026-01-10&to=2026-01-10&limit=10", "")
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if page.Pagination.Total != 1 || page.Data[0].ID != orders[0].ID {
+		t.Fatalf("expected the January order only, got %s", w.Body.String())
+	}
+
+	w = doOrderRequest(router, http.MethodGet, fmt.Sprintf("/orders?store_id=%d&summary=true&limit=10", other.ID), "")
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if page.Pagination.Total != 1 || page.Data[0].ID != orders[2].ID {
+		t.Fatalf("expected the other store's order only, got %s", w.Body.String())
+	}
+
+	if w := doOrderRequest(router, http.MethodGet, "/orders?sort_by=password", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected unknown sort column to be refused, got %d", w.Code)
+	}
+}
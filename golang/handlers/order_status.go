@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+	"siargao-trading-road/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// orderActor identifies who is changing an order.
+type orderActor struct {
+	UserID     uint
+	Role       models.UserRole
+	EmployeeID *uint
+}
+
+func getOrderActor(c *gin.Context) orderActor {
+	userID, _ := getUserID(c)
+	role, _ := c.Get("role")
+	roleStr, _ := role.(string)
+
+	actor := orderActor{UserID: userID, Role: models.UserRole(roleStr)}
+	empCtx := getEmployeeContext(c)
+	if empCtx.IsEmployee && empCtx.EmployeeID > 0 {
+		employeeID := empCtx.EmployeeID
+		actor.EmployeeID = &employeeID
+	}
+	return actor
+}
+
+func (a orderActor) userIDPtr() *uint {
+	if a.UserID == 0 {
+		return nil
+	}
+	userID := a.UserID
+	return &userID
+}
+
+// orderError carries the HTTP status to answer with when an order change is refused.
+type orderError struct {
+	Code    int
+	Message string
+}
+
+func (e *orderError) Error() string {
+	return e.Message
+}
+
+// writeOrderError responds with the code of an orderError, or 500 otherwise.
+func writeOrderError(c *gin.Context, err error, fallback string) {
+	var orderErr *orderError
+	if errors.As(err, &orderErr) {
+		c.JSON(orderErr.Code, gin.H{"error": orderErr.Message})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback, "details": err.Error()})
+}
+
+// transitionOrder validates a status change against the transition table,
+// applies it and appends to the order's status history. It must run inside
+// the caller's transaction. manual is true for the generic status endpoint,
+// which may not use internal transitions.
+func transitionOrder(tx *gorm.DB, order *models.Order, to models.OrderStatus, actor orderActor, reason string, manual bool) (models.OrderTransition, error) {
+	if !to.IsValid() {
+		return models.OrderTransition{}, &orderError{Code: http.StatusBadRequest, Message: "invalid status"}
+	}
+
+	from := order.Status
+	transition, ok := models.FindOrderTransition(from, to)
+	if !ok {
+		if from.IsTerminal() {
+			return transition, &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("order is already %s", from)}
+		}
+		return transition, &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("cannot change order status from %s to %s", from, to)}
+	}
+	if manual && transition.Internal {
+		return transition, &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("orders cannot be moved from %s to %s directly", from, to)}
+	}
+	if !transition.AllowsRole(actor.Role) {
+		return transition, &orderError{Code: http.StatusForbidden, Message: fmt.Sprintf("%s cannot change order status from %s to %s", actor.Role, from, to)}
+	}
+
+	updates := map[string]interface{}{"status": to}
+	if to == models.OrderStatusCancelled {
+		if strings.TrimSpace(reason) == "" {
+			return transition, &orderError{Code: http.StatusBadRequest, Message: "cancellation reason is required"}
+		}
+		updates["cancellation_reason"] = reason
+		order.CancellationReason = reason
+	}
+
+	// The status guard makes a concurrent transition from the same status
+	// lose instead of applying its effects a second time.
+	result := tx.Model(&models.Order{}).Where("id = ? AND status = ?", order.ID, from).Updates(updates)
+	if result.Error != nil {
+		return transition, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return transition, &orderError{Code: http.StatusConflict, Message: "order status was changed by another request; reload and try again"}
+	}
+	order.Status = to
+
+	if transition.HasEffect(models.OrderEffectRestoreStock) {
+		if err := restoreOrderStock(tx, order.ID, actor); err != nil {
+			return transition, err
+		}
+	}
+	if transition.HasEffect(models.OrderEffectReleaseReservation) {
+		if err := releaseOrderReservations(tx, order.ID, actor, "draft order cancelled"); err != nil {
+			return transition, err
+		}
+	}
+
+	if err := recordOrderStatusHistory(tx, order.ID, from, to, actor, reason); err != nil {
+		return transition, err
+	}
+
+	return transition, nil
+}
+
+// restoreOrderStock puts the stock held by every item on the order back on
+// its product and logs an order_cancelled stock history row for each.
+func restoreOrderStock(tx *gorm.DB, orderID uint, actor orderActor) error {
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ?", orderID).Find(&items).Error; err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if err := adjustOrderStock(tx, orderID, item.ShippedProductID(), -item.BilledQuantity(), "order_cancelled", actor, ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func recordOrderStatusHistory(tx *gorm.DB, orderID uint, from, to models.OrderStatus, actor orderActor, reason string) error {
+	history := models.OrderStatusHistory{
+		OrderID:    orderID,
+		FromStatus: from,
+		ToStatus:   to,
+		ActorID:    actor.userIDPtr(),
+		ActorRole:  string(actor.Role),
+		EmployeeID: actor.EmployeeID,
+		Reason:     reason,
+	}
+	return tx.Omit("Actor", "Employee").Create(&history).Error
+}
+
+// runOrderNotifications fires the post-commit side effects of a transition.
+// order must have Store, Supplier and OrderItems loaded.
+func runOrderNotifications(emailService *services.EmailService, order models.Order, from models.OrderStatus, transition models.OrderTransition) {
+	if emailService == nil {
+		return
+	}
+	switch {
+	case transition.HasEffect(models.OrderEffectNotifySubmitted):
+		go emailService.SendOrderSuccessEmail(order)
+	case transition.HasEffect(models.OrderEffectNotifyDelivered):
+		go emailService.SendOrderDeliveredEmail(order)
+	case transition.HasEffect(models.OrderEffectNotifyStatusChange):
+		go emailService.SendOrderStatusChangeEmail(order, from)
+	}
+}
+
+func loadOrderStatusHistory(orderID uint) []models.OrderStatusHistory {
+	history := []models.OrderStatusHistory{}
+	database.DB.Preload("Actor").Preload("Employee").
+		Where("order_id = ?", orderID).
+		Order("created_at ASC, id ASC").
+		Find(&history)
+	return history
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupOrderTestDB(t *testing.T) (models.User, models.User) {
+	t.Helper()
+	return openOrderTestDB(t, "file:"+t.Name()+"?mode=memory&cache=shared")
+}
+
+func openOrderTestDB(t *testing.T, dsn string) (models.User, models.User) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Employee{}, &models.Product{}, &models.Order{}, &models.OrderItem{}, &models.StockHistory{}, &models.OrderStatusHistory{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		t.Cleanup(func() { sqlDB.Close() })
+	}
+	database.DB = db
+
+	store := models.User{Email: "store@example.com", Password: "x", Name: "Store", Role: models.RoleStore}
+	supplier := models.User{Email: "supplier@example.com", Password: "x", Name: "Supplier", Role: models.RoleSupplier}
+	if err := db.Create(&store).Error; err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	if err := db.Create(&supplier).Error; err != nil {
+		t.Fatalf("create supplier: %v", err)
+	}
+	return store, supplier
+}
+
+// migrateTestModels adds the tables a feature test needs on top of the order
+// tables created by setupOrderTestDB.
+func migrateTestModels(t *testing.T, tables ...interface{}) {
+	t.Helper()
+	if err := database.DB.AutoMigrate(tables...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+}
+
+func buildOrderRouter(user models.User) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+		c.Set("role", string(user.Role))
+	})
+	r.PUT("/orders/:id/status", UpdateOrderStatus)
+	return r
+}
+
+func doOrderRequest(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func putOrderStatus(router *gin.Engine, orderID uint, body string) *httptest.ResponseRecorder {
+	return doOrderRequest(router, http.MethodPut, fmt.Sprintf("/orders/%d/status", orderID), body)
+}
+
+func createTestOrder(t *testing.T, store, supplier models.User, status models.OrderStatus) models.Order {
+	t.Helper()
+	order := models.Order{StoreID: store.ID, SupplierID: supplier.ID, Status: status}
+	if err := database.DB.Create(&order).Error; err != nil {
+		t.Fatalf("create order: %v", err)
+	}
+	return order
+}
+
+func TestUpdateOrderStatusRecordsHistory(t *testing.T) {
+	store, supplier := setupOrderTestDB(t)
+	order := createTestOrder(t, store, supplier, models.OrderStatusPreparing)
+
+	w := putOrderStatus(buildOrderRouter(supplier), order.ID, `{"status":"in_transit","reason":"truck left"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var history []models.OrderStatusHistory
+	database.DB.Where("order_id = ?", order.ID).Find(&history)
+	if len(history) != 1 {
+		t.Fatalf("expected 1 history row, got %d", len(history))
+	}
+	h := history[0]
+	if h.FromStatus != models.OrderStatusPreparing || h.ToStatus != models.OrderStatusInTransit {
+		t.Fatalf("unexpected transition: %s -> %s", h.FromStatus, h.ToStatus)
+	}
+	if h.ActorID == nil || *h.ActorID != supplier.ID || h.ActorRole != "supplier" || h.Reason != "truck left" {
+		t.Fatalf("unexpected history row: %+v", h)
+	}
+}
+
+func TestUpdateOrderStatusRejectsIllegalTransitions(t *testing.T) {
+	store, supplier := setupOrderTestDB(t)
+	delivered := createTestOrder(t, store, supplier, models.OrderStatusDelivered)
+
+	for _, status := range []string{"draft", "preparing", "cancelled"} {
+		w := putOrderStatus(buildOrderRouter(supplier), delivered.ID, fmt.Sprintf(`{"status":%q}`, status))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("delivered -> %s: expected status 400, got %d", status, w.Code)
+		}
+	}
+
+	var reloaded models.Order
+	database.DB.First(&reloaded, delivered.ID)
+	if reloaded.Status != models.OrderStatusDelivered {
+		t.Fatalf("status changed to %s", reloaded.Status)
+	}
+
+	draft := createTestOrder(t, store, supplier, models.OrderStatusDraft)
+	w := putOrderStatus(buildOrderRouter(store), draft.ID, `{"status":"preparing"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("draft -> preparing must go through submit, got %d", w.Code)
+	}
+}
+
+func TestUpdateOrderStatusEnforcesRoles(t *testing.T) {
+	store, supplier := setupOrderTestDB(t)
+	order := createTestOrder(t, store, supplier, models.OrderStatusPreparing)
+
+	w := putOrderStatus(buildOrderRouter(store), order.ID, `{"status":"in_transit"}`)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", w.Code)
+	}
+
+	var count int64
+	database.DB.Model(&models.OrderStatusHistory{}).Where("order_id = ?", order.ID).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected no history rows, got %d", count)
+	}
+}
+
+func TestCancelOrderRestoresStock(t *testing.T) {
+	store, supplier := setupOrderTestDB(t)
+	product := models.Product{SupplierID: supplier.ID, Name: "Rice", SKU: "RICE-1", Price: 50, StockQuantity: 7}
+	if err := database.DB.Create(&product).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	order := createTestOrder(t, store, supplier, models.OrderStatusPreparing)
+	item := models.OrderItem{OrderID: order.ID, ProductID: product.ID, Quantity: 3, UnitPrice: 50, Subtotal: 150}
+	if err := database.DB.Create(&item).Error; err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+
+	w := putOrderStatus(buildOrderRouter(store), order.ID, `{"status":"cancelled"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 without reason, got %d", w.Code)
+	}
+
+	w = putOrderStatus(buildOrderRouter(store), order.ID, `{"status":"cancelled","reason":"ordered twice"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var reloaded models.Product
+	database.DB.First(&reloaded, product.ID)
+	if reloaded.StockQuantity != 10 {
+		t.Fatalf("expected stock 10, got %d", reloaded.StockQuantity)
+	}
+
+	var history models.StockHistory
+	if err := database.DB.Where("product_id = ? AND change_type = ?", product.ID, "order_cancelled").First(&history).Error; err != nil {
+		t.Fatalf("stock history not written: %v", err)
+	}
+	if history.ChangeAmount != 3 || history.OrderID == nil || *history.OrderID != order.ID {
+		t.Fatalf("unexpected stock history: %+v", history)
+	}
+
+	var cancelled models.Order
+	database.DB.First(&cancelled, order.ID)
+	if cancelled.CancellationReason != "ordered twice" {
+		t.Fatalf("cancellation reason not stored: %q", cancelled.CancellationReason)
+	}
+
+	w = putOrderStatus(buildOrderRouter(store), order.ID, `{"status":"cancelled","reason":"again"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected cancelled order to be terminal, got %d", w.Code)
+	}
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// paymentLedger is an order's payment entries with the running totals.
+type paymentLedger struct {
+	OrderID       uint                 `json:"order_id"`
+	TotalAmount   float64              `json:"total_amount"`
+	AmountPaid    float64              `json:"amount_paid"`
+	Balance       float64              `json:"balance"`
+	PaymentStatus models.PaymentStatus `json:"payment_status"`
+	Payments      []models.Payment     `json:"payments"`
+}
+
+// GetOrderPayments lists an order's payment ledger, oldest first.
+func GetOrderPayments(c *gin.Context) {
+	orderID := c.Param("id")
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+	role, _ := c.Get("role")
+
+	query := database.DB.Where("id = ?", orderID)
+	switch role {
+	case "supplier":
+		query = query.Where("supplier_id = ?", userID)
+	case "store":
+		query = query.Where("store_id = ?", userID)
+	}
+	var order models.Order
+	if err := query.First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		return
+	}
+
+	ledger, err := loadPaymentLedger(database.DB, order)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch payments"})
+		return
+	}
+	c.JSON(http.StatusOK, ledger)
+}
+
+// RecordOrderPayment adds an entry to an order's payment ledger. A negative
+// amount records a refund.
+func RecordOrderPayment(c *gin.Context) {
+	orderID := c.Param("id")
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+	if !ensureEmployeePermission(c, empCtx.CanChangeStatus, "change_status") {
+		return
+	}
+	role, _ := c.Get("role")
+	if role != "supplier" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only suppliers can record payments"})
+		return
+	}
+
+	var req struct {
+		Method    string     `json:"method" binding:"required,oneof=cash_on_delivery gcash"`
+		Amount    float64    `json:"amount" binding:"required"`
+		Reference string     `json:"reference"`
+		Note      string     `json:"note"`
+		PaidAt    *time.Time `json:"paid_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	paidAt := time.Now()
+	if req.PaidAt != nil {
+		if req.PaidAt.After(paidAt.Add(5 * time.Minute)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "paid_at cannot be in the future"})
+			return
+		}
+		paidAt = *req.PaidAt
+	}
+	reference := strings.TrimSpace(req.Reference)
+	if req.Method == string(models.PaymentMethodGCash) {
+		reference = normalizePaymentReference(reference)
+	}
+
+	actor := getOrderActor(c)
+	var order models.Order
+	var previous models.PaymentStatus
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND supplier_id = ?", orderID, userID).First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &orderError{Code: http.StatusNotFound, Message: "order not found"}
+			}
+			return err
+		}
+		if order.Status == models.OrderStatusDraft {
+			return &orderError{Code: http.StatusBadRequest, Message: "payments can only be recorded for submitted orders"}
+		}
+		previous = order.PaymentStatus
+		return recordPayment(tx, &order, models.Payment{
+			Method:       models.PaymentMethod(req.Method),
+			Amount:       roundTo2(req.Amount),
+			Reference:    reference,
+			Note:         strings.TrimSpace(req.Note),
+			PaidAt:       paidAt,
+			RecordedByID: actor.userIDPtr(),
+			EmployeeID:   actor.EmployeeID,
+		})
+	})
+	if err != nil {
+		writeOrderError(c, err, "failed to record payment")
+		return
+	}
+
+	ledger, err := loadPaymentLedger(database.DB, order)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch payments"})
+		return
+	}
+
+	if order.PaymentStatus == models.PaymentStatusPaid && previous != models.PaymentStatusPaid {
+		sendPaymentPaidEmail(c, order.ID)
+	}
+
+	c.JSON(http.StatusCreated, ledger)
+}
+
+// recordPayment adds an entry to the ledger and re-derives the order's
+// amount paid and payment status. An entry whose ExternalID is already in
+// the ledger is skipped. The order row should be locked by the caller.
+func recordPayment(tx *gorm.DB, order *models.Order, payment models.Payment) error {
+	if payment.ExternalID != nil {
+		var existing int64
+		if err := tx.Model(&models.Payment{}).Where("external_id = ?", *payment.ExternalID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return nil
+		}
+	}
+	payment.OrderID = order.ID
+	if payment.PaidAt.IsZero() {
+		payment.PaidAt = time.Now()
+	}
+	if err := tx.Create(&payment).Error; err != nil {
+		return err
+	}
+
+	var paid float64
+	if err := tx.Model(&models.Payment{}).Where("order_id = ?", order.ID).Select("COALESCE(SUM(amount), 0)").Scan(&paid).Error; err != nil {
+		return err
+	}
+	paid = roundTo2(paid)
+	if paid < 0 {
+		return &orderError{Code: http.StatusBadRequest, Message: "refunds cannot exceed the amount paid"}
+	}
+
+	order.AmountPaid = paid
+	return syncPaymentStatus(tx, order)
+}
+
+// syncPaymentStatus re-derives the order's payment status from its amount
+// paid and latest payment verification, and saves both.
+func syncPaymentStatus(tx *gorm.DB, order *models.Order) error {
+	rejected, err := latestVerificationRejected(tx, order.ID)
+	if err != nil {
+		return err
+	}
+	order.PaymentStatus = models.PaymentStatusFor(order.TotalAmount, order.AmountPaid, rejected)
+	return tx.Model(order).Updates(map[string]interface{}{
+		"amount_paid":    order.AmountPaid,
+		"payment_status": order.PaymentStatus,
+	}).Error
+}
+
+// latestVerificationRejected reports whether the most recent GCash proof
+// check on the order was a rejection.
+func latestVerificationRejected(tx *gorm.DB, orderID uint) (bool, error) {
+	var latest models.PaymentVerification
+	err := tx.Where("order_id = ?", orderID).Order("verified_at DESC, id DESC").First(&latest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return latest.Decision == models.PaymentDecisionRejected, nil
+}
+
+func loadPaymentLedger(db *gorm.DB, order models.Order) (paymentLedger, error) {
+	ledger := paymentLedger{
+		OrderID:       order.ID,
+		TotalAmount:   order.TotalAmount,
+		AmountPaid:    order.AmountPaid,
+		Balance:       roundTo2(order.TotalAmount - order.AmountPaid),
+		PaymentStatus: order.PaymentStatus,
+		Payments:      []models.Payment{},
+	}
+	err := db.Preload("RecordedBy").Preload("Employee").Where("order_id = ?", order.ID).Order("paid_at ASC, id ASC").Find(&ledger.Payments).Error
+	return ledger, err
+}
+
+// sendPaymentPaidEmail tells the store and supplier an order is fully paid.
+func sendPaymentPaidEmail(c *gin.Context, orderID uint) {
+	emailService := getEmailService(c)
+	if emailService == nil {
+		return
+	}
+	var order models.Order
+	if err := database.DB.Preload("Store").Preload("Supplier").First(&order, orderID).Error; err != nil {
+		return
+	}
+	go emailService.SendPaymentPaidEmail(order)
+}
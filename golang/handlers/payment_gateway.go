@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+	"siargao-trading-road/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// paymentProviders are the online gateways orders can be paid through, keyed
+// by payment method.
+var paymentProviders = services.NewPaymentProviderRegistry()
+
+// SetPaymentProviders installs the online payment providers. Call it before
+// serving requests.
+func SetPaymentProviders(registry *services.PaymentProviderRegistry) {
+	paymentProviders = registry
+}
+
+// CreatePaymentIntent asks the order's payment provider to collect the
+// outstanding balance and returns where the store can pay it.
+func CreatePaymentIntent(c *gin.Context) {
+	orderID := c.Param("id")
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+	role, _ := c.Get("role")
+	if role != "store" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only stores can pay for orders"})
+		return
+	}
+
+	var order models.Order
+	if err := database.DB.Where("id = ? AND store_id = ?", orderID, userID).First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		return
+	}
+	if order.Status == models.OrderStatusDraft || order.Status == models.OrderStatusCancelled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("cannot pay for a %s order", order.Status)})
+		return
+	}
+	provider, ok := paymentProviders.Get(string(order.PaymentMethod))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order is not paid through an online payment provider"})
+		return
+	}
+	balance := roundTo2(order.TotalAmount - order.AmountPaid)
+	if balance <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order is already paid"})
+		return
+	}
+
+	created, err := provider.CreateIntent(c.Request.Context(), order, balance)
+	if err != nil {
+		log.Printf("CreatePaymentIntent: provider %s failed. orderID=%d, error=%v", provider.Name(), order.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "payment provider could not start the payment"})
+		return
+	}
+
+	actor := getOrderActor(c)
+	intent := models.PaymentIntent{
+		OrderID:          order.ID,
+		Provider:         provider.Name(),
+		ProviderIntentID: created.ID,
+		Amount:           created.Amount,
+		Status:           string(created.Status),
+		CheckoutURL:      created.CheckoutURL,
+		CreatedByID:      actor.userIDPtr(),
+	}
+	if err := database.DB.Create(&intent).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save payment intent"})
+		return
+	}
+	c.JSON(http.StatusCreated, intent)
+}
+
+// GetPaymentIntents lists an order's online payment attempts. Pending ones
+// are checked with the provider first, in case a webhook was missed.
+func GetPaymentIntents(c *gin.Context) {
+	orderID := c.Param("id")
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+	role, _ := c.Get("role")
+
+	query := database.DB.Where("id = ?", orderID)
+	switch role {
+	case "supplier":
+		query = query.Where("supplier_id = ?", userID)
+	case "store":
+		query = query.Where("store_id = ?", userID)
+	}
+	var order models.Order
+	if err := query.First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		return
+	}
+
+	intents := []models.PaymentIntent{}
+	if err := database.DB.Where("order_id = ?", order.ID).Order("created_at ASC, id ASC").Find(&intents).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch payment intents"})
+		return
+	}
+
+	for i := range intents {
+		if intents[i].Status != string(services.PaymentIntentPending) {
+			continue
+		}
+		provider, ok := paymentProviders.Get(intents[i].Provider)
+		if !ok {
+			continue
+		}
+		current, err := provider.GetIntent(c.Request.Context(), intents[i].ProviderIntentID)
+		if err != nil || current.Status == services.PaymentIntentPending {
+			continue
+		}
+		paid, err := settlePaymentIntent(&intents[i], current.Status, current.Amount, time.Now())
+		if err != nil {
+			log.Printf("GetPaymentIntents: failed to settle intent %d: %v", intents[i].ID, err)
+			continue
+		}
+		if paid {
+			sendPaymentPaidEmail(c, order.ID)
+		}
+	}
+
+	c.JSON(http.StatusOK, intents)
+}
+
+// RefundPaymentIntent refunds part or all of a completed online payment
+// through its provider and records the refund in the ledger.
+func RefundPaymentIntent(c *gin.Context) {
+	orderID := c.Param("id")
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+	if !ensureEmployeePermission(c, empCtx.CanChangeStatus, "change_status") {
+		return
+	}
+	role, _ := c.Get("role")
+	if role != "supplier" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only suppliers can refund payments"})
+		return
+	}
+
+	var req struct {
+		Amount float64 `json:"amount" binding:"required,gt=0"`
+		Note   string  `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var order models.Order
+	if err := database.DB.Where("id = ? AND supplier_id = ?", orderID, userID).First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		return
+	}
+	var intent models.PaymentIntent
+	if err := database.DB.Where("id = ? AND order_id = ?", c.Param("intent_id"), order.ID).First(&intent).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "payment intent not found"})
+		return
+	}
+	if intent.Status != string(services.PaymentIntentSucceeded) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "only completed payments can be refunded"})
+		return
+	}
+	provider, ok := paymentProviders.Get(intent.Provider)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("payment provider %s is not available", intent.Provider)})
+		return
+	}
+
+	refund, err := provider.Refund(c.Request.Context(), intent.ProviderIntentID, roundTo2(req.Amount))
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("payment provider refused the refund: %v", err)})
+		return
+	}
+
+	actor := getOrderActor(c)
+	note := req.Note
+	if note == "" {
+		note = "refunded online"
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, order.ID).Error; err != nil {
+			return err
+		}
+		return recordPayment(tx, &order, models.Payment{
+			Method:       models.PaymentMethod(intent.Provider),
+			Amount:       -roundTo2(refund.Amount),
+			Reference:    refund.ID,
+			Note:         note,
+			ExternalID:   providerExternalID(intent.Provider, refund.ID),
+			RecordedByID: actor.userIDPtr(),
+			EmployeeID:   actor.EmployeeID,
+		})
+	})
+	if err != nil {
+		log.Printf("RefundPaymentIntent: provider refunded %s but the ledger was not updated. orderID=%d, error=%v", refund.ID, order.ID, err)
+		writeOrderError(c, err, "failed to record refund")
+		return
+	}
+
+	ledger, err := loadPaymentLedger(database.DB, order)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch payments"})
+		return
+	}
+	c.JSON(http.StatusOK, ledger)
+}
+
+// HandlePaymentWebhook receives a provider's signed callback and applies it
+// to the ledger. Deliveries are matched on the provider's ids, so a retried
+// or replayed callback changes nothing.
+func HandlePaymentWebhook(c *gin.Context) {
+	provider, ok := paymentProviders.Get(c.Param("provider"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown payment provider"})
+		return
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read webhook"})
+		return
+	}
+	event, err := provider.ParseWebhook(c.Request.Header, body)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidWebhookSignature) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var intent models.PaymentIntent
+	if err := database.DB.Where("provider = ? AND provider_intent_id = ?", provider.Name(), event.IntentID).First(&intent).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "payment intent not found"})
+		return
+	}
+
+	occurredAt := event.OccurredAt
+	if occurredAt.IsZero() {
+		occurredAt = time.Now()
+	}
+	switch event.Type {
+	case services.PaymentEventSucceeded, services.PaymentEventFailed:
+		status := services.PaymentIntentSucceeded
+		if event.Type == services.PaymentEventFailed {
+			status = services.PaymentIntentFailed
+		}
+		paid, err := settlePaymentIntent(&intent, status, event.Amount, occurredAt)
+		if err != nil {
+			writeOrderError(c, err, "failed to apply payment")
+			return
+		}
+		if paid {
+			sendPaymentPaidEmail(c, intent.OrderID)
+		}
+	case services.PaymentEventRefunded:
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			var order models.Order
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, intent.OrderID).Error; err != nil {
+				return err
+			}
+			return recordPayment(tx, &order, models.Payment{
+				Method:     models.PaymentMethod(intent.Provider),
+				Amount:     -roundTo2(event.Amount),
+				Reference:  event.ID,
+				Note:       "refunded online",
+				PaidAt:     occurredAt,
+				ExternalID: providerExternalID(intent.Provider, event.ID),
+			})
+		})
+		if err != nil {
+			writeOrderError(c, err, "failed to apply refund")
+			return
+		}
+	default:
+		// Acknowledge events we do not act on so the provider stops retrying.
+	}
+
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
+
+// settlePaymentIntent moves a pending intent to succeeded or failed. Success
+// adds the payment to the ledger once, keyed by the provider's intent id.
+// It reports whether this made the order fully paid.
+func settlePaymentIntent(intent *models.PaymentIntent, status services.PaymentIntentStatus, amount float64, paidAt time.Time) (bool, error) {
+	if amount <= 0 {
+		amount = intent.Amount
+	}
+	paid := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, intent.OrderID).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(intent, intent.ID).Error; err != nil {
+			return err
+		}
+		if intent.Status == string(services.PaymentIntentSucceeded) {
+			return nil
+		}
+		if err := tx.Model(intent).Update("status", string(status)).Error; err != nil {
+			return err
+		}
+		if status != services.PaymentIntentSucceeded {
+			return nil
+		}
+
+		previous := order.PaymentStatus
+		if err := recordPayment(tx, &order, models.Payment{
+			Method:     models.PaymentMethod(intent.Provider),
+			Amount:     roundTo2(amount),
+			Reference:  intent.ProviderIntentID,
+			Note:       "paid online",
+			PaidAt:     paidAt,
+			ExternalID: providerExternalID(intent.Provider, intent.ProviderIntentID),
+		}); err != nil {
+			return err
+		}
+		paid = order.PaymentStatus == models.PaymentStatusPaid && previous != models.PaymentStatusPaid
+		return nil
+	})
+	return paid, err
+}
+
+func providerExternalID(provider, id string) *string {
+	externalID := provider + ":" + id
+	return &externalID
+}
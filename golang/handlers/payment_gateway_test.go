@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+	"siargao-trading-road/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupPaymentGatewayTestDB(t *testing.T) (models.User, models.User) {
+	t.Helper()
+	store, supplier := setupOrderTestDB(t)
+	migrateTestModels(t, &models.StockReservation{}, &models.SupplierDeliverySettings{}, &models.DeliveryFeeBand{}, &models.DeliveryFeeZone{}, &models.ServiceAreaPlace{}, &models.ScheduleException{}, &models.Payment{}, &models.PaymentIntent{}, &models.PaymentVerification{})
+	return store, supplier
+}
+
+func buildPaymentGatewayRouter(user models.User) *gin.Engine {
+	r := buildOrderRouter(user)
+	r.POST("/orders/:id/items", AddOrderItem)
+	r.POST("/orders/:id/submit", SubmitOrder)
+	r.GET("/orders/:id/payments", GetOrderPayments)
+	r.GET("/orders/:id/payment/intents", GetPaymentIntents)
+	r.POST("/orders/:id/payment/intents", CreatePaymentIntent)
+	r.POST("/orders/:id/payment/intents/:intent_id/refund", RefundPaymentIntent)
+	r.POST("/payments/webhooks/:provider", HandlePaymentWebhook)
+	return r
+}
+
+func TestSimulatedProviderWebhookUpdatesLedgerOnce(t *testing.T) {
+	store, supplier := setupPaymentGatewayTestDB(t)
+	simulator := services.NewSimulatedPaymentProvider("test-secret")
+	SetPaymentProviders(services.NewPaymentProviderRegistry(simulator))
+	t.Cleanup(func() { SetPaymentProviders(services.NewPaymentProviderRegistry()) })
+
+	rice := models.Product{SupplierID: supplier.ID, Name: "Rice", SKU: "RICE-1", Price: 50, StockQuantity: 100}
+	database.DB.Create(&rice)
+	database.DB.Create(&models.SupplierDeliverySettings{SupplierID: supplier.ID, MinimumOrderAmount: 100})
+	storeRouter := buildPaymentGatewayRouter(store)
+	supplierRouter := buildPaymentGatewayRouter(supplier)
+
+	draft := createTestOrder(t, store, supplier, models.OrderStatusDraft)
+	doOrderRequest(storeRouter, http.MethodPost, fmt.Sprintf("/orders/%d/items", draft.ID), fmt.Sprintf(`{"product_id":%d,"quantity":10}`, rice.ID))
+	submitPath := fmt.Sprintf("/orders/%d/submit", draft.ID)
+	if w := doOrderRequest(storeRouter, http.MethodPost, submitPath, `{"payment_method":"paymaya","delivery_option":"pickup"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected unregistered provider to be refused, got %d", w.Code)
+	}
+	if w := doOrderRequest(storeRouter, http.MethodPost, submitPath, `{"payment_method":"simulated","delivery_option":"pickup"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	intentsPath := fmt.Sprintf("/orders/%d/payment/intents", draft.ID)
+	w := doOrderRequest(storeRouter, http.MethodPost, intentsPath, "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var intent models.PaymentIntent
+	json.Unmarshal(w.Body.Bytes(), &intent)
+	if intent.Amount != 500 || intent.Status != string(services.PaymentIntentPending) || intent.CheckoutURL == "" {
+		t.Fatalf("unexpected intent: %s", w.Body.String())
+	}
+
+	body, signature, err := simulator.Settle(intent.ProviderIntentID, true)
+	if err != nil {
+		t.Fatalf("settle: %v", err)
+	}
+	postWebhook := func(signature string) int {
+		req := httptest.NewRequest(http.MethodPost, "/payments/webhooks/simulated", bytes.NewReader(body))
+		req.Header.Set(services.SimulatorSignatureHeader, signature)
+		w := httptest.NewRecorder()
+		storeRouter.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := postWebhook("00" + signature[2:]); code != http.StatusUnauthorized {
+		t.Fatalf("expected a bad signature to be refused, got %d", code)
+	}
+	for i := 0; i < 2; i++ {
+		if code := postWebhook(signature); code != http.StatusOK {
+			t.Fatalf("expected webhook delivery %d to be accepted, got %d", i+1, code)
+		}
+	}
+
+	var ledger paymentLedger
+	w = doOrderRequest(storeRouter, http.MethodGet, fmt.Sprintf("/orders/%d/payments", draft.ID), "")
+	json.Unmarshal(w.Body.Bytes(), &ledger)
+	if ledger.PaymentStatus != models.PaymentStatusPaid || ledger.AmountPaid != 500 || len(ledger.Payments) != 1 {
+		t.Fatalf("expected one online payment in the ledger: %s", w.Body.String())
+	}
+
+	refundPath := fmt.Sprintf("/orders/%d/payment/intents/%d/refund", draft.ID, intent.ID)
+	if w := doOrderRequest(supplierRouter, http.MethodPost, refundPath, `{"amount":600}`); w.Code != http.StatusBadGateway {
+		t.Fatalf("expected refund over the payment to be refused, got %d", w.Code)
+	}
+	w = doOrderRequest(supplierRouter, http.MethodPost, refundPath, `{"amount":100,"note":"short shipped"}`)
+	json.Unmarshal(w.Body.Bytes(), &ledger)
+	if w.Code != http.StatusOK || ledger.PaymentStatus != models.PaymentStatusPartial || ledger.AmountPaid != 400 || len(ledger.Payments) != 2 {
+		t.Fatalf("unexpected ledger after refund: %d %s", w.Code, w.Body.String())
+	}
+}
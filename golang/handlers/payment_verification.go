@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// VerifyPayment records the supplier's check of a GCash payment: the
+// reference number, amount and sender it was matched against, and whether it
+// was approved. Approval adds the amount to the payment ledger; rejection
+// tells the store why.
+func VerifyPayment(c *gin.Context) {
+	orderID := c.Param("id")
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+	if !ensureEmployeePermission(c, empCtx.CanChangeStatus, "change_status") {
+		return
+	}
+	role, _ := c.Get("role")
+	if role != "supplier" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only suppliers can verify payments"})
+		return
+	}
+
+	var req struct {
+		ReferenceNumber string  `json:"reference_number" binding:"required"`
+		AmountPaid      float64 `json:"amount_paid" binding:"required,gt=0"`
+		SenderNumber    string  `json:"sender_number"`
+		ScreenshotURL   string  `json:"screenshot_url"`
+		Decision        string  `json:"decision" binding:"required,oneof=approved rejected"`
+		Reason          string  `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reference := normalizePaymentReference(req.ReferenceNumber)
+	if reference == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reference_number is required"})
+		return
+	}
+	decision := models.PaymentDecision(req.Decision)
+	reason := strings.TrimSpace(req.Reason)
+	if decision == models.PaymentDecisionRejected && reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required when rejecting a payment"})
+		return
+	}
+
+	actor := getOrderActor(c)
+	var order models.Order
+	var verification models.PaymentVerification
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND supplier_id = ?", orderID, userID).First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &orderError{Code: http.StatusNotFound, Message: "order not found"}
+			}
+			return err
+		}
+		if order.PaymentMethod != models.PaymentMethodGCash {
+			return &orderError{Code: http.StatusBadRequest, Message: "payment verification is only applicable for GCash orders"}
+		}
+		if decision == models.PaymentDecisionApproved && roundTo2(order.TotalAmount-order.AmountPaid) <= 0 {
+			return &orderError{Code: http.StatusBadRequest, Message: "payment is already marked as paid"}
+		}
+
+		// A reference may be reviewed again on the same order, for example
+		// after the store sends a clearer screenshot, but never reused on
+		// another order. The first order to verify it claims it; the unique
+		// index makes a concurrent claim from another order wait and lose.
+		claim := models.PaymentReference{ReferenceNumber: reference, OrderID: order.ID}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&claim).Error; err != nil {
+			return err
+		}
+		var owner models.PaymentReference
+		if err := tx.Where("reference_number = ?", reference).First(&owner).Error; err != nil {
+			return err
+		}
+		if owner.OrderID != order.ID {
+			return &orderError{Code: http.StatusConflict, Message: fmt.Sprintf("reference number %s was already used on order #%d", reference, owner.OrderID)}
+		}
+		var approved int64
+		if err := tx.Model(&models.PaymentVerification{}).
+			Where("reference_number = ? AND order_id = ? AND decision = ?", reference, order.ID, models.PaymentDecisionApproved).
+			Count(&approved).Error; err != nil {
+			return err
+		}
+		if approved > 0 {
+			return &orderError{Code: http.StatusConflict, Message: fmt.Sprintf("reference number %s was already approved", reference)}
+		}
+
+		screenshot := strings.TrimSpace(req.ScreenshotURL)
+		if screenshot == "" {
+			screenshot = order.PaymentProofURL
+		}
+		verification = models.PaymentVerification{
+			OrderID:         order.ID,
+			ReferenceNumber: reference,
+			AmountPaid:      req.AmountPaid,
+			SenderNumber:    strings.TrimSpace(req.SenderNumber),
+			ScreenshotURL:   screenshot,
+			Decision:        decision,
+			Reason:          reason,
+			VerifiedByID:    actor.userIDPtr(),
+			EmployeeID:      actor.EmployeeID,
+			VerifiedAt:      time.Now(),
+		}
+		if err := tx.Create(&verification).Error; err != nil {
+			return err
+		}
+
+		if decision == models.PaymentDecisionRejected {
+			return syncPaymentStatus(tx, &order)
+		}
+		return recordPayment(tx, &order, models.Payment{
+			Method:       models.PaymentMethodGCash,
+			Amount:       roundTo2(req.AmountPaid),
+			Reference:    reference,
+			Note:         "GCash payment verified",
+			PaidAt:       verification.VerifiedAt,
+			RecordedByID: actor.userIDPtr(),
+			EmployeeID:   actor.EmployeeID,
+		})
+	})
+	if err != nil {
+		writeOrderError(c, err, "failed to verify payment")
+		return
+	}
+
+	if err := database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product").
+		Preload("PaymentVerifications", func(db *gorm.DB) *gorm.DB { return db.Order("verified_at ASC, id ASC") }).
+		Preload("Payments").First(&order, order.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load order details"})
+		return
+	}
+
+	if emailService := getEmailService(c); emailService != nil {
+		switch {
+		case decision == models.PaymentDecisionRejected:
+			go emailService.SendPaymentRejectedEmail(order, verification)
+		case order.PaymentStatus == models.PaymentStatusPaid:
+			go emailService.SendPaymentPaidEmail(order)
+		}
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// GetPaymentVerifications lists the payment checks recorded for an order.
+func GetPaymentVerifications(c *gin.Context) {
+	orderID := c.Param("id")
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+	role, _ := c.Get("role")
+
+	query := database.DB.Where("id = ?", orderID)
+	switch role {
+	case "supplier":
+		query = query.Where("supplier_id = ?", userID)
+	case "store":
+		query = query.Where("store_id = ?", userID)
+	}
+	var order models.Order
+	if err := query.First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		return
+	}
+
+	verifications := []models.PaymentVerification{}
+	if err := database.DB.Preload("VerifiedBy").Preload("Employee").Where("order_id = ?", order.ID).Order("verified_at DESC").Find(&verifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch payment verifications"})
+		return
+	}
+	c.JSON(http.StatusOK, verifications)
+}
+
+// normalizePaymentReference drops the spaces and dashes GCash shows in
+// reference numbers so the same payment always compares equal.
+func normalizePaymentReference(value string) string {
+	return strings.ToUpper(strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '\t' {
+			return -1
+		}
+		return r
+	}, value))
+}
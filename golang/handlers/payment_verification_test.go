@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupPaymentTestDB(t *testing.T) (models.User, models.User) {
+	t.Helper()
+	store, supplier := setupOrderTestDB(t)
+	migrateTestModels(t, &models.Payment{}, &models.PaymentVerification{}, &models.PaymentReference{})
+	return store, supplier
+}
+
+func buildPaymentRouter(user models.User) *gin.Engine {
+	r := buildOrderRouter(user)
+	r.POST("/orders/:id/payment/verify", VerifyPayment)
+	r.POST("/orders/:id/payment/paid", MarkPaymentAsPaid)
+	r.POST("/orders/:id/payment/pending", MarkPaymentAsPending)
+	r.GET("/orders/:id/payments", GetOrderPayments)
+	r.POST("/orders/:id/payments", RecordOrderPayment)
+	return r
+}
+
+func TestVerifyPaymentRecordsDecisionAndRejectsReusedReference(t *testing.T) {
+	store, supplier := setupPaymentTestDB(t)
+	first := models.Order{StoreID: store.ID, SupplierID: supplier.ID, Status: models.OrderStatusPreparing, PaymentMethod: models.PaymentMethodGCash, TotalAmount: 500, PaymentProofURL: "https://example.com/proof.jpg"}
+	second := models.Order{StoreID: store.ID, SupplierID: supplier.ID, Status: models.OrderStatusPreparing, PaymentMethod: models.PaymentMethodGCash, TotalAmount: 500}
+	database.DB.Create(&first)
+	database.DB.Create(&second)
+	router := buildPaymentRouter(supplier)
+	verify := func(orderID uint, body string) int {
+		return doOrderRequest(router, http.MethodPost, fmt.Sprintf("/orders/%d/payment/verify", orderID), body).Code
+	}
+
+	if code := verify(first.ID, `{"reference_number":"1234 567 890123","amount_paid":500,"decision":"rejected"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected rejection without reason to be refused, got %d", code)
+	}
+	if code := verify(first.ID, `{"reference_number":"1234 567 890123","amount_paid":300,"sender_number":"09171234567","decision":"rejected","reason":"amount short"}`); code != http.StatusOK {
+		t.Fatalf("expected rejection to be recorded, got %d", code)
+	}
+	var reloaded models.Order
+	database.DB.First(&reloaded, first.ID)
+	if reloaded.PaymentStatus != models.PaymentStatusFailed || reloaded.AmountPaid != 0 {
+		t.Fatalf("expected failed payment, got %s %.2f", reloaded.PaymentStatus, reloaded.AmountPaid)
+	}
+
+	if code := verify(second.ID, `{"reference_number":"1234-567-890123","amount_paid":500,"decision":"approved"}`); code != http.StatusConflict {
+		t.Fatalf("expected reused reference to be refused, got %d", code)
+	}
+	duplicate := models.PaymentReference{ReferenceNumber: "1234567890123", OrderID: second.ID}
+	if err := database.DB.Create(&duplicate).Error; err == nil {
+		t.Fatalf("expected the database to refuse a second claim on the reference")
+	}
+
+	w := doOrderRequest(router, http.MethodPost, fmt.Sprintf("/orders/%d/payment/verify", first.ID), `{"reference_number":"1234567890123","amount_paid":500,"decision":"approved"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected approval after review, got %d: %s", w.Code, w.Body.String())
+	}
+	var paid models.Order
+	json.Unmarshal(w.Body.Bytes(), &paid)
+	if paid.PaymentStatus != models.PaymentStatusPaid || len(paid.PaymentVerifications) != 2 || len(paid.Payments) != 1 || paid.Payments[0].Reference != "1234567890123" {
+		t.Fatalf("unexpected order: %s", w.Body.String())
+	}
+	if paid.PaymentVerifications[0].Decision != models.PaymentDecisionRejected {
+		t.Fatalf("expected the earlier rejection to be kept, got %+v", paid.PaymentVerifications[0])
+	}
+	verification := paid.PaymentVerifications[1]
+	if verification.ReferenceNumber != "1234567890123" || verification.Decision != models.PaymentDecisionApproved || verification.ScreenshotURL != first.PaymentProofURL || verification.VerifiedByID == nil || *verification.VerifiedByID != supplier.ID {
+		t.Fatalf("unexpected verification: %+v", verification)
+	}
+}
+
+func TestPaymentLedgerTracksPartialPaymentsAndRefunds(t *testing.T) {
+	store, supplier := setupPaymentTestDB(t)
+	order := models.Order{StoreID: store.ID, SupplierID: supplier.ID, Status: models.OrderStatusInTransit, PaymentMethod: models.PaymentMethodGCash, TotalAmount: 1000}
+	database.DB.Create(&order)
+	router := buildPaymentRouter(supplier)
+	paymentsPath := fmt.Sprintf("/orders/%d/payments", order.ID)
+
+	record := func(body string) paymentLedger {
+		w := doOrderRequest(router, http.MethodPost, paymentsPath, body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+		}
+		var ledger paymentLedger
+		json.Unmarshal(w.Body.Bytes(), &ledger)
+		return ledger
+	}
+
+	ledger := record(`{"method":"gcash","amount":400,"reference":"9876 543"}`)
+	if ledger.PaymentStatus != models.PaymentStatusPartial || ledger.Balance != 600 || ledger.Payments[0].Reference != "9876543" {
+		t.Fatalf("unexpected ledger after deposit: %+v", ledger)
+	}
+
+	w := doOrderRequest(router, http.MethodPost, fmt.Sprintf("/orders/%d/payment/paid", order.ID), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var paid models.Order
+	json.Unmarshal(w.Body.Bytes(), &paid)
+	if paid.PaymentStatus != models.PaymentStatusPaid || paid.AmountPaid != 1000 {
+		t.Fatalf("expected the balance to be paid, got %s %.2f", paid.PaymentStatus, paid.AmountPaid)
+	}
+
+	ledger = record(`{"method":"cash_on_delivery","amount":50,"note":"change not given"}`)
+	if ledger.PaymentStatus != models.PaymentStatusOverpaid || ledger.Balance != -50 {
+		t.Fatalf("unexpected ledger after overpayment: %+v", ledger)
+	}
+	ledger = record(`{"method":"cash_on_delivery","amount":-50,"note":"change returned"}`)
+	if ledger.PaymentStatus != models.PaymentStatusPaid {
+		t.Fatalf("expected refund to settle the order, got %s", ledger.PaymentStatus)
+	}
+	if w := doOrderRequest(router, http.MethodPost, paymentsPath, `{"method":"gcash","amount":-5000}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected refund above the amount paid to be refused, got %d", w.Code)
+	}
+
+	if w := doOrderRequest(router, http.MethodPost, fmt.Sprintf("/orders/%d/payment/pending", order.ID), ""); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	w = doOrderRequest(router, http.MethodGet, paymentsPath, "")
+	json.Unmarshal(w.Body.Bytes(), &ledger)
+	if ledger.PaymentStatus != models.PaymentStatusUnpaid || ledger.AmountPaid != 0 || len(ledger.Payments) != 5 || ledger.Payments[4].Amount != -1000 {
+		t.Fatalf("expected the revert to be kept in the ledger: %s", w.Body.String())
+	}
+}
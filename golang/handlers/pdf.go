@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// newBrandedPDF starts an A4 document with the branded header band, the
+// title on the left and a reference such as the invoice number on the
+// right. The cursor is left at the start of the body.
+func newBrandedPDF(title, reference string) *gofpdf.Fpdf {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(12, 15, 12)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.AddPage()
+	hasLogo := registerLogo(pdf)
+
+	primaryBlue := struct{ r, g, b int }{r: 0, g: 86, b: 155}
+	teal := struct{ r, g, b int }{r: 0, g: 170, b: 190}
+
+	// Header band
+	pdf.SetFillColor(primaryBlue.r, primaryBlue.g, primaryBlue.b)
+	pdf.Rect(0, 0, 210, 24, "F")
+
+	// Curved accent using teal
+	pdf.SetFillColor(teal.r, teal.g, teal.b)
+	pdf.Rect(0, 17, 210, 9, "F")
+
+	// Header text
+	pdf.SetTextColor(255, 255, 255)
+	pdf.SetFont("Arial", "B", 18)
+	pdf.SetXY(15, 8)
+	pdf.CellFormat(60, 8, title, "", 0, "L", false, 0, "")
+	pdf.SetFont("Arial", "B", 12)
+	pdf.SetXY(135, 8)
+	pdf.CellFormat(60, 8, reference, "", 0, "R", false, 0, "")
+
+	// Center logo in header if registered
+	if hasLogo {
+		pageW, _ := pdf.GetPageSize()
+		left, _, right, _ := pdf.GetMargins()
+		usable := pageW - left - right
+		logoW := 45.0
+		x := left + (usable-logoW)/2
+		pdf.ImageOptions("app-logo", x, 5, logoW, 0, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	}
+
+	// Body start
+	pdf.SetY(26)
+	pdf.SetTextColor(0, 0, 0)
+
+	return pdf
+}
+
+// writePDFFooter prints the company line at the bottom of the current page.
+func writePDFFooter(pdf *gofpdf.Fpdf) {
+	pageH, _ := pdf.GetPageSize()
+	pdf.SetY(pageH - 25)
+	pdf.SetFont("Arial", "", 9)
+	pdf.CellFormat(0, 6, "Siargao Trading Road | siargaotradingroad.com | info@siargaotradingroad.com", "", 1, "C", false, 0, "")
+}
+
+func registerLogo(pdf *gofpdf.Fpdf) bool {
+	// Try embedded first
+	if len(embeddedSplash) > 0 {
+		if pdf.RegisterImageOptionsReader("app-logo", gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(embeddedSplash)) == nil {
+			pageW, _ := pdf.GetPageSize()
+			left, _, right, _ := pdf.GetMargins()
+			usable := pageW - left - right
+			logoW := 70.0
+			x := left + (usable-logoW)/2
+			pdf.ImageOptions("app-logo", x, 10, logoW, 0, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+			return true
+		}
+	}
+
+	// Fallback to file paths
+	candidates := []string{
+		filepath.Join("assets", "splash.png"),
+		filepath.Join("golang", "assets", "splash.png"),
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		candidates = append(candidates, filepath.Join(cwd, "assets", "splash.png"))
+		candidates = append(candidates, filepath.Join(cwd, "golang", "assets", "splash.png"))
+	}
+	if exe, err := os.Executable(); err == nil {
+		exeDir := filepath.Dir(exe)
+		candidates = append(candidates, filepath.Join(exeDir, "assets", "splash.png"))
+		candidates = append(candidates, filepath.Join(exeDir, "..", "assets", "splash.png"))
+	}
+
+	for _, logoPath := range candidates {
+		if logoBytes, err := os.ReadFile(logoPath); err == nil {
+			if pdf.RegisterImageOptionsReader("app-logo", gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(logoBytes)) == nil {
+				pageW, _ := pdf.GetPageSize()
+				left, _, right, _ := pdf.GetMargins()
+				usable := pageW - left - right
+				logoW := 70.0
+				x := left + (usable-logoW)/2
+				pdf.ImageOptions("app-logo", x, 10, logoW, 0, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+				return true
+			}
+		}
+	}
+
+	log.Printf("registerLogo: logo not found in any candidate path")
+	return false
+}
@@ -10,6 +10,7 @@ import (
 	"siargao-trading-road/models"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type CreateProductRequest struct {
@@ -19,33 +20,43 @@ type CreateProductRequest struct {
 	Price         float64 `json:"price" binding:"required,min=0"`
 	StockQuantity int     `json:"stock_quantity" binding:"min=0"`
 	Unit          string  `json:"unit"`
+	WeightKg      float64 `json:"weight_kg" binding:"min=0"`
 	Category      string  `json:"category"`
 	ImageURL      string  `json:"image_url"`
 	SupplierID    *uint   `json:"supplier_id"`
 }
 
 type UpdateProductRequest struct {
-	Name          string  `json:"name"`
-	Description   string  `json:"description"`
-	SKU           string  `json:"sku"`
-	Price         float64 `json:"price" binding:"omitempty,min=0"`
-	StockQuantity *int    `json:"stock_quantity"`
-	Unit          string  `json:"unit"`
-	Category      string  `json:"category"`
-	ImageURL      string  `json:"image_url"`
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	SKU           string   `json:"sku"`
+	Price         float64  `json:"price" binding:"omitempty,min=0"`
+	StockQuantity *int     `json:"stock_quantity"`
+	Unit          string   `json:"unit"`
+	WeightKg      *float64 `json:"weight_kg" binding:"omitempty,min=0"`
+	Category      string   `json:"category"`
+	ImageURL      string   `json:"image_url"`
 }
 
 func logStockChange(productID uint, previousStock int, newStock int, changeType string, userID *uint, employeeID *uint, orderID *uint, notes string) {
-	changeAmount := newStock - previousStock
-	if changeAmount == 0 {
-		return
-	}
-
 	if database.DB == nil {
 		log.Printf("ERROR: Database connection is nil when trying to create stock history")
 		return
 	}
 
+	if err := logStockChangeTx(database.DB, productID, previousStock, newStock, changeType, userID, employeeID, orderID, notes); err != nil {
+		fmt.Printf("STOCK_HISTORY_ERROR: %v\n", err)
+	}
+}
+
+// logStockChangeTx writes a stock history row using tx, so it commits or rolls
+// back together with the stock change it describes.
+func logStockChangeTx(tx *gorm.DB, productID uint, previousStock int, newStock int, changeType string, userID *uint, employeeID *uint, orderID *uint, notes string) error {
+	changeAmount := newStock - previousStock
+	if changeAmount == 0 {
+		return nil
+	}
+
 	stockHistory := models.StockHistory{
 		ProductID:     productID,
 		PreviousStock: previousStock,
@@ -58,18 +69,17 @@ func logStockChange(productID uint, previousStock int, newStock int, changeType
 		Notes:         notes,
 	}
 
-	result := database.DB.Omit("Product", "User", "Employee", "Order").Create(&stockHistory)
+	result := tx.Omit("Product", "User", "Employee", "Order").Create(&stockHistory)
 	if result.Error != nil {
 		log.Printf("ERROR: Failed to create stock history: %v", result.Error)
 		log.Printf("ERROR: Stock history details: ProductID=%d, PreviousStock=%d, NewStock=%d, ChangeType=%s, UserID=%v, EmployeeID=%v, OrderID=%v",
 			productID, previousStock, newStock, changeType, userID, employeeID, orderID)
-		log.Printf("ERROR: SQL Error: %s", result.Error.Error())
-		fmt.Printf("STOCK_HISTORY_ERROR: %v\n", result.Error)
-	} else {
-		log.Printf("SUCCESS: Stock history created: ID=%d, ProductID=%d, ChangeType=%s, ChangeAmount=%d, RowsAffected=%d",
-			stockHistory.ID, productID, changeType, changeAmount, result.RowsAffected)
-		fmt.Printf("STOCK_HISTORY_SUCCESS: ID=%d, ProductID=%d\n", stockHistory.ID, productID)
+		return result.Error
 	}
+
+	log.Printf("SUCCESS: Stock history created: ID=%d, ProductID=%d, ChangeType=%s, ChangeAmount=%d, RowsAffected=%d",
+		stockHistory.ID, productID, changeType, changeAmount, result.RowsAffected)
+	return nil
 }
 
 func GetProducts(c *gin.Context) {
@@ -107,6 +117,7 @@ func GetProducts(c *gin.Context) {
 		return
 	}
 
+	fillAvailableQuantities(products)
 	c.JSON(http.StatusOK, products)
 }
 
@@ -136,7 +147,9 @@ func GetProduct(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, product)
+	products := []models.Product{product}
+	fillAvailableQuantities(products)
+	c.JSON(http.StatusOK, products[0])
 }
 
 func CreateProduct(c *gin.Context) {
@@ -192,6 +205,7 @@ func CreateProduct(c *gin.Context) {
 		Price:         req.Price,
 		StockQuantity: req.StockQuantity,
 		Unit:          req.Unit,
+		WeightKg:      req.WeightKg,
 		Category:      req.Category,
 		ImageURL:      req.ImageURL,
 	}
@@ -297,6 +311,9 @@ func UpdateProduct(c *gin.Context) {
 		if req.Unit != "" {
 			product.Unit = req.Unit
 		}
+		if req.WeightKg != nil {
+			product.WeightKg = *req.WeightKg
+		}
 		if req.Category != "" {
 			product.Category = req.Category
 		}
@@ -501,6 +518,7 @@ func BulkCreateProducts(c *gin.Context) {
 			Price:         productReq.Price,
 			StockQuantity: productReq.StockQuantity,
 			Unit:          productReq.Unit,
+			WeightKg:      productReq.WeightKg,
 			Category:      productReq.Category,
 			ImageURL:      productReq.ImageURL,
 		}
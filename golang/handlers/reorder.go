@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Outcomes for one line of a reorder.
+const (
+	reorderLineAdded   = "added"
+	reorderLineCapped  = "capped"
+	reorderLineSkipped = "skipped"
+)
+
+// reorderLine reports what happened to one item of the source order.
+type reorderLine struct {
+	ProductID         uint    `json:"product_id"`
+	ProductName       string  `json:"product_name"`
+	RequestedQuantity int     `json:"requested_quantity"`
+	Quantity          int     `json:"quantity"`
+	PreviousUnitPrice float64 `json:"previous_unit_price"`
+	UnitPrice         float64 `json:"unit_price"`
+	PriceChanged      bool    `json:"price_changed"`
+	Status            string  `json:"status"`
+	Reason            string  `json:"reason,omitempty"`
+}
+
+// ReorderOrder copies the items of a finished order into the store's draft
+// with that supplier, at current prices and as far as stock allows.
+func ReorderOrder(c *gin.Context) {
+	orderID := c.Param("id")
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+
+	role, _ := c.Get("role")
+
+	if role != "store" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only stores can reorder"})
+		return
+	}
+
+	var source models.Order
+	if err := database.DB.Preload("OrderItems").Where("id = ? AND store_id = ?", orderID, userID).First(&source).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		return
+	}
+
+	if source.Status != models.OrderStatusDelivered && source.Status != models.OrderStatusCancelled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "only delivered or cancelled orders can be reordered"})
+		return
+	}
+
+	var supplier models.User
+	if err := database.DB.Where("id = ? AND role = ?", source.SupplierID, "supplier").First(&supplier).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "supplier not found"})
+		return
+	}
+
+	actor := getOrderActor(c)
+	ttl := reservationTTL(c)
+	var draft models.Order
+	var lines []reorderLine
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		draft, _, err = findOrCreateDraftOrder(tx, userID, source.SupplierID, actor)
+		if err != nil {
+			return err
+		}
+
+		lines = make([]reorderLine, 0, len(source.OrderItems))
+		for _, item := range source.OrderItems {
+			line, err := reorderItem(tx, draft.ID, item, actor, ttl)
+			if err != nil {
+				return err
+			}
+			lines = append(lines, line)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error reordering order %s: %v", orderID, err)
+		writeOrderError(c, err, "failed to reorder")
+		return
+	}
+
+	database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product").First(&draft, draft.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"order": draft,
+		"lines": lines,
+	})
+}
+
+// reorderItem adds one line of a previous order to the draft, capping it to
+// what is still available. Lines that cannot be added are reported, not
+// treated as errors.
+func reorderItem(tx *gorm.DB, draftID uint, item models.OrderItem, actor orderActor, ttl time.Duration) (reorderLine, error) {
+	line := reorderLine{
+		ProductID:         item.ProductID,
+		RequestedQuantity: item.Quantity,
+		PreviousUnitPrice: item.UnitPrice,
+		Status:            reorderLineSkipped,
+	}
+
+	var product models.Product
+	if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, item.ProductID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			line.Reason = "product no longer exists"
+			return line, nil
+		}
+		return line, err
+	}
+	line.ProductName = product.Name
+	line.UnitPrice = product.Price
+	line.PriceChanged = product.Price != item.UnitPrice
+
+	if product.DeletedAt.Valid {
+		line.Reason = "product is no longer sold"
+		return line, nil
+	}
+
+	others, err := reservedQuantity(tx, product.ID, draftID)
+	if err != nil {
+		return line, err
+	}
+	own, err := activeReservation(tx, draftID, product.ID)
+	if err != nil {
+		return line, err
+	}
+	available := product.StockQuantity - others
+	if own != nil {
+		available -= own.Quantity
+	}
+	if available <= 0 {
+		line.Reason = "out of stock"
+		return line, nil
+	}
+
+	line.Quantity = item.Quantity
+	line.Status = reorderLineAdded
+	if available < item.Quantity {
+		line.Quantity = available
+		line.Status = reorderLineCapped
+		line.Reason = "limited stock"
+	}
+
+	err = applyOrderItemChangeTx(tx, orderItemChange{OrderID: draftID, ProductID: product.ID, Quantity: line.Quantity, Add: true}, actor, ttl)
+	var orderErr *orderError
+	if errors.As(err, &orderErr) {
+		line.Quantity = 0
+		line.Status = reorderLineSkipped
+		line.Reason = orderErr.Message
+		return line, nil
+	}
+	return line, err
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupReorderTestDB(t *testing.T) (models.User, models.User) {
+	t.Helper()
+	store, supplier := setupOrderTestDB(t)
+	migrateTestModels(t, &models.StockReservation{})
+	return store, supplier
+}
+
+func buildReorderRouter(user models.User) *gin.Engine {
+	r := buildOrderRouter(user)
+	r.POST("/orders/:id/reorder", ReorderOrder)
+	return r
+}
+
+func TestReorderReusesDraftAndReportsLines(t *testing.T) {
+	store, supplier := setupReorderTestDB(t)
+	rice := models.Product{SupplierID: supplier.ID, Name: "Rice", SKU: "RICE-1", Price: 60, StockQuantity: 2}
+	oil := models.Product{SupplierID: supplier.ID, Name: "Oil", SKU: "OIL-1", Price: 90, StockQuantity: 10}
+	database.DB.Create(&rice)
+	database.DB.Create(&oil)
+
+	delivered := createTestOrder(t, store, supplier, models.OrderStatusDelivered)
+	database.DB.Create(&models.OrderItem{OrderID: delivered.ID, ProductID: rice.ID, Quantity: 5, UnitPrice: 50, Subtotal: 250})
+	database.DB.Create(&models.OrderItem{OrderID: delivered.ID, ProductID: oil.ID, Quantity: 1, UnitPrice: 90, Subtotal: 90})
+	database.DB.Delete(&oil)
+
+	draft := createTestOrder(t, store, supplier, models.OrderStatusDraft)
+
+	w := doOrderRequest(buildReorderRouter(store), http.MethodPost, fmt.Sprintf("/orders/%d/reorder", delivered.ID), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Order models.Order  `json:"order"`
+		Lines []reorderLine `json:"lines"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Order.ID != draft.ID {
+		t.Fatalf("expected existing draft %d to be reused, got %d", draft.ID, resp.Order.ID)
+	}
+	if len(resp.Lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(resp.Lines))
+	}
+
+	riceLine, oilLine := resp.Lines[0], resp.Lines[1]
+	if riceLine.Status != reorderLineCapped || riceLine.Quantity != 2 || !riceLine.PriceChanged || riceLine.UnitPrice != 60 {
+		t.Fatalf("unexpected rice line: %+v", riceLine)
+	}
+	if oilLine.Status != reorderLineSkipped || oilLine.Quantity != 0 {
+		t.Fatalf("unexpected oil line: %+v", oilLine)
+	}
+	if len(resp.Order.OrderItems) != 1 || resp.Order.TotalAmount != 120 {
+		t.Fatalf("unexpected draft: %d items, total %.2f", len(resp.Order.OrderItems), resp.Order.TotalAmount)
+	}
+
+	preparing := createTestOrder(t, store, supplier, models.OrderStatusPreparing)
+	w = doOrderRequest(buildReorderRouter(store), http.MethodPost, fmt.Sprintf("/orders/%d/reorder", preparing.ID), "")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for an open order, got %d", w.Code)
+	}
+}
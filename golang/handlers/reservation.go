@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"siargao-trading-road/config"
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Draft orders reserve stock instead of taking it from products.stock_quantity.
+// Reservation changes are logged to stock history in available-to-sell terms
+// (on-hand minus active reservations); only submitting a draft moves on-hand stock.
+
+func reservationTTL(c *gin.Context) time.Duration {
+	if cfgVal, ok := c.Get("config"); ok {
+		if cfg, ok := cfgVal.(*config.Config); ok && cfg.StockReservationTTL > 0 {
+			return cfg.StockReservationTTL
+		}
+	}
+	return config.DefaultStockReservationTTL
+}
+
+// reservedQuantity sums active, unexpired reservations on a product, ignoring
+// those held by excludeOrderID.
+func reservedQuantity(tx *gorm.DB, productID uint, excludeOrderID uint) (int, error) {
+	var reserved int
+	err := tx.Model(&models.StockReservation{}).
+		Where("product_id = ? AND order_id != ? AND status = ? AND expires_at > ?", productID, excludeOrderID, models.ReservationStatusActive, time.Now()).
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&reserved).Error
+	return reserved, err
+}
+
+// activeReservation returns the order's live reservation on a product, if any.
+func activeReservation(tx *gorm.DB, orderID, productID uint) (*models.StockReservation, error) {
+	var reservation models.StockReservation
+	err := tx.Where("order_id = ? AND product_id = ? AND status = ? AND expires_at > ?", orderID, productID, models.ReservationStatusActive, time.Now()).
+		First(&reservation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+// reserveStock sets the draft order's reservation on product to quantity and
+// returns available-to-sell before and after the change.
+func reserveStock(tx *gorm.DB, orderID uint, product models.Product, quantity int, ttl time.Duration) (int, int, error) {
+	others, err := reservedQuantity(tx, product.ID, orderID)
+	if err != nil {
+		return 0, 0, err
+	}
+	own, err := activeReservation(tx, orderID, product.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	available := product.StockQuantity - others
+	if quantity > available {
+		return 0, 0, &orderError{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("insufficient stock: only %d %s available", available, product.Unit),
+		}
+	}
+
+	reservation := models.StockReservation{
+		OrderID:   orderID,
+		ProductID: product.ID,
+		Quantity:  quantity,
+		Status:    models.ReservationStatusActive,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	err = tx.Omit("Product", "Order").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "order_id"}, {Name: "product_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"quantity", "status", "expires_at", "released_at", "updated_at"}),
+	}).Create(&reservation).Error
+	if err != nil {
+		return 0, 0, err
+	}
+
+	previous := available
+	if own != nil {
+		previous -= own.Quantity
+	}
+	return previous, available - quantity, nil
+}
+
+// releaseStock drops the draft order's reservation on product and returns
+// available-to-sell before and after the change.
+func releaseStock(tx *gorm.DB, orderID uint, product models.Product) (int, int, error) {
+	own, err := activeReservation(tx, orderID, product.ID)
+	if err != nil || own == nil {
+		return 0, 0, err
+	}
+	others, err := reservedQuantity(tx, product.ID, orderID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	now := time.Now()
+	if err := tx.Model(&models.StockReservation{}).Where("id = ?", own.ID).
+		Updates(map[string]interface{}{"status": models.ReservationStatusReleased, "released_at": now}).Error; err != nil {
+		return 0, 0, err
+	}
+
+	available := product.StockQuantity - others
+	return available - own.Quantity, available, nil
+}
+
+// extendOrderReservations pushes back the expiry of every live reservation on
+// a draft, so an active cart keeps its stock.
+func extendOrderReservations(tx *gorm.DB, orderID uint, ttl time.Duration) error {
+	return tx.Model(&models.StockReservation{}).
+		Where("order_id = ? AND status = ? AND expires_at > ?", orderID, models.ReservationStatusActive, time.Now()).
+		Update("expires_at", time.Now().Add(ttl)).Error
+}
+
+// releaseOrderReservations releases every live reservation held by an order.
+func releaseOrderReservations(tx *gorm.DB, orderID uint, actor orderActor, notes string) error {
+	var reservations []models.StockReservation
+	if err := tx.Where("order_id = ? AND status = ?", orderID, models.ReservationStatusActive).Find(&reservations).Error; err != nil {
+		return err
+	}
+
+	for _, reservation := range reservations {
+		if err := releaseReservation(tx, reservation, actor.userIDPtr(), actor.EmployeeID, notes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseReservation marks one reservation released and logs the change in
+// available stock. An already expired reservation no longer counted against
+// stock, so only the status changes.
+func releaseReservation(tx *gorm.DB, reservation models.StockReservation, userID, employeeID *uint, notes string) error {
+	now := time.Now()
+	result := tx.Model(&models.StockReservation{}).
+		Where("id = ? AND status = ?", reservation.ID, models.ReservationStatusActive).
+		Updates(map[string]interface{}{"status": models.ReservationStatusReleased, "released_at": now})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+
+	var product models.Product
+	if err := tx.Unscoped().First(&product, reservation.ProductID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	reserved, err := reservedQuantity(tx, product.ID, 0)
+	if err != nil {
+		return err
+	}
+
+	available := product.StockQuantity - reserved
+	previous := available
+	if reservation.ExpiresAt.After(now) {
+		previous -= reservation.Quantity
+	}
+	orderID := reservation.OrderID
+	return logStockChangeTx(tx, product.ID, previous, available, "reservation_released", userID, employeeID, &orderID, notes)
+}
+
+// consumeOrderReservations turns a draft's reservations into real stock
+// deductions when it is submitted. Items whose reservation has lapsed are
+// re-checked against what is available now.
+func consumeOrderReservations(tx *gorm.DB, order *models.Order, actor orderActor) error {
+	for _, item := range order.OrderItems {
+		var product models.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, item.ProductID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("product %d is no longer available", item.ProductID)}
+			}
+			return err
+		}
+
+		others, err := reservedQuantity(tx, product.ID, order.ID)
+		if err != nil {
+			return err
+		}
+		if available := product.StockQuantity - others; item.Quantity > available {
+			return &orderError{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("insufficient stock for %s: only %d %s available", product.Name, available, product.Unit),
+			}
+		}
+
+		// The conditional update is the last line of defence should the row
+		// lock be unavailable (e.g. SQLite): it never takes stock below zero.
+		result := tx.Model(&models.Product{}).Where("id = ? AND stock_quantity >= ?", product.ID, item.Quantity).
+			UpdateColumn("stock_quantity", gorm.Expr("stock_quantity - ?", item.Quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return &orderError{Code: http.StatusConflict, Message: fmt.Sprintf("insufficient stock for %s", product.Name)}
+		}
+		if err := tx.Model(&models.StockReservation{}).
+			Where("order_id = ? AND product_id = ? AND status = ?", order.ID, product.ID, models.ReservationStatusActive).
+			Update("status", models.ReservationStatusConsumed).Error; err != nil {
+			return err
+		}
+
+		orderID := order.ID
+		if err := logStockChangeTx(tx, product.ID, product.StockQuantity, product.StockQuantity-item.Quantity, "order_submitted", actor.userIDPtr(), actor.EmployeeID, &orderID, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseExpiredReservations is the sweeper job: it releases reservations on
+// draft orders whose hold has run out.
+func releaseExpiredReservations(now time.Time) {
+	var expired []models.StockReservation
+	if err := database.DB.
+		Joins("JOIN orders ON orders.id = stock_reservations.order_id").
+		Where("stock_reservations.status = ? AND stock_reservations.expires_at <= ? AND orders.status = ?", models.ReservationStatusActive, now, models.OrderStatusDraft).
+		Find(&expired).Error; err != nil {
+		log.Printf("releaseExpiredReservations: failed to load expired reservations: %v", err)
+		return
+	}
+
+	for _, reservation := range expired {
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			return releaseReservation(tx, reservation, nil, nil, "draft reservation expired")
+		})
+		if err != nil {
+			log.Printf("releaseExpiredReservations: failed to release reservation %d: %v", reservation.ID, err)
+		}
+	}
+
+	if len(expired) > 0 {
+		log.Printf("releaseExpiredReservations: released %d expired reservations", len(expired))
+	}
+}
+
+// fillAvailableQuantities sets AvailableQuantity on each product using one
+// aggregated reservation query.
+func fillAvailableQuantities(products []models.Product) {
+	if len(products) == 0 {
+		return
+	}
+	ids := make([]uint, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+
+	var rows []struct {
+		ProductID uint
+		Reserved  int
+	}
+	database.DB.Model(&models.StockReservation{}).
+		Select("product_id, COALESCE(SUM(quantity), 0) as reserved").
+		Where("product_id IN ? AND status = ? AND expires_at > ?", ids, models.ReservationStatusActive, time.Now()).
+		Group("product_id").
+		Scan(&rows)
+
+	reserved := make(map[uint]int, len(rows))
+	for _, r := range rows {
+		reserved[r.ProductID] = r.Reserved
+	}
+	for i := range products {
+		available := products[i].StockQuantity - reserved[products[i].ID]
+		if available < 0 {
+			available = 0
+		}
+		products[i].AvailableQuantity = &available
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupReservationTestDB(t *testing.T) (models.User, models.User) {
+	t.Helper()
+	store, supplier := setupOrderTestDB(t)
+	migrateTestModels(t, &models.StockReservation{}, &models.SupplierDeliverySettings{}, &models.DeliveryFeeBand{}, &models.DeliveryFeeZone{}, &models.ServiceAreaPlace{}, &models.ScheduleException{})
+	return store, supplier
+}
+
+func buildReservationRouter(user models.User) *gin.Engine {
+	r := buildOrderRouter(user)
+	r.POST("/orders/:id/items", AddOrderItem)
+	r.POST("/orders/:id/submit", SubmitOrder)
+	return r
+}
+
+func TestDraftItemsReserveStockUntilSubmit(t *testing.T) {
+	store, supplier := setupReservationTestDB(t)
+	otherStore := models.User{Email: "other@example.com", Password: "x", Name: "Other", Role: models.RoleStore}
+	database.DB.Create(&otherStore)
+	product := models.Product{SupplierID: supplier.ID, Name: "Rice", SKU: "RICE-1", Price: 2000, StockQuantity: 10, Unit: "sack"}
+	database.DB.Create(&product)
+
+	draft := createTestOrder(t, store, supplier, models.OrderStatusDraft)
+	w := doOrderRequest(buildReservationRouter(store), http.MethodPost, fmt.Sprintf("/orders/%d/items", draft.ID), fmt.Sprintf(`{"product_id":%d,"quantity":3}`, product.ID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("add item: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var reloaded models.Product
+	database.DB.First(&reloaded, product.ID)
+	if reloaded.StockQuantity != 10 {
+		t.Fatalf("draft must not change on-hand stock, got %d", reloaded.StockQuantity)
+	}
+
+	otherDraft := createTestOrder(t, otherStore, supplier, models.OrderStatusDraft)
+	w = doOrderRequest(buildReservationRouter(otherStore), http.MethodPost, fmt.Sprintf("/orders/%d/items", otherDraft.ID), fmt.Sprintf(`{"product_id":%d,"quantity":8}`, product.ID))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected reserved stock to be unavailable, got %d", w.Code)
+	}
+
+	w = doOrderRequest(buildReservationRouter(store), http.MethodPost, fmt.Sprintf("/orders/%d/submit", draft.ID), `{"payment_method":"cash_on_delivery","delivery_option":"pickup"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("submit: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	database.DB.First(&reloaded, product.ID)
+	if reloaded.StockQuantity != 7 {
+		t.Fatalf("expected stock 7 after submit, got %d", reloaded.StockQuantity)
+	}
+	var reservation models.StockReservation
+	database.DB.Where("order_id = ?", draft.ID).First(&reservation)
+	if reservation.Status != models.ReservationStatusConsumed {
+		t.Fatalf("expected consumed reservation, got %s", reservation.Status)
+	}
+}
+
+func TestExpiredReservationsAreReleased(t *testing.T) {
+	store, supplier := setupReservationTestDB(t)
+	product := models.Product{SupplierID: supplier.ID, Name: "Rice", SKU: "RICE-1", Price: 50, StockQuantity: 5}
+	database.DB.Create(&product)
+	draft := createTestOrder(t, store, supplier, models.OrderStatusDraft)
+	reservation := models.StockReservation{OrderID: draft.ID, ProductID: product.ID, Quantity: 5, Status: models.ReservationStatusActive, ExpiresAt: time.Now().Add(-time.Minute)}
+	database.DB.Create(&reservation)
+
+	releaseExpiredReservations(time.Now())
+
+	database.DB.First(&reservation, reservation.ID)
+	if reservation.Status != models.ReservationStatusReleased || reservation.ReleasedAt == nil {
+		t.Fatalf("expected released reservation, got %+v", reservation)
+	}
+
+	other := createTestOrder(t, store, supplier, models.OrderStatusDraft)
+	w := doOrderRequest(buildReservationRouter(store), http.MethodPost, fmt.Sprintf("/orders/%d/items", other.ID), fmt.Sprintf(`{"product_id":%d,"quantity":5}`, product.ID))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected released stock to be available, got %d: %s", w.Code, w.Body.String())
+	}
+}
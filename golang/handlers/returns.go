@@ -0,0 +1,357 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type returnLine struct {
+	OrderItemID uint     `json:"order_item_id" binding:"required"`
+	Quantity    int      `json:"quantity" binding:"required,min=1"`
+	Reason      string   `json:"reason" binding:"required"`
+	PhotoURLs   []string `json:"photo_urls"`
+}
+
+// CreateReturnRequest opens a return against lines of a delivered order. A
+// line can be returned up to the quantity that was shipped, less what earlier
+// pending or approved returns already claim.
+func CreateReturnRequest(c *gin.Context) {
+	orderID := c.Param("id")
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+
+	role, _ := c.Get("role")
+	if role != "store" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only stores can request returns"})
+		return
+	}
+
+	var req struct {
+		Notes string       `json:"notes"`
+		Items []returnLine `json:"items" binding:"required,min=1,dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var returnRequest models.ReturnRequest
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Locking the order serialises returns against it, so two requests
+		// cannot both claim the same remaining quantity.
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND store_id = ?", orderID, userID).First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &orderError{Code: http.StatusNotFound, Message: "order not found"}
+			}
+			return err
+		}
+		if order.Status != models.OrderStatusDelivered {
+			return &orderError{Code: http.StatusBadRequest, Message: "returns can only be requested for delivered orders"}
+		}
+
+		var orderItems []models.OrderItem
+		if err := tx.Where("order_id = ?", order.ID).Find(&orderItems).Error; err != nil {
+			return err
+		}
+		itemsByID := make(map[uint]models.OrderItem, len(orderItems))
+		for _, item := range orderItems {
+			itemsByID[item.ID] = item
+		}
+
+		claimed, err := claimedReturnQuantities(tx, order.ID)
+		if err != nil {
+			return err
+		}
+
+		returnRequest = models.ReturnRequest{
+			OrderID:    order.ID,
+			StoreID:    order.StoreID,
+			SupplierID: order.SupplierID,
+			Status:     models.ReturnStatusPending,
+			Notes:      req.Notes,
+		}
+		for _, line := range req.Items {
+			item, ok := itemsByID[line.OrderItemID]
+			if !ok {
+				return &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("item %d is not on this order", line.OrderItemID)}
+			}
+			returnable := item.BilledQuantity() - claimed[item.ID]
+			if line.Quantity > returnable {
+				return &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("item %d: only %d can be returned", item.ID, returnable)}
+			}
+			claimed[item.ID] += line.Quantity
+
+			photoURLs := line.PhotoURLs
+			if photoURLs == nil {
+				photoURLs = []string{}
+			}
+			returnRequest.Items = append(returnRequest.Items, models.ReturnItem{
+				OrderItemID: item.ID,
+				ProductID:   item.ShippedProductID(),
+				Quantity:    line.Quantity,
+				UnitPrice:   item.BilledUnitPrice(),
+				Reason:      strings.TrimSpace(line.Reason),
+				PhotoURLs:   photoURLs,
+			})
+		}
+
+		return tx.Omit("Order", "Store", "Supplier", "CreditMemo", "Items.Product").Create(&returnRequest).Error
+	})
+	if err != nil {
+		writeOrderError(c, err, "failed to create return request")
+		return
+	}
+
+	loadReturnRequest(&returnRequest)
+	c.JSON(http.StatusCreated, returnRequest)
+}
+
+// claimedReturnQuantities sums, per order item, the quantity held by pending
+// and approved returns on the order.
+func claimedReturnQuantities(tx *gorm.DB, orderID uint) (map[uint]int, error) {
+	var rows []struct {
+		OrderItemID uint
+		Quantity    int
+	}
+	err := tx.Model(&models.ReturnItem{}).
+		Select("return_items.order_item_id, COALESCE(SUM(return_items.quantity), 0) as quantity").
+		Joins("JOIN return_requests ON return_requests.id = return_items.return_request_id").
+		Where("return_requests.order_id = ? AND return_requests.status IN ?", orderID, []models.ReturnStatus{models.ReturnStatusPending, models.ReturnStatusApproved}).
+		Group("return_items.order_item_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := make(map[uint]int, len(rows))
+	for _, r := range rows {
+		claimed[r.OrderItemID] = r.Quantity
+	}
+	return claimed, nil
+}
+
+func GetReturnRequests(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	role, _ := c.Get("role")
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+
+	query := database.DB.Preload("Store").Preload("Supplier").Preload("Items").Preload("Items.Product").Preload("CreditMemo")
+	switch role {
+	case "supplier":
+		query = query.Where("supplier_id = ?", userID)
+	case "store":
+		query = query.Where("store_id = ?", userID)
+	case "admin":
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if orderID := c.Query("order_id"); orderID != "" {
+		query = query.Where("order_id = ?", orderID)
+	}
+
+	var returns []models.ReturnRequest
+	if err := query.Order("created_at DESC").Find(&returns).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch return requests"})
+		return
+	}
+
+	c.JSON(http.StatusOK, returns)
+}
+
+func GetReturnRequest(c *gin.Context) {
+	returnRequest, ok := findReturnRequest(c)
+	if !ok {
+		return
+	}
+	loadReturnRequest(&returnRequest)
+	c.JSON(http.StatusOK, returnRequest)
+}
+
+// ApproveReturnRequest puts the returned goods back in stock and issues a
+// credit memo for them against the original order.
+func ApproveReturnRequest(c *gin.Context) {
+	returnRequest, ok := findReturnRequest(c)
+	if !ok {
+		return
+	}
+	role, _ := c.Get("role")
+	if role != "supplier" && role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the supplier can approve returns"})
+		return
+	}
+
+	actor := getOrderActor(c)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").First(&returnRequest, returnRequest.ID).Error; err != nil {
+			return err
+		}
+		if returnRequest.Status != models.ReturnStatusPending {
+			return &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("return request is already %s", returnRequest.Status)}
+		}
+
+		var amount float64
+		notes := fmt.Sprintf("return #%d", returnRequest.ID)
+		for _, item := range returnRequest.Items {
+			if err := adjustOrderStock(tx, returnRequest.OrderID, item.ProductID, -item.Quantity, "return", actor, notes); err != nil {
+				return err
+			}
+			amount += float64(item.Quantity) * item.UnitPrice
+		}
+
+		now := time.Now()
+		if err := tx.Model(&models.ReturnRequest{}).Where("id = ?", returnRequest.ID).Updates(map[string]interface{}{
+			"status":         models.ReturnStatusApproved,
+			"reviewed_by_id": actor.userIDPtr(),
+			"reviewed_at":    now,
+		}).Error; err != nil {
+			return err
+		}
+
+		memo := models.CreditMemo{
+			MemoNumber:      fmt.Sprintf("CM-%06d", returnRequest.ID),
+			ReturnRequestID: returnRequest.ID,
+			OrderID:         returnRequest.OrderID,
+			StoreID:         returnRequest.StoreID,
+			SupplierID:      returnRequest.SupplierID,
+			Amount:          amount,
+		}
+		return tx.Create(&memo).Error
+	})
+	if err != nil {
+		writeOrderError(c, err, "failed to approve return request")
+		return
+	}
+
+	loadReturnRequest(&returnRequest)
+	c.JSON(http.StatusOK, returnRequest)
+}
+
+func RejectReturnRequest(c *gin.Context) {
+	returnRequest, ok := findReturnRequest(c)
+	if !ok {
+		return
+	}
+	role, _ := c.Get("role")
+	if role != "supplier" && role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the supplier can reject returns"})
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor := getOrderActor(c)
+	result := database.DB.Model(&models.ReturnRequest{}).
+		Where("id = ? AND status = ?", returnRequest.ID, models.ReturnStatusPending).
+		Updates(map[string]interface{}{
+			"status":           models.ReturnStatusRejected,
+			"rejection_reason": req.Reason,
+			"reviewed_by_id":   actor.userIDPtr(),
+			"reviewed_at":      time.Now(),
+		})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reject return request"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("return request is already %s", returnRequest.Status)})
+		return
+	}
+
+	loadReturnRequest(&returnRequest)
+	c.JSON(http.StatusOK, returnRequest)
+}
+
+// findReturnRequest loads the return named in the path if the caller is a
+// party to it, answering 404 otherwise.
+func findReturnRequest(c *gin.Context) (models.ReturnRequest, bool) {
+	var returnRequest models.ReturnRequest
+	userID, _ := c.Get("user_id")
+	role, _ := c.Get("role")
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return returnRequest, false
+	}
+
+	query := database.DB.Where("id = ?", c.Param("id"))
+	switch role {
+	case "supplier":
+		query = query.Where("supplier_id = ?", userID)
+	case "store":
+		query = query.Where("store_id = ?", userID)
+	}
+	if err := query.First(&returnRequest).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "return request not found"})
+		return returnRequest, false
+	}
+	return returnRequest, true
+}
+
+func loadReturnRequest(returnRequest *models.ReturnRequest) {
+	database.DB.Preload("Store").Preload("Supplier").Preload("Items").Preload("Items.Product").Preload("CreditMemo").
+		First(returnRequest, returnRequest.ID)
+}
+
+// returnAnalytics summarises returns and credit memos for one side of the
+// trade; column is store_id or supplier_id.
+func returnAnalytics(column string, userID interface{}) gin.H {
+	var counts struct {
+		Total   int64
+		Pending int64
+	}
+	database.DB.Model(&models.ReturnRequest{}).
+		Where(column+" = ?", userID).
+		Select("COUNT(*) as total, COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) as pending", models.ReturnStatusPending).
+		Scan(&counts)
+
+	var totalCredited float64
+	database.DB.Model(&models.CreditMemo{}).
+		Where(column+" = ?", userID).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&totalCredited)
+
+	var returnedQuantity int64
+	database.DB.Model(&models.ReturnItem{}).
+		Joins("JOIN return_requests ON return_requests.id = return_items.return_request_id").
+		Where("return_requests."+column+" = ? AND return_requests.status = ?", userID, models.ReturnStatusApproved).
+		Select("COALESCE(SUM(return_items.quantity), 0)").
+		Scan(&returnedQuantity)
+
+	return gin.H{
+		"total_returns":     counts.Total,
+		"pending_returns":   counts.Pending,
+		"returned_quantity": returnedQuantity,
+		"total_credited":    totalCredited,
+	}
+}
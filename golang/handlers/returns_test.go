@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupReturnTestDB(t *testing.T) (models.User, models.User) {
+	t.Helper()
+	store, supplier := setupOrderTestDB(t)
+	migrateTestModels(t, &models.ReturnRequest{}, &models.ReturnItem{}, &models.CreditMemo{})
+	return store, supplier
+}
+
+func buildReturnRouter(user models.User) *gin.Engine {
+	r := buildOrderRouter(user)
+	r.POST("/orders/:id/returns", CreateReturnRequest)
+	r.POST("/returns/:id/approve", ApproveReturnRequest)
+	r.POST("/returns/:id/reject", RejectReturnRequest)
+	return r
+}
+
+func createDeliveredOrderWithItem(t *testing.T, store, supplier models.User, product models.Product, quantity int) (models.Order, models.OrderItem) {
+	t.Helper()
+	order := createTestOrder(t, store, supplier, models.OrderStatusDelivered)
+	item := models.OrderItem{OrderID: order.ID, ProductID: product.ID, Quantity: quantity, UnitPrice: product.Price, Subtotal: float64(quantity) * product.Price}
+	if err := database.DB.Create(&item).Error; err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+	return order, item
+}
+
+func TestApproveReturnRestocksAndIssuesCreditMemo(t *testing.T) {
+	store, supplier := setupReturnTestDB(t)
+	product := models.Product{SupplierID: supplier.ID, Name: "Eggs", SKU: "EGG-1", Price: 8, StockQuantity: 10}
+	if err := database.DB.Create(&product).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	order, item := createDeliveredOrderWithItem(t, store, supplier, product, 12)
+
+	body := fmt.Sprintf(`{"items":[{"order_item_id":%d,"quantity":3,"reason":"cracked","photo_urls":["https://example.com/1.jpg"]}]}`, item.ID)
+	w := doOrderRequest(buildReturnRouter(store), http.MethodPost, fmt.Sprintf("/orders/%d/returns", order.ID), body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created models.ReturnRequest
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(created.Items) != 1 || len(created.Items[0].PhotoURLs) != 1 {
+		t.Fatalf("unexpected return request: %+v", created)
+	}
+
+	// Only 9 of the 12 delivered remain returnable.
+	over := fmt.Sprintf(`{"items":[{"order_item_id":%d,"quantity":10,"reason":"wrong size"}]}`, item.ID)
+	if w := doOrderRequest(buildReturnRouter(store), http.MethodPost, fmt.Sprintf("/orders/%d/returns", order.ID), over); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected over-claim to be refused, got %d", w.Code)
+	}
+
+	if w := doOrderRequest(buildReturnRouter(store), http.MethodPost, fmt.Sprintf("/returns/%d/approve", created.ID), ""); w.Code != http.StatusForbidden {
+		t.Fatalf("expected store approval to be refused, got %d", w.Code)
+	}
+	w = doOrderRequest(buildReturnRouter(supplier), http.MethodPost, fmt.Sprintf("/returns/%d/approve", created.ID), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var reloaded models.Product
+	database.DB.First(&reloaded, product.ID)
+	if reloaded.StockQuantity != 13 {
+		t.Fatalf("expected stock 13, got %d", reloaded.StockQuantity)
+	}
+	var history models.StockHistory
+	if err := database.DB.Where("product_id = ? AND change_type = ?", product.ID, "return").First(&history).Error; err != nil {
+		t.Fatalf("stock history not written: %v", err)
+	}
+	if history.ChangeAmount != 3 || history.OrderID == nil || *history.OrderID != order.ID {
+		t.Fatalf("unexpected stock history: %+v", history)
+	}
+
+	var memo models.CreditMemo
+	if err := database.DB.Where("return_request_id = ?", created.ID).First(&memo).Error; err != nil {
+		t.Fatalf("credit memo not created: %v", err)
+	}
+	if memo.OrderID != order.ID || memo.Amount != 24 {
+		t.Fatalf("unexpected credit memo: %+v", memo)
+	}
+
+	if w := doOrderRequest(buildReturnRouter(supplier), http.MethodPost, fmt.Sprintf("/returns/%d/approve", created.ID), ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected second approval to be refused, got %d", w.Code)
+	}
+
+	stats := returnAnalytics("store_id", store.ID)
+	if stats["total_credited"].(float64) != 24 || stats["returned_quantity"].(int64) != 3 {
+		t.Fatalf("unexpected return analytics: %+v", stats)
+	}
+}
+
+func TestRejectReturnFreesQuantity(t *testing.T) {
+	store, supplier := setupReturnTestDB(t)
+	product := models.Product{SupplierID: supplier.ID, Name: "Rice", SKU: "RICE-1", Price: 50, StockQuantity: 5}
+	if err := database.DB.Create(&product).Error; err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	order, item := createDeliveredOrderWithItem(t, store, supplier, product, 2)
+
+	path := fmt.Sprintf("/orders/%d/returns", order.ID)
+	body := fmt.Sprintf(`{"items":[{"order_item_id":%d,"quantity":2,"reason":"wet"}]}`, item.ID)
+	w := doOrderRequest(buildReturnRouter(store), http.MethodPost, path, body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created models.ReturnRequest
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	w = doOrderRequest(buildReturnRouter(supplier), http.MethodPost, fmt.Sprintf("/returns/%d/reject", created.ID), `{"reason":"bags were dry on delivery"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var reloaded models.Product
+	database.DB.First(&reloaded, product.ID)
+	if reloaded.StockQuantity != 5 {
+		t.Fatalf("rejected return changed stock to %d", reloaded.StockQuantity)
+	}
+
+	if w := doOrderRequest(buildReturnRouter(store), http.MethodPost, path, body); w.Code != http.StatusCreated {
+		t.Fatalf("expected the rejected quantity to be returnable again, got %d", w.Code)
+	}
+}
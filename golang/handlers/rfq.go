@@ -0,0 +1,447 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"siargao-trading-road/config"
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type rfqRequest struct {
+	Title       string `json:"title" binding:"required"`
+	Notes       string `json:"notes"`
+	SupplierIDs []uint `json:"supplier_ids" binding:"required,min=1"`
+	Lines       []struct {
+		Description string `json:"description" binding:"required"`
+		Quantity    int    `json:"quantity" binding:"required,min=1"`
+		Unit        string `json:"unit"`
+		ProductID   *uint  `json:"product_id"`
+	} `json:"lines" binding:"required,min=1,dive"`
+}
+
+type quoteRequest struct {
+	ExpiresAt time.Time `json:"expires_at" binding:"required"`
+	Notes     string    `json:"notes"`
+	Lines     []struct {
+		RFQLineID uint    `json:"rfq_line_id" binding:"required"`
+		ProductID uint    `json:"product_id" binding:"required"`
+		Quantity  int     `json:"quantity"`
+		UnitPrice float64 `json:"unit_price" binding:"required,gt=0"`
+	} `json:"lines" binding:"required,min=1,dive"`
+}
+
+// CreateRFQ lets a store send a request for quote to one or more suppliers.
+func CreateRFQ(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+
+	role, _ := c.Get("role")
+	if role != "store" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only stores can request quotes"})
+		return
+	}
+
+	var req rfqRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	supplierIDs := make([]uint, 0, len(req.SupplierIDs))
+	seen := make(map[uint]bool)
+	for _, id := range req.SupplierIDs {
+		if !seen[id] {
+			seen[id] = true
+			supplierIDs = append(supplierIDs, id)
+		}
+	}
+	var suppliers []models.User
+	database.DB.Where("id IN ? AND role = ?", supplierIDs, models.RoleSupplier).Find(&suppliers)
+	if len(suppliers) != len(supplierIDs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "one or more suppliers not found"})
+		return
+	}
+
+	rfq := models.RFQ{
+		StoreID: userID,
+		Title:   strings.TrimSpace(req.Title),
+		Notes:   req.Notes,
+		Status:  models.RFQStatusOpen,
+	}
+	for _, line := range req.Lines {
+		rfq.Lines = append(rfq.Lines, models.RFQLine{
+			Description: strings.TrimSpace(line.Description),
+			Quantity:    line.Quantity,
+			Unit:        line.Unit,
+			ProductID:   line.ProductID,
+		})
+	}
+	for _, supplier := range suppliers {
+		rfq.Recipients = append(rfq.Recipients, models.RFQRecipient{SupplierID: supplier.ID})
+	}
+
+	if err := database.DB.Omit("Store", "Quotes", "Recipients.Supplier").Create(&rfq).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create request for quote"})
+		return
+	}
+
+	loadRFQ(&rfq, "store", userID)
+
+	if emailService := getEmailService(c); emailService != nil {
+		for _, supplier := range suppliers {
+			go emailService.SendRFQReceivedEmail(rfq, supplier)
+		}
+	}
+
+	c.JSON(http.StatusCreated, rfq)
+}
+
+func GetRFQs(c *gin.Context) {
+	userID, _ := getUserID(c)
+	role, _ := c.Get("role")
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+
+	query := database.DB.Preload("Store").Preload("Lines").Preload("Recipients").Preload("Recipients.Supplier")
+	switch role {
+	case "store":
+		query = query.Where("store_id = ?", userID).Preload("Quotes")
+	case "supplier":
+		query = query.Where("id IN (?)", database.DB.Model(&models.RFQRecipient{}).Select("rfq_id").Where("supplier_id = ?", userID)).
+			Preload("Quotes", "supplier_id = ?", userID)
+	case "admin":
+		query = query.Preload("Quotes")
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+	query = query.Preload("Quotes.Supplier").Preload("Quotes.Lines")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var rfqs []models.RFQ
+	if err := query.Order("created_at DESC").Find(&rfqs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch requests for quote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rfqs)
+}
+
+func GetRFQ(c *gin.Context) {
+	rfq, ok := findRFQ(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, rfq)
+}
+
+// SubmitQuote records a recipient supplier's prices for an open RFQ. Quoting
+// again replaces the supplier's earlier quote as long as it was not accepted.
+func SubmitQuote(c *gin.Context) {
+	rfq, ok := findRFQ(c)
+	if !ok {
+		return
+	}
+	role, _ := c.Get("role")
+	if role != "supplier" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only suppliers can submit quotes"})
+		return
+	}
+	if rfq.Status != models.RFQStatusOpen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("request for quote is %s", rfq.Status)})
+		return
+	}
+	supplierID, _ := getUserID(c)
+
+	var req quoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	rfqLines := make(map[uint]models.RFQLine, len(rfq.Lines))
+	for _, line := range rfq.Lines {
+		rfqLines[line.ID] = line
+	}
+
+	quote := models.Quote{
+		RFQID:      rfq.ID,
+		SupplierID: supplierID,
+		Status:     models.QuoteStatusSubmitted,
+		ExpiresAt:  req.ExpiresAt,
+		Notes:      req.Notes,
+	}
+	seenLines := make(map[uint]bool)
+	seenProducts := make(map[uint]bool)
+	for _, line := range req.Lines {
+		rfqLine, ok := rfqLines[line.RFQLineID]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("line %d is not on this request for quote", line.RFQLineID)})
+			return
+		}
+		if seenLines[line.RFQLineID] || seenProducts[line.ProductID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "each line and product may only be quoted once"})
+			return
+		}
+		seenLines[line.RFQLineID] = true
+		seenProducts[line.ProductID] = true
+
+		var product models.Product
+		if err := database.DB.Where("id = ? AND supplier_id = ?", line.ProductID, supplierID).First(&product).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("product %d not found", line.ProductID)})
+			return
+		}
+
+		quantity := line.Quantity
+		if quantity <= 0 {
+			quantity = rfqLine.Quantity
+		}
+		quote.Lines = append(quote.Lines, models.QuoteLine{
+			RFQLineID: rfqLine.ID,
+			ProductID: product.ID,
+			Quantity:  quantity,
+			UnitPrice: line.UnitPrice,
+		})
+		quote.Total += float64(quantity) * line.UnitPrice
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var existing models.Quote
+		err := tx.Where("rfq_id = ? AND supplier_id = ?", rfq.ID, supplierID).First(&existing).Error
+		if err == nil {
+			if existing.Status != models.QuoteStatusSubmitted {
+				return &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("quote is already %s", existing.Status)}
+			}
+			if err := tx.Where("quote_id = ?", existing.ID).Delete(&models.QuoteLine{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&existing).Error; err != nil {
+				return err
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return tx.Omit("Supplier", "Lines.Product").Create(&quote).Error
+	})
+	if err != nil {
+		writeOrderError(c, err, "failed to submit quote")
+		return
+	}
+
+	database.DB.Preload("Supplier").Preload("Lines").Preload("Lines.Product").First(&quote, quote.ID)
+
+	if emailService := getEmailService(c); emailService != nil {
+		database.DB.Preload("Store").First(&rfq, rfq.ID)
+		go emailService.SendQuoteReceivedEmail(rfq, quote)
+	}
+
+	c.JSON(http.StatusCreated, quote)
+}
+
+// AcceptQuote turns an unexpired quote into a submitted order whose items are
+// priced from the quote rather than the catalogue. The other quotes on the
+// RFQ are rejected.
+func AcceptQuote(c *gin.Context) {
+	rfq, ok := findRFQ(c)
+	if !ok {
+		return
+	}
+	role, _ := c.Get("role")
+	if role != "store" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the store can accept a quote"})
+		return
+	}
+
+	var req orderSubmission
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor := getOrderActor(c)
+	var order models.Order
+	var transition models.OrderTransition
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&rfq, rfq.ID).Error; err != nil {
+			return err
+		}
+		if rfq.Status != models.RFQStatusOpen {
+			return &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("request for quote is %s", rfq.Status)}
+		}
+
+		var quote models.Quote
+		if err := tx.Preload("Lines").Where("id = ? AND rfq_id = ?", c.Param("quote_id"), rfq.ID).First(&quote).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &orderError{Code: http.StatusNotFound, Message: "quote not found"}
+			}
+			return err
+		}
+		if quote.Status != models.QuoteStatusSubmitted {
+			return &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("quote is %s", quote.Status)}
+		}
+		if !quote.ExpiresAt.After(time.Now()) {
+			return &orderError{Code: http.StatusBadRequest, Message: "quote has expired"}
+		}
+
+		quoteID := quote.ID
+		reason := fmt.Sprintf("quote #%d accepted", quote.ID)
+		order = models.Order{
+			StoreID:    rfq.StoreID,
+			SupplierID: quote.SupplierID,
+			Status:     models.OrderStatusDraft,
+			QuoteID:    &quoteID,
+		}
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+		if err := recordOrderStatusHistory(tx, order.ID, "", models.OrderStatusDraft, actor, reason); err != nil {
+			return err
+		}
+
+		for _, line := range quote.Lines {
+			change := orderItemChange{OrderID: order.ID, ProductID: line.ProductID, Quantity: line.Quantity, Add: true}
+			if err := applyOrderItemChangeTx(tx, change, actor, config.DefaultStockReservationTTL); err != nil {
+				return err
+			}
+			if err := tx.Model(&models.OrderItem{}).Where("order_id = ? AND product_id = ?", order.ID, line.ProductID).
+				Updates(map[string]interface{}{"unit_price": line.UnitPrice, "subtotal": float64(line.Quantity) * line.UnitPrice}).Error; err != nil {
+				return err
+			}
+		}
+		if err := recomputeOrderTotal(tx, order.ID); err != nil {
+			return err
+		}
+
+		if err := tx.Preload("OrderItems").First(&order, order.ID).Error; err != nil {
+			return err
+		}
+		if err := prepareOrderSubmission(tx, &order, req, true); err != nil {
+			return err
+		}
+		var err error
+		if transition, err = submitOrderTx(tx, &order, actor, reason); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Quote{}).Where("id = ?", quote.ID).Update("status", models.QuoteStatusAccepted).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Quote{}).Where("rfq_id = ? AND id != ? AND status = ?", rfq.ID, quote.ID, models.QuoteStatusSubmitted).
+			Update("status", models.QuoteStatusRejected).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RFQ{}).Where("id = ?", rfq.ID).Updates(map[string]interface{}{
+			"status":            models.RFQStatusAccepted,
+			"accepted_quote_id": quote.ID,
+			"order_id":          order.ID,
+		}).Error
+	})
+	if err != nil {
+		log.Printf("AcceptQuote: rfq %d: %v", rfq.ID, err)
+		writeOrderError(c, err, "failed to accept quote")
+		return
+	}
+
+	if err := database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product").First(&order, order.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load order details"})
+		return
+	}
+
+	runOrderNotifications(getEmailService(c), order, models.OrderStatusDraft, transition)
+
+	c.JSON(http.StatusOK, order)
+}
+
+// CloseRFQ withdraws an open RFQ; its quotes can no longer be accepted.
+func CloseRFQ(c *gin.Context) {
+	rfq, ok := findRFQ(c)
+	if !ok {
+		return
+	}
+	role, _ := c.Get("role")
+	if role != "store" && role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the store can close a request for quote"})
+		return
+	}
+
+	result := database.DB.Model(&models.RFQ{}).Where("id = ? AND status = ?", rfq.ID, models.RFQStatusOpen).Update("status", models.RFQStatusClosed)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to close request for quote"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("request for quote is %s", rfq.Status)})
+		return
+	}
+
+	rfq.Status = models.RFQStatusClosed
+	c.JSON(http.StatusOK, rfq)
+}
+
+// findRFQ loads the RFQ named in the path for its store, a recipient
+// supplier or an admin. Suppliers only ever see their own quote.
+func findRFQ(c *gin.Context) (models.RFQ, bool) {
+	var rfq models.RFQ
+	userID, _ := getUserID(c)
+	role, _ := c.Get("role")
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return rfq, false
+	}
+
+	query := database.DB.Where("id = ?", c.Param("id"))
+	switch role {
+	case "store":
+		query = query.Where("store_id = ?", userID)
+	case "supplier":
+		query = query.Where("id IN (?)", database.DB.Model(&models.RFQRecipient{}).Select("rfq_id").Where("supplier_id = ?", userID))
+	case "admin":
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return rfq, false
+	}
+	if err := query.First(&rfq).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "request for quote not found"})
+		return rfq, false
+	}
+
+	roleStr, _ := role.(string)
+	loadRFQ(&rfq, roleStr, userID)
+	return rfq, true
+}
+
+func loadRFQ(rfq *models.RFQ, role string, userID uint) {
+	query := database.DB.Preload("Store").Preload("Lines").Preload("Recipients").Preload("Recipients.Supplier")
+	if role == "supplier" {
+		query = query.Preload("Quotes", "supplier_id = ?", userID)
+	} else {
+		query = query.Preload("Quotes")
+	}
+	query.Preload("Quotes.Supplier").Preload("Quotes.Lines").Preload("Quotes.Lines.Product").First(rfq, rfq.ID)
+}
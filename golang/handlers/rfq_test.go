@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupRFQTestDB(t *testing.T) (models.User, models.User) {
+	t.Helper()
+	store, supplier := setupOrderTestDB(t)
+	migrateTestModels(t, &models.StockReservation{}, &models.SupplierDeliverySettings{}, &models.DeliveryFeeBand{}, &models.DeliveryFeeZone{}, &models.ServiceAreaPlace{}, &models.ScheduleException{}, &models.RFQ{}, &models.RFQLine{}, &models.RFQRecipient{}, &models.Quote{}, &models.QuoteLine{})
+	return store, supplier
+}
+
+func buildRFQRouter(user models.User) *gin.Engine {
+	r := buildOrderRouter(user)
+	r.POST("/rfqs", CreateRFQ)
+	r.POST("/rfqs/:id/quotes", SubmitQuote)
+	r.POST("/rfqs/:id/quotes/:quote_id/accept", AcceptQuote)
+	return r
+}
+
+func TestAcceptQuotePlacesOrderAtQuotedPrices(t *testing.T) {
+	store, supplier := setupRFQTestDB(t)
+	rival := models.User{Email: "rival@example.com", Password: "x", Name: "Rival", Role: models.RoleSupplier}
+	database.DB.Create(&rival)
+	rice := models.Product{SupplierID: supplier.ID, Name: "Rice", SKU: "RICE-1", Price: 2000, StockQuantity: 50, Unit: "sack"}
+	rivalRice := models.Product{SupplierID: rival.ID, Name: "Rice", SKU: "RICE-R", Price: 2100, StockQuantity: 50, Unit: "sack"}
+	database.DB.Create(&rice)
+	database.DB.Create(&rivalRice)
+
+	w := doOrderRequest(buildRFQRouter(store), http.MethodPost, "/rfqs",
+		fmt.Sprintf(`{"title":"Fiesta rice","supplier_ids":[%d,%d],"lines":[{"description":"Rice, 50kg","quantity":10,"unit":"sack"}]}`, supplier.ID, rival.ID))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create rfq: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var rfq models.RFQ
+	json.Unmarshal(w.Body.Bytes(), &rfq)
+	if len(rfq.Lines) != 1 || len(rfq.Recipients) != 2 {
+		t.Fatalf("unexpected rfq: %+v", rfq)
+	}
+
+	quoteBody := func(productID uint, price float64, expires time.Time) string {
+		return fmt.Sprintf(`{"expires_at":%q,"lines":[{"rfq_line_id":%d,"product_id":%d,"unit_price":%.2f}]}`,
+			expires.Format(time.RFC3339), rfq.Lines[0].ID, productID, price)
+	}
+	w = doOrderRequest(buildRFQRouter(supplier), http.MethodPost, fmt.Sprintf("/rfqs/%d/quotes", rfq.ID), quoteBody(rice.ID, 1800, time.Now().Add(48*time.Hour)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("submit quote: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var quote models.Quote
+	json.Unmarshal(w.Body.Bytes(), &quote)
+	if quote.Total != 18000 {
+		t.Fatalf("expected quote total 18000, got %.2f", quote.Total)
+	}
+
+	w = doOrderRequest(buildRFQRouter(rival), http.MethodPost, fmt.Sprintf("/rfqs/%d/quotes", rfq.ID), quoteBody(rice.ID, 1500, time.Now().Add(48*time.Hour)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected quoting another supplier's product to fail, got %d", w.Code)
+	}
+	w = doOrderRequest(buildRFQRouter(rival), http.MethodPost, fmt.Sprintf("/rfqs/%d/quotes", rfq.ID), quoteBody(rivalRice.ID, 1700, time.Now().Add(48*time.Hour)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("rival quote: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var rivalQuote models.Quote
+	json.Unmarshal(w.Body.Bytes(), &rivalQuote)
+	database.DB.Model(&rivalQuote).Update("expires_at", time.Now().Add(-time.Hour))
+
+	submission := `{"payment_method":"cash_on_delivery","delivery_option":"pickup"}`
+	w = doOrderRequest(buildRFQRouter(store), http.MethodPost, fmt.Sprintf("/rfqs/%d/quotes/%d/accept", rfq.ID, rivalQuote.ID), submission)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected expired quote to be refused, got %d: %s", w.Code, w.Body.String())
+	}
+
+	closedToday := models.ScheduleException{UserID: supplier.ID, Date: storedDeliveryDate(nowInPH()), IsClosed: true}
+	database.DB.Create(&closedToday)
+	w = doOrderRequest(buildRFQRouter(store), http.MethodPost, fmt.Sprintf("/rfqs/%d/quotes/%d/accept", rfq.ID, quote.ID), submission)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "is closed") {
+		t.Fatalf("expected a closed supplier to refuse the quote, got %d: %s", w.Code, w.Body.String())
+	}
+	database.DB.Delete(&closedToday)
+
+	w = doOrderRequest(buildRFQRouter(store), http.MethodPost, fmt.Sprintf("/rfqs/%d/quotes/%d/accept", rfq.ID, quote.ID), submission)
+	if w.Code != http.StatusOK {
+		t.Fatalf("accept: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var order models.Order
+	json.Unmarshal(w.Body.Bytes(), &order)
+	if order.Status != models.OrderStatusPreparing || order.QuoteID == nil || *order.QuoteID != quote.ID {
+		t.Fatalf("unexpected order: status %s, quote %v", order.Status, order.QuoteID)
+	}
+	if len(order.OrderItems) != 1 || order.OrderItems[0].UnitPrice != 1800 || order.TotalAmount != 18000 {
+		t.Fatalf("expected quoted prices on the order, got %+v (total %.2f)", order.OrderItems, order.TotalAmount)
+	}
+
+	var product models.Product
+	database.DB.First(&product, rice.ID)
+	if product.StockQuantity != 40 {
+		t.Fatalf("expected stock 40, got %d", product.StockQuantity)
+	}
+
+	database.DB.First(&rfq, rfq.ID)
+	database.DB.First(&rivalQuote, rivalQuote.ID)
+	if rfq.Status != models.RFQStatusAccepted || rfq.OrderID == nil || rivalQuote.Status != models.QuoteStatusRejected {
+		t.Fatalf("unexpected rfq %s / rival quote %s", rfq.Status, rivalQuote.Status)
+	}
+}
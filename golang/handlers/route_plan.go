@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxRouteStops keeps 2-opt, which is quadratic per pass, fast enough to run
+// inside a request.
+const maxRouteStops = 200
+
+type routeStop struct {
+	Sequence     int            `json:"sequence"`
+	StoreID      uint           `json:"store_id"`
+	StoreName    string         `json:"store_name"`
+	Address      string         `json:"address"`
+	Phone        string         `json:"phone"`
+	Latitude     float64        `json:"latitude"`
+	Longitude    float64        `json:"longitude"`
+	Orders       []models.Order `json:"orders"`
+	LegKm        float64        `json:"leg_km"`
+	CumulativeKm float64        `json:"cumulative_km"`
+}
+
+type routePlan struct {
+	StartLatitude    float64        `json:"start_latitude"`
+	StartLongitude   float64        `json:"start_longitude"`
+	ReturnToStart    bool           `json:"return_to_start"`
+	Stops            []routeStop    `json:"stops"`
+	TotalDistanceKm  float64        `json:"total_distance_km"`
+	EstimatedMinutes int            `json:"estimated_minutes"`
+	Unroutable       []models.Order `json:"unroutable"`
+}
+
+// GetRoutePlan orders the supplier's preparing deliveries into one run,
+// starting from the supplier's own location.
+func GetRoutePlan(c *gin.Context) {
+	plan, _, ok := loadRoutePlan(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// DownloadRunSheet prints the route plan as a run sheet for the driver.
+func DownloadRunSheet(c *gin.Context) {
+	plan, supplier, ok := loadRoutePlan(c)
+	if !ok {
+		return
+	}
+
+	pdf := newBrandedPDF("Run Sheet", nowInPH().Format("2006-01-02"))
+	pdf.SetFont("Arial", "", 11)
+	pdf.CellFormat(0, 7, fmt.Sprintf("Supplier: %s", supplier.Name), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 7, fmt.Sprintf("Stops: %d | Distance: %.2f km | Est. driving time: %d min", len(plan.Stops), plan.TotalDistanceKm, plan.EstimatedMinutes), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	pdf.SetFont("Arial", "B", 11)
+	pdf.CellFormat(10, 8, "#", "1", 0, "C", false, 0, "")
+	pdf.CellFormat(60, 8, "Store", "1", 0, "L", false, 0, "")
+	pdf.CellFormat(36, 8, "Orders", "1", 0, "L", false, 0, "")
+	pdf.CellFormat(30, 8, "Amount", "1", 0, "L", false, 0, "")
+	pdf.CellFormat(22, 8, "Leg km", "1", 0, "L", false, 0, "")
+	pdf.CellFormat(28, 8, "Signature", "1", 1, "L", false, 0, "")
+
+	pdf.SetFont("Arial", "", 10)
+	for _, stop := range plan.Stops {
+		ids := make([]string, 0, len(stop.Orders))
+		amount := 0.0
+		for _, order := range stop.Orders {
+			ids = append(ids, fmt.Sprintf("#%d", order.ID))
+			amount += order.TotalAmount
+		}
+		pdf.CellFormat(10, 8, strconv.Itoa(stop.Sequence), "1", 0, "C", false, 0, "")
+		pdf.CellFormat(60, 8, stop.StoreName, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(36, 8, strings.Join(ids, ", "), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(30, 8, fmt.Sprintf("PHP %.2f", amount), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(22, 8, fmt.Sprintf("%.2f", stop.LegKm), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(28, 8, "", "1", 1, "L", false, 0, "")
+		details := stop.Address
+		if stop.Phone != "" {
+			details = strings.TrimSpace(details + " | " + stop.Phone)
+		}
+		if details != "" {
+			pdf.SetFont("Arial", "I", 9)
+			pdf.CellFormat(186, 6, details, "LRB", 1, "L", false, 0, "")
+			pdf.SetFont("Arial", "", 10)
+		}
+	}
+
+	if len(plan.Unroutable) > 0 {
+		pdf.Ln(4)
+		pdf.SetFont("Arial", "B", 11)
+		pdf.CellFormat(0, 7, "Not routed (store location missing)", "", 1, "L", false, 0, "")
+		pdf.SetFont("Arial", "", 10)
+		for _, order := range plan.Unroutable {
+			pdf.CellFormat(0, 6, fmt.Sprintf("#%d %s %s", order.ID, order.Store.Name, order.ShippingAddress), "", 1, "L", false, 0, "")
+		}
+	}
+
+	writePDFFooter(pdf)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate run sheet"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=run-sheet-%s.pdf", nowInPH().Format("20060102")))
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+// loadRoutePlan reads the order selection from the query and plans the run.
+// order_ids picks specific orders, otherwise all preparing deliveries are
+// used, optionally only those scheduled for date.
+func loadRoutePlan(c *gin.Context) (routePlan, models.User, bool) {
+	userID, ok := requireSupplier(c)
+	if !ok {
+		return routePlan{}, models.User{}, false
+	}
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return routePlan{}, models.User{}, false
+	}
+
+	var supplier models.User
+	if err := database.DB.First(&supplier, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "supplier not found"})
+		return routePlan{}, models.User{}, false
+	}
+	if supplier.Latitude == nil || supplier.Longitude == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "set your location before planning a route"})
+		return routePlan{}, models.User{}, false
+	}
+
+	query := database.DB.Preload("Store").Preload("OrderItems").
+		Where("supplier_id = ? AND status = ? AND delivery_option = ?", userID, models.OrderStatusPreparing, models.DeliveryOptionDeliver)
+	if value := c.Query("order_ids"); value != "" {
+		var ids []uint
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "order_ids must be a comma separated list of ids"})
+				return routePlan{}, models.User{}, false
+			}
+			ids = append(ids, uint(id))
+		}
+		query = query.Where("id IN ?", ids)
+	}
+	if value := c.Query("date"); value != "" {
+		day, err := parseDeliveryDate(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date must be YYYY-MM-DD"})
+			return routePlan{}, models.User{}, false
+		}
+		query = query.Where("DATE(delivery_date) = ?", day.Format(dateLayout))
+	}
+
+	var orders []models.Order
+	if err := query.Order("id ASC").Find(&orders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch orders"})
+		return routePlan{}, models.User{}, false
+	}
+
+	plan := planRoute(*supplier.Latitude, *supplier.Longitude, orders, c.Query("return") == "true")
+	if len(plan.Stops) > maxRouteStops {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("a route can have at most %d stops", maxRouteStops)})
+		return routePlan{}, models.User{}, false
+	}
+	return plan, supplier, true
+}
+
+// planRoute groups orders into one stop per store and orders the stops with
+// a nearest-neighbour tour improved by 2-opt. Distances are straight lines,
+// so no map service is needed.
+func planRoute(startLat, startLng float64, orders []models.Order, returnToStart bool) routePlan {
+	plan := routePlan{
+		StartLatitude:  startLat,
+		StartLongitude: startLng,
+		ReturnToStart:  returnToStart,
+		Stops:          []routeStop{},
+		Unroutable:     []models.Order{},
+	}
+
+	stopIndex := make(map[uint]int)
+	for _, order := range orders {
+		if order.Store.Latitude == nil || order.Store.Longitude == nil {
+			plan.Unroutable = append(plan.Unroutable, order)
+			continue
+		}
+		i, ok := stopIndex[order.StoreID]
+		if !ok {
+			i = len(plan.Stops)
+			stopIndex[order.StoreID] = i
+			plan.Stops = append(plan.Stops, routeStop{
+				StoreID:   order.StoreID,
+				StoreName: order.Store.Name,
+				Address:   order.ShippingAddress,
+				Phone:     order.Store.Phone,
+				Latitude:  *order.Store.Latitude,
+				Longitude: *order.Store.Longitude,
+			})
+			if plan.Stops[i].Address == "" {
+				plan.Stops[i].Address = order.Store.Address
+			}
+		}
+		plan.Stops[i].Orders = append(plan.Stops[i].Orders, order)
+	}
+	if len(plan.Stops) == 0 || len(plan.Stops) > maxRouteStops {
+		return plan
+	}
+
+	// Point 0 is the start, point i+1 is plan.Stops[i].
+	points := make([][2]float64, 0, len(plan.Stops)+1)
+	points = append(points, [2]float64{startLat, startLng})
+	for _, stop := range plan.Stops {
+		points = append(points, [2]float64{stop.Latitude, stop.Longitude})
+	}
+	dist := make([][]float64, len(points))
+	for i := range points {
+		dist[i] = make([]float64, len(points))
+		for j := range points {
+			dist[i][j] = haversineKm(points[i][0], points[i][1], points[j][0], points[j][1])
+		}
+	}
+
+	tour := twoOpt(nearestNeighbourTour(dist), dist, returnToStart)
+
+	stops := make([]routeStop, 0, len(plan.Stops))
+	total := 0.0
+	for seq, point := range tour[1:] {
+		stop := plan.Stops[point-1]
+		leg := dist[tour[seq]][point]
+		total += leg
+		stop.Sequence = seq + 1
+		stop.LegKm = roundTo2(leg)
+		stop.CumulativeKm = roundTo2(total)
+		stops = append(stops, stop)
+	}
+	if returnToStart {
+		total += dist[tour[len(tour)-1]][0]
+	}
+	plan.Stops = stops
+	plan.TotalDistanceKm = roundTo2(total)
+	plan.EstimatedMinutes = int(math.Ceil(total / deliveryAverageSpeedKmh * 60))
+	return plan
+}
+
+// nearestNeighbourTour starts at point 0 and repeatedly visits the closest
+// unvisited point. Ties go to the lower index so plans are repeatable.
+func nearestNeighbourTour(dist [][]float64) []int {
+	n := len(dist)
+	visited := make([]bool, n)
+	tour := make([]int, 0, n)
+	tour = append(tour, 0)
+	visited[0] = true
+	for len(tour) < n {
+		last := tour[len(tour)-1]
+		next := -1
+		for j := 0; j < n; j++ {
+			if !visited[j] && (next == -1 || dist[last][j] < dist[last][next]) {
+				next = j
+			}
+		}
+		visited[next] = true
+		tour = append(tour, next)
+	}
+	return tour
+}
+
+// twoOpt reverses segments of the tour while doing so shortens it. The first
+// point stays fixed; when closed the tour also returns to it.
+func twoOpt(tour []int, dist [][]float64, closed bool) []int {
+	const epsilon = 1e-9
+	n := len(tour)
+	for improved := true; improved; {
+		improved = false
+		for i := 1; i < n-1; i++ {
+			for j := i + 1; j < n; j++ {
+				a, b, c := tour[i-1], tour[i], tour[j]
+				delta := dist[a][c] - dist[a][b]
+				if j+1 < n {
+					d := tour[j+1]
+					delta += dist[b][d] - dist[c][d]
+				} else if closed {
+					delta += dist[b][tour[0]] - dist[c][tour[0]]
+				}
+				if delta < -epsilon {
+					for l, r := i, j; l < r; l, r = l+1, r-1 {
+						tour[l], tour[r] = tour[r], tour[l]
+					}
+					improved = true
+				}
+			}
+		}
+	}
+	return tour
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupRoutePlanTestDB(t *testing.T) (models.User, models.User) {
+	t.Helper()
+	store, supplier := setupOrderTestDB(t)
+	migrateTestModels(t, &models.DeliverySlot{})
+	return store, supplier
+}
+
+func buildRoutePlanRouter(user models.User) *gin.Engine {
+	r := buildOrderRouter(user)
+	r.GET("/me/route-plan", GetRoutePlan)
+	r.GET("/me/route-plan/run-sheet", DownloadRunSheet)
+	return r
+}
+
+func TestTwoOptUntanglesCrossingTour(t *testing.T) {
+	points := [][2]float64{{0, 0}, {1, 1}, {1, 0}, {0, 1}}
+	dist := make([][]float64, len(points))
+	for i := range points {
+		dist[i] = make([]float64, len(points))
+		for j := range points {
+			dist[i][j] = math.Hypot(points[i][0]-points[j][0], points[i][1]-points[j][1])
+		}
+	}
+
+	tour := twoOpt([]int{0, 1, 2, 3}, dist, true)
+	length := dist[tour[len(tour)-1]][tour[0]]
+	for i := 1; i < len(tour); i++ {
+		length += dist[tour[i-1]][tour[i]]
+	}
+	if tour[0] != 0 || math.Abs(length-4) > 1e-9 {
+		t.Fatalf("expected the square's perimeter from point 0, got %v (%.3f)", tour, length)
+	}
+}
+
+func TestRoutePlanOrdersStopsAndPrintsRunSheet(t *testing.T) {
+	_, supplier := setupRoutePlanTestDB(t)
+	database.DB.Model(&supplier).Updates(map[string]interface{}{"latitude": 9.60, "longitude": 126.0})
+
+	lng := 126.0
+	var stores []models.User
+	for i, lat := range []float64{9.70, 9.90, 9.80} {
+		store := models.User{Email: fmt.Sprintf("store%d@example.com", i), Password: "x", Name: fmt.Sprintf("Store %d", i), Role: models.RoleStore, Latitude: &lat, Longitude: &lng}
+		if err := database.DB.Create(&store).Error; err != nil {
+			t.Fatalf("create store: %v", err)
+		}
+		stores = append(stores, store)
+	}
+	noLocation := models.User{Email: "nowhere@stores.example.com", Password: "x", Name: "Nowhere", Role: models.RoleStore}
+	database.DB.Create(&noLocation)
+
+	for _, store := range append(stores, stores[0], noLocation) {
+		order := models.Order{StoreID: store.ID, SupplierID: supplier.ID, Status: models.OrderStatusPreparing, DeliveryOption: models.DeliveryOptionDeliver}
+		database.DB.Create(&order)
+	}
+
+	router := buildRoutePlanRouter(supplier)
+	w := doOrderRequest(router, http.MethodGet, "/me/route-plan", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var plan routePlan
+	json.Unmarshal(w.Body.Bytes(), &plan)
+	if len(plan.Stops) != 3 || len(plan.Unroutable) != 1 {
+		t.Fatalf("expected 3 stops and 1 unroutable order, got %s", w.Body.String())
+	}
+	want := []uint{stores[0].ID, stores[2].ID, stores[1].ID}
+	for i, stop := range plan.Stops {
+		if stop.StoreID != want[i] || stop.Sequence != i+1 {
+			t.Fatalf("unexpected stop %d: %+v", i, stop)
+		}
+	}
+	if len(plan.Stops[0].Orders) != 2 {
+		t.Fatalf("expected both orders for the first store on one stop, got %d", len(plan.Stops[0].Orders))
+	}
+	// 0.3 degrees of latitude is about 33.4 km.
+	if plan.TotalDistanceKm < 33.3 || plan.TotalDistanceKm > 33.4 {
+		t.Fatalf("unexpected total distance %.2f", plan.TotalDistanceKm)
+	}
+
+	w = doOrderRequest(router, http.MethodGet, "/me/route-plan/run-sheet", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/pdf" || !bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF")) {
+		t.Fatalf("expected a PDF run sheet, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
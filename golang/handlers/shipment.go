@@ -0,0 +1,498 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type shipmentRequest struct {
+	CarrierName   string `json:"carrier_name" binding:"required"`
+	VesselName    string `json:"vessel_name"`
+	DepartureDate string `json:"departure_date"`
+	ArrivalDate   string `json:"arrival_date"`
+	Notes         string `json:"notes"`
+	OrderIDs      []uint `json:"order_ids"`
+}
+
+// shipmentDetail adds the manifest totals to a shipment.
+type shipmentDetail struct {
+	models.Shipment
+	OrderCount    int     `json:"order_count"`
+	ItemCount     int     `json:"item_count"`
+	TotalWeightKg float64 `json:"total_weight_kg"`
+}
+
+// manifestLine is one item as it is loaded on the carrier.
+type manifestLine struct {
+	Name     string
+	Unit     string
+	Quantity int
+	WeightKg float64
+}
+
+var shipmentTransitions = map[models.ShipmentStatus][]models.ShipmentStatus{
+	models.ShipmentStatusPlanned:   {models.ShipmentStatusInTransit, models.ShipmentStatusCancelled},
+	models.ShipmentStatusInTransit: {models.ShipmentStatusArrived},
+}
+
+func CreateShipment(c *gin.Context) {
+	userID, ok := requireSupplier(c)
+	if !ok {
+		return
+	}
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+
+	var req shipmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	shipment := models.Shipment{SupplierID: userID, Status: models.ShipmentStatusPlanned}
+	if err := applyShipmentRequest(&shipment, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&shipment).Error; err != nil {
+			return err
+		}
+		shipment.ManifestNumber = fmt.Sprintf("MF-%06d", shipment.ID)
+		if err := tx.Model(&shipment).Update("manifest_number", shipment.ManifestNumber).Error; err != nil {
+			return err
+		}
+		return attachShipmentOrders(tx, shipment, req.OrderIDs)
+	})
+	if err != nil {
+		writeOrderError(c, err, "failed to create shipment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, loadShipmentDetail(shipment.ID))
+}
+
+func GetShipments(c *gin.Context) {
+	userID, ok := requireSupplier(c)
+	if !ok {
+		return
+	}
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+
+	query := database.DB.Where("supplier_id = ?", userID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var shipments []models.Shipment
+	if err := query.Order("created_at DESC").Find(&shipments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shipments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, shipments)
+}
+
+func GetShipment(c *gin.Context) {
+	shipment, ok := findShipment(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, loadShipmentDetail(shipment.ID))
+}
+
+// UpdateShipment replaces the carrier details and order list of a shipment
+// that has not left yet.
+func UpdateShipment(c *gin.Context) {
+	shipment, ok := findShipment(c)
+	if !ok {
+		return
+	}
+	if shipment.Status != models.ShipmentStatusPlanned {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "only planned shipments can be changed"})
+		return
+	}
+
+	var req shipmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applyShipmentRequest(&shipment, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Re-check under lock so a shipment departing meanwhile is not edited.
+		var locked models.Shipment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, shipment.ID).Error; err != nil {
+			return err
+		}
+		if locked.Status != models.ShipmentStatusPlanned {
+			return &orderError{Code: http.StatusBadRequest, Message: "only planned shipments can be changed"}
+		}
+		if err := tx.Model(&locked).Updates(map[string]interface{}{
+			"carrier_name":   shipment.CarrierName,
+			"vessel_name":    shipment.VesselName,
+			"departure_date": shipment.DepartureDate,
+			"arrival_date":   shipment.ArrivalDate,
+			"notes":          shipment.Notes,
+		}).Error; err != nil {
+			return err
+		}
+		return attachShipmentOrders(tx, locked, req.OrderIDs)
+	})
+	if err != nil {
+		writeOrderError(c, err, "failed to update shipment")
+		return
+	}
+
+	c.JSON(http.StatusOK, loadShipmentDetail(shipment.ID))
+}
+
+// UpdateShipmentStatus moves a shipment along. Departing puts every
+// preparing order on it in transit in the same transaction; cancelling a
+// planned shipment releases its orders.
+func UpdateShipmentStatus(c *gin.Context) {
+	shipment, ok := findShipment(c)
+	if !ok {
+		return
+	}
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanChangeStatus, "change_status") {
+		return
+	}
+
+	var req struct {
+		Status string `json:"status" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	to := models.ShipmentStatus(req.Status)
+
+	actor := getOrderActor(c)
+	type shippedOrder struct {
+		orderID    uint
+		transition models.OrderTransition
+	}
+	var shipped []shippedOrder
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&shipment, shipment.ID).Error; err != nil {
+			return err
+		}
+		allowed := false
+		for _, next := range shipmentTransitions[shipment.Status] {
+			if next == to {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("cannot change shipment from %s to %s", shipment.Status, to)}
+		}
+
+		now := time.Now()
+		updates := map[string]interface{}{"status": to}
+		switch to {
+		case models.ShipmentStatusInTransit:
+			var orders []models.Order
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("shipment_id = ?", shipment.ID).Order("id ASC").Find(&orders).Error; err != nil {
+				return err
+			}
+			reason := fmt.Sprintf("Shipped on manifest %s", shipment.ManifestNumber)
+			for i := range orders {
+				switch orders[i].Status {
+				case models.OrderStatusPreparing:
+					transition, err := transitionOrder(tx, &orders[i], models.OrderStatusInTransit, actor, reason, false)
+					if err != nil {
+						return err
+					}
+					shipped = append(shipped, shippedOrder{orderID: orders[i].ID, transition: transition})
+				case models.OrderStatusCancelled:
+					if err := tx.Model(&orders[i]).Update("shipment_id", nil).Error; err != nil {
+						return err
+					}
+				}
+			}
+			if len(shipped) == 0 {
+				return &orderError{Code: http.StatusBadRequest, Message: "shipment has no preparing orders to ship"}
+			}
+			updates["departed_at"] = now
+		case models.ShipmentStatusArrived:
+			updates["arrived_at"] = now
+		case models.ShipmentStatusCancelled:
+			if err := tx.Model(&models.Order{}).Where("shipment_id = ?", shipment.ID).Update("shipment_id", nil).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&shipment).Updates(updates).Error
+	})
+	if err != nil {
+		writeOrderError(c, err, "failed to update shipment status")
+		return
+	}
+
+	emailService := getEmailService(c)
+	for _, s := range shipped {
+		var order models.Order
+		if err := database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product").First(&order, s.orderID).Error; err == nil {
+			runOrderNotifications(emailService, order, models.OrderStatusPreparing, s.transition)
+		}
+	}
+
+	c.JSON(http.StatusOK, loadShipmentDetail(shipment.ID))
+}
+
+// DownloadShipmentManifest prints the manifest handed to the carrier: every
+// order with its store and the count and weight of each item.
+func DownloadShipmentManifest(c *gin.Context) {
+	shipment, ok := findShipment(c)
+	if !ok {
+		return
+	}
+	detail := loadShipmentDetail(shipment.ID)
+	shipment = detail.Shipment
+
+	pdf := newBrandedPDF("Manifest", shipment.ManifestNumber)
+	pdf.SetFont("Arial", "", 11)
+	pdf.CellFormat(95, 7, fmt.Sprintf("Shipper: %s", shipment.Supplier.Name), "", 0, "L", false, 0, "")
+	pdf.CellFormat(95, 7, fmt.Sprintf("Status: %s", shipment.Status), "", 1, "R", false, 0, "")
+	carrier := shipment.CarrierName
+	if shipment.VesselName != "" {
+		carrier = fmt.Sprintf("%s / %s", carrier, shipment.VesselName)
+	}
+	pdf.CellFormat(95, 7, fmt.Sprintf("Carrier: %s", carrier), "", 0, "L", false, 0, "")
+	pdf.CellFormat(95, 7, fmt.Sprintf("Departs: %s  Arrives: %s", formatShipmentDate(shipment.DepartureDate), formatShipmentDate(shipment.ArrivalDate)), "", 1, "R", false, 0, "")
+	pdf.Ln(4)
+
+	for _, order := range shipment.Orders {
+		if order.Status == models.OrderStatusCancelled {
+			continue
+		}
+		address := order.ShippingAddress
+		if address == "" {
+			address = order.Store.Address
+		}
+		pdf.SetFont("Arial", "B", 11)
+		pdf.CellFormat(0, 8, fmt.Sprintf("Order #%d - %s", order.ID, order.Store.Name), "", 1, "L", false, 0, "")
+		if address != "" {
+			pdf.SetFont("Arial", "I", 9)
+			pdf.CellFormat(0, 5, address, "", 1, "L", false, 0, "")
+		}
+
+		pdf.SetFont("Arial", "B", 10)
+		pdf.CellFormat(90, 7, "Item", "1", 0, "L", false, 0, "")
+		pdf.CellFormat(20, 7, "Qty", "1", 0, "L", false, 0, "")
+		pdf.CellFormat(26, 7, "Unit", "1", 0, "L", false, 0, "")
+		pdf.CellFormat(50, 7, "Weight (kg)", "1", 1, "L", false, 0, "")
+
+		pdf.SetFont("Arial", "", 10)
+		count, weight := 0, 0.0
+		for _, item := range order.OrderItems {
+			line := newManifestLine(item)
+			if line.Quantity == 0 {
+				continue
+			}
+			pdf.CellFormat(90, 7, line.Name, "1", 0, "L", false, 0, "")
+			pdf.CellFormat(20, 7, fmt.Sprintf("%d", line.Quantity), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(26, 7, line.Unit, "1", 0, "L", false, 0, "")
+			pdf.CellFormat(50, 7, fmt.Sprintf("%.2f", line.WeightKg), "1", 1, "L", false, 0, "")
+			count += line.Quantity
+			weight += line.WeightKg
+		}
+		pdf.SetFont("Arial", "B", 10)
+		pdf.CellFormat(90, 7, "Order total", "1", 0, "R", false, 0, "")
+		pdf.CellFormat(46, 7, fmt.Sprintf("%d", count), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(50, 7, fmt.Sprintf("%.2f", weight), "1", 1, "L", false, 0, "")
+		pdf.Ln(3)
+	}
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.CellFormat(0, 8, fmt.Sprintf("Orders: %d | Items: %d | Total weight: %.2f kg", detail.OrderCount, detail.ItemCount, detail.TotalWeightKg), "", 1, "L", false, 0, "")
+
+	writePDFFooter(pdf)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate manifest"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%s.pdf", shipment.ManifestNumber))
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+// findShipment loads one of the signed-in supplier's shipments.
+func findShipment(c *gin.Context) (models.Shipment, bool) {
+	userID, ok := requireSupplier(c)
+	if !ok {
+		return models.Shipment{}, false
+	}
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return models.Shipment{}, false
+	}
+
+	var shipment models.Shipment
+	if err := database.DB.Where("id = ? AND supplier_id = ?", c.Param("id"), userID).First(&shipment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "shipment not found"})
+		return models.Shipment{}, false
+	}
+	return shipment, true
+}
+
+func loadShipmentDetail(id uint) shipmentDetail {
+	var shipment models.Shipment
+	database.DB.Preload("Supplier").Preload("Orders", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).Preload("Orders.Store").Preload("Orders.OrderItems").Preload("Orders.OrderItems.Product").Preload("Orders.OrderItems.SubstituteProduct").First(&shipment, id)
+
+	detail := shipmentDetail{Shipment: shipment}
+	for _, order := range shipment.Orders {
+		if order.Status == models.OrderStatusCancelled {
+			continue
+		}
+		detail.OrderCount++
+		for _, item := range order.OrderItems {
+			line := newManifestLine(item)
+			detail.ItemCount += line.Quantity
+			detail.TotalWeightKg += line.WeightKg
+		}
+	}
+	detail.TotalWeightKg = roundTo2(detail.TotalWeightKg)
+	return detail
+}
+
+// newManifestLine describes what is actually loaded for an order line, so a
+// substitute is listed under its own name and weight.
+func newManifestLine(item models.OrderItem) manifestLine {
+	product := item.Product
+	if item.FulfilmentStatus == models.ItemFulfilmentSubstituted && item.SubstituteProduct != nil {
+		product = *item.SubstituteProduct
+	}
+	name := product.Name
+	if name == "" {
+		name = fmt.Sprintf("Product %d", item.ShippedProductID())
+	}
+	unit := product.Unit
+	if unit == "" {
+		unit = "-"
+	}
+	quantity := item.BilledQuantity()
+	return manifestLine{
+		Name:     name,
+		Unit:     unit,
+		Quantity: quantity,
+		WeightKg: float64(quantity) * product.WeightKg,
+	}
+}
+
+// applyShipmentRequest copies the carrier details from a request.
+func applyShipmentRequest(shipment *models.Shipment, req shipmentRequest) error {
+	carrier := strings.TrimSpace(req.CarrierName)
+	if carrier == "" {
+		return errors.New("carrier_name is required")
+	}
+
+	var departure, arrival *time.Time
+	if req.DepartureDate != "" {
+		day, err := parseDeliveryDate(req.DepartureDate)
+		if err != nil {
+			return errors.New("departure_date must be YYYY-MM-DD")
+		}
+		stored := storedDeliveryDate(day)
+		departure = &stored
+	}
+	if req.ArrivalDate != "" {
+		day, err := parseDeliveryDate(req.ArrivalDate)
+		if err != nil {
+			return errors.New("arrival_date must be YYYY-MM-DD")
+		}
+		stored := storedDeliveryDate(day)
+		arrival = &stored
+	}
+	if departure != nil && arrival != nil && arrival.Before(*departure) {
+		return errors.New("arrival_date cannot be before departure_date")
+	}
+
+	shipment.CarrierName = carrier
+	shipment.VesselName = strings.TrimSpace(req.VesselName)
+	shipment.DepartureDate = departure
+	shipment.ArrivalDate = arrival
+	shipment.Notes = req.Notes
+	return nil
+}
+
+// attachShipmentOrders makes orderIDs the orders of a planned shipment. Each
+// must be one of the supplier's preparing orders and not on another
+// shipment; orders left out are released.
+func attachShipmentOrders(tx *gorm.DB, shipment models.Shipment, orderIDs []uint) error {
+	seen := make(map[uint]bool, len(orderIDs))
+	ids := make([]uint, 0, len(orderIDs))
+	for _, id := range orderIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	release := tx.Model(&models.Order{}).Where("shipment_id = ?", shipment.ID)
+	if len(ids) > 0 {
+		release = release.Where("id NOT IN ?", ids)
+	}
+	if err := release.Update("shipment_id", nil).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var orders []models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ? AND supplier_id = ?", ids, shipment.SupplierID).Find(&orders).Error; err != nil {
+		return err
+	}
+	found := make(map[uint]models.Order, len(orders))
+	for _, order := range orders {
+		found[order.ID] = order
+	}
+	for _, id := range ids {
+		order, ok := found[id]
+		if !ok {
+			return &orderError{Code: http.StatusNotFound, Message: fmt.Sprintf("order %d not found", id)}
+		}
+		if order.Status != models.OrderStatusPreparing {
+			return &orderError{Code: http.StatusBadRequest, Message: fmt.Sprintf("order %d is %s, only preparing orders can be shipped", id, order.Status)}
+		}
+		if order.ShipmentID != nil && *order.ShipmentID != shipment.ID {
+			return &orderError{Code: http.StatusConflict, Message: fmt.Sprintf("order %d is already on another shipment", id)}
+		}
+	}
+
+	return tx.Model(&models.Order{}).Where("id IN ?", ids).Update("shipment_id", shipment.ID).Error
+}
+
+func formatShipmentDate(day *time.Time) string {
+	if day == nil {
+		return "-"
+	}
+	return day.Format(dateLayout)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupShipmentTestDB(t *testing.T) (models.User, models.User) {
+	t.Helper()
+	store, supplier := setupOrderTestDB(t)
+	migrateTestModels(t, &models.Shipment{})
+	return store, supplier
+}
+
+func buildShipmentRouter(user models.User) *gin.Engine {
+	r := buildOrderRouter(user)
+	r.POST("/shipments", CreateShipment)
+	r.PUT("/shipments/:id", UpdateShipment)
+	r.PUT("/shipments/:id/status", UpdateShipmentStatus)
+	r.GET("/shipments/:id/manifest", DownloadShipmentManifest)
+	return r
+}
+
+func TestShipmentDepartureMovesOrdersInTransit(t *testing.T) {
+	store, supplier := setupShipmentTestDB(t)
+	rice := models.Product{SupplierID: supplier.ID, Name: "Rice", SKU: "RICE-1", Price: 50, StockQuantity: 50, WeightKg: 25}
+	database.DB.Create(&rice)
+
+	first := createTestOrder(t, store, supplier, models.OrderStatusPreparing)
+	second := createTestOrder(t, store, supplier, models.OrderStatusPreparing)
+	delivered := createTestOrder(t, store, supplier, models.OrderStatusDelivered)
+	for _, order := range []models.Order{first, second} {
+		database.DB.Create(&models.OrderItem{OrderID: order.ID, ProductID: rice.ID, Quantity: 2, UnitPrice: 50, Subtotal: 100})
+	}
+	router := buildShipmentRouter(supplier)
+
+	body := fmt.Sprintf(`{"carrier_name":"Montenegro Lines","vessel_name":"MV Maria","departure_date":"2026-03-02","arrival_date":"2026-03-01","order_ids":[%d]}`, first.ID)
+	if w := doOrderRequest(router, http.MethodPost, "/shipments", body); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected arrival before departure to be refused, got %d", w.Code)
+	}
+	body = fmt.Sprintf(`{"carrier_name":"Montenegro Lines","order_ids":[%d,%d]}`, first.ID, delivered.ID)
+	if w := doOrderRequest(router, http.MethodPost, "/shipments", body); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected delivered order to be refused, got %d", w.Code)
+	}
+
+	body = fmt.Sprintf(`{"carrier_name":"Montenegro Lines","vessel_name":"MV Maria","departure_date":"2026-03-02","arrival_date":"2026-03-03","order_ids":[%d,%d]}`, first.ID, second.ID)
+	w := doOrderRequest(router, http.MethodPost, "/shipments", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created shipmentDetail
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if created.ManifestNumber != fmt.Sprintf("MF-%06d", created.ID) || created.OrderCount != 2 || created.ItemCount != 4 || created.TotalWeightKg != 100 {
+		t.Fatalf("unexpected shipment: %s", w.Body.String())
+	}
+
+	body = fmt.Sprintf(`{"carrier_name":"2GO","order_ids":[%d]}`, first.ID)
+	if w := doOrderRequest(router, http.MethodPost, "/shipments", body); w.Code != http.StatusConflict {
+		t.Fatalf("expected order on another shipment to be refused, got %d", w.Code)
+	}
+
+	body = fmt.Sprintf(`{"carrier_name":"Montenegro Lines","vessel_name":"MV Reina","order_ids":[%d,%d]}`, first.ID, second.ID)
+	w = doOrderRequest(router, http.MethodPut, fmt.Sprintf("/shipments/%d", created.ID), body)
+	var updated shipmentDetail
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	if w.Code != http.StatusOK || updated.VesselName != "MV Reina" || updated.DepartureDate != nil || updated.ManifestNumber != created.ManifestNumber || updated.Status != models.ShipmentStatusPlanned {
+		t.Fatalf("unexpected updated shipment: %d %s", w.Code, w.Body.String())
+	}
+
+	statusPath := fmt.Sprintf("/shipments/%d/status", created.ID)
+	if w := doOrderRequest(router, http.MethodPut, statusPath, `{"status":"arrived"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected planned shipment to not arrive, got %d", w.Code)
+	}
+	if w := doOrderRequest(router, http.MethodPut, statusPath, `{"status":"in_transit"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	for _, id := range []uint{first.ID, second.ID} {
+		var order models.Order
+		database.DB.First(&order, id)
+		if order.Status != models.OrderStatusInTransit {
+			t.Fatalf("expected order %d in transit, got %s", id, order.Status)
+		}
+		var history models.OrderStatusHistory
+		database.DB.Where("order_id = ? AND to_status = ?", id, models.OrderStatusInTransit).First(&history)
+		if history.ID == 0 {
+			t.Fatalf("expected status history for order %d", id)
+		}
+	}
+
+	if w := doOrderRequest(router, http.MethodPut, fmt.Sprintf("/shipments/%d", created.ID), `{"carrier_name":"2GO"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected departed shipment to be locked, got %d", w.Code)
+	}
+
+	w = doOrderRequest(router, http.MethodGet, fmt.Sprintf("/shipments/%d/manifest", created.ID), "")
+	if w.Code != http.StatusOK || !bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF")) {
+		t.Fatalf("expected a PDF manifest, got %d", w.Code)
+	}
+}
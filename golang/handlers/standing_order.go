@@ -0,0 +1,499 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"siargao-trading-road/config"
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+	"siargao-trading-road/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Standing orders run at this hour of the day, Philippine time, before
+// suppliers open.
+const standingOrderRunHour = 6
+
+// A deferred run looks at most this many days ahead for an open day.
+const maxStandingOrderDeferDays = 14
+
+type standingOrderRequest struct {
+	SupplierID      uint   `json:"supplier_id"`
+	Name            string `json:"name"`
+	Recurrence      string `json:"recurrence" binding:"required"`
+	Weekdays        string `json:"weekdays"`
+	IntervalDays    int    `json:"interval_days"`
+	ClosedDayPolicy string `json:"closed_day_policy"`
+	StartDate       string `json:"start_date"`
+	PaymentMethod   string `json:"payment_method" binding:"required"`
+	DeliveryOption  string `json:"delivery_option" binding:"required"`
+	ShippingAddress string `json:"shipping_address"`
+	Notes           string `json:"notes"`
+	Active          *bool  `json:"active"`
+	Items           []struct {
+		ProductID uint `json:"product_id"`
+		Quantity  int  `json:"quantity"`
+	} `json:"items" binding:"required"`
+}
+
+func GetStandingOrders(c *gin.Context) {
+	userID, ok := requireStandingOrderStore(c)
+	if !ok {
+		return
+	}
+
+	var standingOrders []models.StandingOrder
+	if err := database.DB.Preload("Supplier").Preload("Items").Preload("Items.Product").
+		Where("store_id = ?", userID).Order("next_run_at ASC").Find(&standingOrders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch standing orders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, standingOrders)
+}
+
+func GetStandingOrder(c *gin.Context) {
+	userID, ok := requireStandingOrderStore(c)
+	if !ok {
+		return
+	}
+
+	var standingOrder models.StandingOrder
+	if err := database.DB.Preload("Supplier").Preload("Items").Preload("Items.Product").
+		Where("id = ? AND store_id = ?", c.Param("id"), userID).First(&standingOrder).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "standing order not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, standingOrder)
+}
+
+func CreateStandingOrder(c *gin.Context) {
+	userID, ok := requireStandingOrderStore(c)
+	if !ok {
+		return
+	}
+
+	var req standingOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.SupplierID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "supplier_id is required"})
+		return
+	}
+
+	standingOrder := models.StandingOrder{StoreID: userID, SupplierID: req.SupplierID, Active: true}
+	if err := applyStandingOrderRequest(&standingOrder, req, time.Now()); err != nil {
+		writeOrderError(c, err, "failed to create standing order")
+		return
+	}
+
+	if err := database.DB.Omit("Store", "Supplier").Create(&standingOrder).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create standing order", "details": err.Error()})
+		return
+	}
+
+	database.DB.Preload("Supplier").Preload("Items").Preload("Items.Product").First(&standingOrder, standingOrder.ID)
+	c.JSON(http.StatusCreated, standingOrder)
+}
+
+func UpdateStandingOrder(c *gin.Context) {
+	userID, ok := requireStandingOrderStore(c)
+	if !ok {
+		return
+	}
+
+	var standingOrder models.StandingOrder
+	if err := database.DB.Where("id = ? AND store_id = ?", c.Param("id"), userID).First(&standingOrder).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "standing order not found"})
+		return
+	}
+
+	var req standingOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := applyStandingOrderRequest(&standingOrder, req, time.Now()); err != nil {
+		writeOrderError(c, err, "failed to update standing order")
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("standing_order_id = ?", standingOrder.ID).Delete(&models.StandingOrderItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Store", "Supplier").Save(&standingOrder).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update standing order", "details": err.Error()})
+		return
+	}
+
+	database.DB.Preload("Supplier").Preload("Items").Preload("Items.Product").First(&standingOrder, standingOrder.ID)
+	c.JSON(http.StatusOK, standingOrder)
+}
+
+func DeleteStandingOrder(c *gin.Context) {
+	userID, ok := requireStandingOrderStore(c)
+	if !ok {
+		return
+	}
+
+	result := database.DB.Where("id = ? AND store_id = ?", c.Param("id"), userID).Delete(&models.StandingOrder{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete standing order"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "standing order not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "standing order deleted"})
+}
+
+func requireStandingOrderStore(c *gin.Context) (uint, bool) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return 0, false
+	}
+
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return 0, false
+	}
+
+	role, _ := c.Get("role")
+	if role != "store" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only stores can manage standing orders"})
+		return 0, false
+	}
+	return userID, true
+}
+
+// applyStandingOrderRequest validates req and copies it onto standingOrder,
+// replacing its items and rescheduling the next run.
+func applyStandingOrderRequest(standingOrder *models.StandingOrder, req standingOrderRequest, now time.Time) error {
+	badRequest := func(message string) error {
+		return &orderError{Code: http.StatusBadRequest, Message: message}
+	}
+
+	var supplier models.User
+	if err := database.DB.Where("id = ? AND role = ?", standingOrder.SupplierID, "supplier").First(&supplier).Error; err != nil {
+		return &orderError{Code: http.StatusNotFound, Message: "supplier not found"}
+	}
+
+	recurrence := models.StandingOrderRecurrence(req.Recurrence)
+	switch recurrence {
+	case models.StandingOrderWeekly:
+		days, err := parseWeekdays(req.Weekdays)
+		if err != nil || len(days) == 0 {
+			return badRequest("weekdays must list at least one day, 0=Sunday to 6=Saturday")
+		}
+	case models.StandingOrderInterval:
+		if req.IntervalDays < 1 {
+			return badRequest("interval_days must be at least 1")
+		}
+	default:
+		return badRequest("recurrence must be weekly or interval")
+	}
+
+	policy := models.ClosedDayPolicy(req.ClosedDayPolicy)
+	if policy == "" {
+		policy = models.ClosedDaySkip
+	}
+	if policy != models.ClosedDaySkip && policy != models.ClosedDayDefer {
+		return badRequest("closed_day_policy must be skip or defer")
+	}
+
+	switch models.PaymentMethod(req.PaymentMethod) {
+	case models.PaymentMethodCashOnDelivery, models.PaymentMethodGCash, models.PaymentMethodCredit:
+	default:
+		if _, ok := paymentProviders.Get(req.PaymentMethod); !ok {
+			return badRequest("invalid payment method")
+		}
+	}
+	if req.DeliveryOption != string(models.DeliveryOptionPickup) && req.DeliveryOption != string(models.DeliveryOptionDeliver) {
+		return badRequest("invalid delivery option")
+	}
+
+	if len(req.Items) == 0 {
+		return badRequest("a standing order needs at least one item")
+	}
+	items := make([]models.StandingOrderItem, 0, len(req.Items))
+	seen := make(map[uint]bool, len(req.Items))
+	for _, item := range req.Items {
+		if item.Quantity < 1 {
+			return badRequest("item quantity must be at least 1")
+		}
+		if seen[item.ProductID] {
+			return badRequest("each product may only appear once")
+		}
+		seen[item.ProductID] = true
+
+		var count int64
+		database.DB.Model(&models.Product{}).Where("id = ? AND supplier_id = ?", item.ProductID, supplier.ID).Count(&count)
+		if count == 0 {
+			return &orderError{Code: http.StatusNotFound, Message: fmt.Sprintf("product %d not found", item.ProductID)}
+		}
+		items = append(items, models.StandingOrderItem{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+
+	start := now
+	if req.StartDate != "" {
+		date, err := time.ParseInLocation("2006-01-02", req.StartDate, philippineTZ)
+		if err != nil {
+			return badRequest("invalid start_date format. Use YYYY-MM-DD")
+		}
+		start = date
+	}
+
+	standingOrder.Name = req.Name
+	standingOrder.Recurrence = recurrence
+	standingOrder.Weekdays = strings.TrimSpace(req.Weekdays)
+	standingOrder.IntervalDays = req.IntervalDays
+	standingOrder.ClosedDayPolicy = policy
+	standingOrder.PaymentMethod = models.PaymentMethod(req.PaymentMethod)
+	standingOrder.DeliveryOption = models.DeliveryOption(req.DeliveryOption)
+	standingOrder.ShippingAddress = req.ShippingAddress
+	standingOrder.Notes = req.Notes
+	if req.Active != nil {
+		standingOrder.Active = *req.Active
+	}
+	standingOrder.Items = items
+	standingOrder.NextRunAt = firstStandingOrderRun(*standingOrder, start, now)
+	return nil
+}
+
+// parseWeekdays reads a comma-separated weekday list (0=Sunday) in the same
+// format as User.ClosedDaysOfWeek.
+func parseWeekdays(value string) (map[time.Weekday]bool, error) {
+	days := make(map[time.Weekday]bool)
+	for _, part := range strings.Split(value, ",") {
+		p := strings.TrimSpace(part)
+		if p == "" {
+			continue
+		}
+		day, err := strconv.Atoi(p)
+		if err != nil || day < 0 || day > 6 {
+			return nil, fmt.Errorf("invalid weekday %q", p)
+		}
+		days[time.Weekday(day)] = true
+	}
+	return days, nil
+}
+
+func standingOrderRunTime(day time.Time) time.Time {
+	day = day.In(philippineTZ)
+	return time.Date(day.Year(), day.Month(), day.Day(), standingOrderRunHour, 0, 0, 0, philippineTZ)
+}
+
+// nextStandingOrderRun returns the scheduled run after from. Interval
+// schedules count from the previous run, including a deferred one.
+func nextStandingOrderRun(standingOrder models.StandingOrder, from time.Time) time.Time {
+	from = standingOrderRunTime(from)
+	if standingOrder.Recurrence == models.StandingOrderInterval {
+		return from.AddDate(0, 0, standingOrder.IntervalDays)
+	}
+
+	days, _ := parseWeekdays(standingOrder.Weekdays)
+	for i := 1; i <= 7; i++ {
+		next := from.AddDate(0, 0, i)
+		if days[next.Weekday()] {
+			return next
+		}
+	}
+	return from.AddDate(0, 0, 7)
+}
+
+// firstStandingOrderRun returns the first scheduled run on or after start
+// that has not already passed.
+func firstStandingOrderRun(standingOrder models.StandingOrder, start, now time.Time) time.Time {
+	run := standingOrderRunTime(start)
+	if standingOrder.Recurrence == models.StandingOrderWeekly {
+		if days, _ := parseWeekdays(standingOrder.Weekdays); !days[run.Weekday()] {
+			run = nextStandingOrderRun(standingOrder, run)
+		}
+	}
+	for run.Before(now) {
+		run = nextStandingOrderRun(standingOrder, run)
+	}
+	return run
+}
+
+// isSupplierClosedOn reports whether the supplier is closed for the whole
+// day, by weekly closed day or a closed schedule exception.
+func isSupplierClosedOn(tx *gorm.DB, supplier models.User, day time.Time) bool {
+	day = day.In(philippineTZ)
+	if isClosedToday(supplier.ClosedDaysOfWeek, day) {
+		return true
+	}
+	var count int64
+	tx.Model(&models.ScheduleException{}).
+		Where("user_id = ? AND DATE(date) = ? AND is_closed = ?", supplier.ID, day.Format("2006-01-02"), true).
+		Count(&count)
+	return count > 0
+}
+
+// runStandingOrders is the scheduler job: it places every standing order
+// whose next run is due.
+func runStandingOrders(now time.Time, emailService *services.EmailService) {
+	var due []models.StandingOrder
+	if err := database.DB.Preload("Store").Preload("Supplier").Preload("Items").
+		Where("active = ? AND next_run_at <= ?", true, now).
+		Find(&due).Error; err != nil {
+		log.Printf("runStandingOrders: failed to load due standing orders: %v", err)
+		return
+	}
+
+	for _, standingOrder := range due {
+		runStandingOrder(standingOrder, now, emailService)
+	}
+}
+
+func runStandingOrder(standingOrder models.StandingOrder, now time.Time, emailService *services.EmailService) {
+	runDate := standingOrder.NextRunAt
+	next := nextStandingOrderRun(standingOrder, runDate)
+
+	updates := map[string]interface{}{"last_run_at": now}
+	closed := isSupplierClosedOn(database.DB, standingOrder.Supplier, runDate)
+	if closed {
+		updates["last_run_status"] = models.StandingOrderRunSkipped
+		updates["last_run_message"] = fmt.Sprintf("%s is closed on %s", standingOrder.Supplier.Name, runDate.Format("2006-01-02"))
+		if standingOrder.ClosedDayPolicy == models.ClosedDayDefer {
+			if deferred, ok := nextOpenStandingOrderDay(standingOrder.Supplier, runDate, next); ok {
+				updates["last_run_status"] = models.StandingOrderRunDeferred
+				updates["last_run_message"] = fmt.Sprintf("%s; deferred to %s", updates["last_run_message"], deferred.Format("2006-01-02"))
+				next = deferred
+			}
+		}
+	}
+
+	// Runs missed while the server was down are not made up.
+	for !next.After(now) {
+		next = nextStandingOrderRun(standingOrder, next)
+	}
+
+	// Claim the run before placing anything, so a second instance running
+	// the same tick finds next_run_at already moved and leaves it alone.
+	claim := database.DB.Model(&models.StandingOrder{}).
+		Where("id = ? AND next_run_at = ?", standingOrder.ID, runDate).
+		Update("next_run_at", next)
+	if claim.Error != nil {
+		log.Printf("runStandingOrders: failed to claim standing order %d: %v", standingOrder.ID, claim.Error)
+		return
+	}
+	if claim.RowsAffected == 0 {
+		return
+	}
+
+	if !closed {
+		order, transition, err := placeStandingOrder(standingOrder)
+		if err != nil {
+			log.Printf("runStandingOrders: standing order %d failed: %v", standingOrder.ID, err)
+			updates["last_run_status"] = models.StandingOrderRunFailed
+			updates["last_run_message"] = err.Error()
+			if emailService != nil {
+				go emailService.SendStandingOrderFailedEmail(standingOrder, runDate, err.Error())
+			}
+		} else {
+			log.Printf("runStandingOrders: standing order %d placed order %d", standingOrder.ID, order.ID)
+			updates["last_run_status"] = models.StandingOrderRunSubmitted
+			updates["last_run_message"] = ""
+			updates["last_order_id"] = order.ID
+			runOrderNotifications(emailService, order, models.OrderStatusDraft, transition)
+		}
+	}
+
+	if err := database.DB.Model(&models.StandingOrder{}).Where("id = ?", standingOrder.ID).Updates(updates).Error; err != nil {
+		log.Printf("runStandingOrders: failed to update standing order %d: %v", standingOrder.ID, err)
+	}
+}
+
+// nextOpenStandingOrderDay finds the first day after runDate, and before the
+// next scheduled run, on which the supplier is open.
+func nextOpenStandingOrderDay(supplier models.User, runDate, next time.Time) (time.Time, bool) {
+	for i := 1; i <= maxStandingOrderDeferDays; i++ {
+		day := standingOrderRunTime(runDate).AddDate(0, 0, i)
+		if !day.Before(next) {
+			break
+		}
+		if !isSupplierClosedOn(database.DB, supplier, day) {
+			return day, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// placeStandingOrder builds an order from the template and submits it with
+// the same stock and minimum-order checks as SubmitOrder. Nothing is kept if
+// any check fails.
+func placeStandingOrder(standingOrder models.StandingOrder) (models.Order, models.OrderTransition, error) {
+	actor := orderActor{UserID: standingOrder.StoreID, Role: models.RoleStore}
+	reason := fmt.Sprintf("standing order #%d", standingOrder.ID)
+	standingOrderID := standingOrder.ID
+
+	order := models.Order{
+		StoreID:         standingOrder.StoreID,
+		SupplierID:      standingOrder.SupplierID,
+		Status:          models.OrderStatusDraft,
+		StandingOrderID: &standingOrderID,
+	}
+	var transition models.OrderTransition
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+		if err := recordOrderStatusHistory(tx, order.ID, "", models.OrderStatusDraft, actor, reason); err != nil {
+			return err
+		}
+
+		for _, item := range standingOrder.Items {
+			change := orderItemChange{OrderID: order.ID, ProductID: item.ProductID, Quantity: item.Quantity, Add: true}
+			if err := applyOrderItemChangeTx(tx, change, actor, config.DefaultStockReservationTTL); err != nil {
+				var orderErr *orderError
+				if errors.As(err, &orderErr) {
+					return &orderError{Code: orderErr.Code, Message: fmt.Sprintf("product %d: %s", item.ProductID, orderErr.Message)}
+				}
+				return err
+			}
+		}
+
+		if err := tx.Preload("OrderItems").First(&order, order.ID).Error; err != nil {
+			return err
+		}
+		submission := orderSubmission{
+			PaymentMethod:   string(standingOrder.PaymentMethod),
+			DeliveryOption:  string(standingOrder.DeliveryOption),
+			ShippingAddress: standingOrder.ShippingAddress,
+			Notes:           standingOrder.Notes,
+		}
+		// Runs happen at a fixed hour and closed days are handled by the
+		// closed day policy, so opening hours are not checked here.
+		if err := prepareOrderSubmission(tx, &order, submission, false); err != nil {
+			return err
+		}
+		var err error
+		transition, err = submitOrderTx(tx, &order, actor, reason)
+		return err
+	})
+	if err != nil {
+		return order, transition, err
+	}
+
+	database.DB.Preload("Store").Preload("Supplier").Preload("OrderItems").Preload("OrderItems.Product").First(&order, order.ID)
+	return order, transition, nil
+}
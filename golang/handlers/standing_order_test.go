@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+)
+
+func setupStandingOrderTestDB(t *testing.T) (models.User, models.User) {
+	t.Helper()
+	store, supplier := setupOrderTestDB(t)
+	migrateTestModels(t, &models.StockReservation{}, &models.SupplierDeliverySettings{}, &models.DeliveryFeeBand{}, &models.DeliveryFeeZone{}, &models.ServiceAreaPlace{}, &models.ScheduleException{}, &models.StandingOrder{}, &models.StandingOrderItem{})
+	return store, supplier
+}
+
+func TestRunStandingOrders(t *testing.T) {
+	store, supplier := setupStandingOrderTestDB(t)
+	rice := models.Product{SupplierID: supplier.ID, Name: "Rice", SKU: "RICE-1", Price: 2500, StockQuantity: 10}
+	database.DB.Create(&rice)
+
+	monday := time.Date(2026, time.October, 12, standingOrderRunHour, 0, 0, 0, philippineTZ)
+	newStandingOrder := func(quantity int, policy models.ClosedDayPolicy) models.StandingOrder {
+		standingOrder := models.StandingOrder{
+			StoreID:         store.ID,
+			SupplierID:      supplier.ID,
+			Recurrence:      models.StandingOrderWeekly,
+			Weekdays:        "1,4",
+			ClosedDayPolicy: policy,
+			PaymentMethod:   models.PaymentMethodCashOnDelivery,
+			DeliveryOption:  models.DeliveryOptionPickup,
+			Active:          true,
+			NextRunAt:       monday,
+			Items:           []models.StandingOrderItem{{ProductID: rice.ID, Quantity: quantity}},
+		}
+		if err := database.DB.Omit("Store", "Supplier").Create(&standingOrder).Error; err != nil {
+			t.Fatalf("create standing order: %v", err)
+		}
+		return standingOrder
+	}
+
+	placed := newStandingOrder(2, models.ClosedDaySkip)
+	belowMinimum := newStandingOrder(1, models.ClosedDaySkip)
+	var stale models.StandingOrder
+	database.DB.Preload("Store").Preload("Supplier").Preload("Items").First(&stale, placed.ID)
+	runStandingOrders(monday.Add(time.Minute), nil)
+
+	database.DB.First(&placed, placed.ID)
+	if placed.LastRunStatus != models.StandingOrderRunSubmitted || placed.LastOrderID == nil {
+		t.Fatalf("expected a submitted run, got %s: %s", placed.LastRunStatus, placed.LastRunMessage)
+	}
+	if thursday := monday.AddDate(0, 0, 3); !placed.NextRunAt.Equal(thursday) {
+		t.Fatalf("expected next run %s, got %s", thursday, placed.NextRunAt)
+	}
+	var order models.Order
+	database.DB.First(&order, *placed.LastOrderID)
+	if order.Status != models.OrderStatusPreparing || order.StandingOrderID == nil || *order.StandingOrderID != placed.ID {
+		t.Fatalf("unexpected order: %+v", order)
+	}
+	database.DB.First(&rice, rice.ID)
+	if rice.StockQuantity != 8 {
+		t.Fatalf("expected stock 8, got %d", rice.StockQuantity)
+	}
+
+	database.DB.First(&belowMinimum, belowMinimum.ID)
+	if belowMinimum.LastRunStatus != models.StandingOrderRunFailed || belowMinimum.LastOrderID != nil {
+		t.Fatalf("expected a failed run, got %s", belowMinimum.LastRunStatus)
+	}
+	var orders int64
+	database.DB.Model(&models.Order{}).Count(&orders)
+	if orders != 1 {
+		t.Fatalf("failed run must not leave an order behind, got %d orders", orders)
+	}
+
+	// A second runner that loaded the same due run finds it already claimed.
+	runStandingOrder(stale, monday.Add(time.Minute), nil)
+	database.DB.Model(&models.Order{}).Count(&orders)
+	if orders != 1 {
+		t.Fatalf("expected a claimed run to be placed once, got %d orders", orders)
+	}
+
+	database.DB.Model(&models.StandingOrder{}).Where("id IN ?", []uint{placed.ID, belowMinimum.ID}).Update("active", false)
+	database.DB.Create(&models.ScheduleException{UserID: supplier.ID, Date: time.Date(2026, time.October, 12, 0, 0, 0, 0, time.UTC), IsClosed: true})
+	skipped := newStandingOrder(2, models.ClosedDaySkip)
+	deferred := newStandingOrder(2, models.ClosedDayDefer)
+	runStandingOrders(monday.Add(time.Minute), nil)
+
+	database.DB.First(&skipped, skipped.ID)
+	if skipped.LastRunStatus != models.StandingOrderRunSkipped || !skipped.NextRunAt.Equal(monday.AddDate(0, 0, 3)) {
+		t.Fatalf("expected skip to Thursday, got %s next %s", skipped.LastRunStatus, skipped.NextRunAt)
+	}
+	database.DB.First(&deferred, deferred.ID)
+	if deferred.LastRunStatus != models.StandingOrderRunDeferred || !deferred.NextRunAt.Equal(monday.AddDate(0, 0, 1)) {
+		t.Fatalf("expected deferral to Tuesday, got %s next %s", deferred.LastRunStatus, deferred.NextRunAt)
+	}
+}
@@ -0,0 +1,427 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+	"siargao-trading-road/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	statementLineInvoice    = "invoice"
+	statementLinePayment    = "payment"
+	statementLineRefund     = "refund"
+	statementLineCreditMemo = "credit_memo"
+)
+
+// statementLine is one invoice, payment, refund or credit memo on a
+// statement, with the balance after it.
+type statementLine struct {
+	Date        time.Time `json:"date"`
+	Type        string    `json:"type"`
+	Reference   string    `json:"reference"`
+	OrderID     uint      `json:"order_id"`
+	Description string    `json:"description"`
+	Debit       float64   `json:"debit"`
+	Credit      float64   `json:"credit"`
+	Balance     float64   `json:"balance"`
+}
+
+// accountStatement is what a store owes a supplier over a period: the
+// balance brought forward, every movement in the period and the balance at
+// the end. A positive balance is owed by the store.
+type accountStatement struct {
+	Supplier       models.User     `json:"supplier"`
+	Store          models.User     `json:"store"`
+	From           string          `json:"from"`
+	To             string          `json:"to"`
+	OpeningBalance float64         `json:"opening_balance"`
+	TotalDebits    float64         `json:"total_debits"`
+	TotalCredits   float64         `json:"total_credits"`
+	ClosingBalance float64         `json:"closing_balance"`
+	Lines          []statementLine `json:"lines"`
+}
+
+// GetMyStatement returns the statement of account between the caller and a
+// counterpart: store_id for suppliers, supplier_id for stores. from and to
+// are inclusive Philippine days and default to last month.
+func GetMyStatement(c *gin.Context) {
+	statement, ok := loadStatementFromQuery(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, statement)
+}
+
+// DownloadMyStatement is GetMyStatement as a PDF.
+func DownloadMyStatement(c *gin.Context) {
+	statement, ok := loadStatementFromQuery(c)
+	if !ok {
+		return
+	}
+
+	data, err := renderStatementPDF(statement)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate statement"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%s", statementFilename(statement)))
+	c.Data(http.StatusOK, "application/pdf", data)
+}
+
+func loadStatementFromQuery(c *gin.Context) (accountStatement, bool) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return accountStatement{}, false
+	}
+	empCtx := getEmployeeContext(c)
+	if !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return accountStatement{}, false
+	}
+	role, _ := c.Get("role")
+
+	var supplierID, storeID uint
+	switch role {
+	case "supplier":
+		supplierID = userID
+		storeID, err = parseStatementParty(c.Query("store_id"))
+	case "store":
+		storeID = userID
+		supplierID, err = parseStatementParty(c.Query("supplier_id"))
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "only suppliers and stores have statements"})
+		return accountStatement{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return accountStatement{}, false
+	}
+
+	start, end := previousMonth(nowInPH())
+	if value := c.Query("from"); value != "" {
+		if start, err = parseDeliveryDate(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be YYYY-MM-DD"})
+			return accountStatement{}, false
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		day, err := parseDeliveryDate(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be YYYY-MM-DD"})
+			return accountStatement{}, false
+		}
+		end = day.AddDate(0, 0, 1)
+	}
+	if !end.After(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to cannot be before from"})
+		return accountStatement{}, false
+	}
+
+	var supplier, store models.User
+	if err := database.DB.Where("id = ? AND role = ?", supplierID, models.RoleSupplier).First(&supplier).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "supplier not found"})
+		return accountStatement{}, false
+	}
+	if err := database.DB.Where("id = ? AND role = ?", storeID, models.RoleStore).First(&store).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "store not found"})
+		return accountStatement{}, false
+	}
+
+	statement, err := buildStatement(database.DB, supplier, store, start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build statement"})
+		return accountStatement{}, false
+	}
+	return statement, true
+}
+
+func parseStatementParty(value string) (uint, error) {
+	if value == "" {
+		return 0, fmt.Errorf("choose whose statement to show")
+	}
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid id %q", value)
+	}
+	return uint(id), nil
+}
+
+// previousMonth is the calendar month before now's, as [start, end).
+func previousMonth(now time.Time) (time.Time, time.Time) {
+	end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	return end.AddDate(0, -1, 0), end
+}
+
+// buildStatement collects a supplier and store's invoices, payments and
+// credit memos for [start, end). Invoices are submitted orders, dated when
+// the order was created as on the invoice PDF.
+func buildStatement(db *gorm.DB, supplier, store models.User, start, end time.Time) (accountStatement, error) {
+	statement := accountStatement{
+		Supplier: supplier,
+		Store:    store,
+		From:     start.Format(dateLayout),
+		To:       end.AddDate(0, 0, -1).Format(dateLayout),
+		Lines:    []statementLine{},
+	}
+
+	orders := func() *gorm.DB {
+		return db.Model(&models.Order{}).Where("supplier_id = ? AND store_id = ? AND status NOT IN ?",
+			supplier.ID, store.ID, []models.OrderStatus{models.OrderStatusDraft, models.OrderStatusCancelled})
+	}
+	payments := func() *gorm.DB {
+		return db.Model(&models.Payment{}).Joins("JOIN orders ON orders.id = payments.order_id").
+			Where("orders.supplier_id = ? AND orders.store_id = ? AND orders.deleted_at IS NULL", supplier.ID, store.ID)
+	}
+	memos := func() *gorm.DB {
+		return db.Model(&models.CreditMemo{}).Where("supplier_id = ? AND store_id = ?", supplier.ID, store.ID)
+	}
+
+	var invoiced, paid, credited float64
+	if err := orders().Where("created_at < ?", start.UTC()).Select("COALESCE(SUM(total_amount), 0)").Scan(&invoiced).Error; err != nil {
+		return statement, err
+	}
+	if err := payments().Where("payments.paid_at < ?", start.UTC()).Select("COALESCE(SUM(payments.amount), 0)").Scan(&paid).Error; err != nil {
+		return statement, err
+	}
+	if err := memos().Where("created_at < ?", start.UTC()).Select("COALESCE(SUM(amount), 0)").Scan(&credited).Error; err != nil {
+		return statement, err
+	}
+	statement.OpeningBalance = roundTo2(invoiced - paid - credited)
+
+	var invoices []models.Order
+	if err := orders().Where("created_at >= ? AND created_at < ?", start.UTC(), end.UTC()).Find(&invoices).Error; err != nil {
+		return statement, err
+	}
+	for _, order := range invoices {
+		statement.Lines = append(statement.Lines, statementLine{
+			Date:        order.CreatedAt,
+			Type:        statementLineInvoice,
+			Reference:   fmt.Sprintf("Invoice #%d", order.ID),
+			OrderID:     order.ID,
+			Description: fmt.Sprintf("Order #%d", order.ID),
+			Debit:       order.TotalAmount,
+		})
+	}
+
+	var entries []models.Payment
+	if err := payments().Where("payments.paid_at >= ? AND payments.paid_at < ?", start.UTC(), end.UTC()).Find(&entries).Error; err != nil {
+		return statement, err
+	}
+	for _, payment := range entries {
+		line := statementLine{
+			Date:        payment.PaidAt,
+			Type:        statementLinePayment,
+			Reference:   payment.Reference,
+			OrderID:     payment.OrderID,
+			Description: fmt.Sprintf("%s payment for order #%d", paymentMethodLabel(payment.Method), payment.OrderID),
+			Credit:      payment.Amount,
+		}
+		if payment.Amount < 0 {
+			line.Type = statementLineRefund
+			line.Description = fmt.Sprintf("%s refund for order #%d", paymentMethodLabel(payment.Method), payment.OrderID)
+			line.Credit = 0
+			line.Debit = -payment.Amount
+		}
+		statement.Lines = append(statement.Lines, line)
+	}
+
+	var creditMemos []models.CreditMemo
+	if err := memos().Where("created_at >= ? AND created_at < ?", start.UTC(), end.UTC()).Find(&creditMemos).Error; err != nil {
+		return statement, err
+	}
+	for _, memo := range creditMemos {
+		statement.Lines = append(statement.Lines, statementLine{
+			Date:        memo.CreatedAt,
+			Type:        statementLineCreditMemo,
+			Reference:   memo.MemoNumber,
+			OrderID:     memo.OrderID,
+			Description: fmt.Sprintf("Return credit for order #%d", memo.OrderID),
+			Credit:      memo.Amount,
+		})
+	}
+
+	sort.SliceStable(statement.Lines, func(i, j int) bool {
+		return statement.Lines[i].Date.Before(statement.Lines[j].Date)
+	})
+	balance := statement.OpeningBalance
+	for i := range statement.Lines {
+		line := &statement.Lines[i]
+		balance = roundTo2(balance + line.Debit - line.Credit)
+		line.Balance = balance
+		statement.TotalDebits = roundTo2(statement.TotalDebits + line.Debit)
+		statement.TotalCredits = roundTo2(statement.TotalCredits + line.Credit)
+	}
+	statement.ClosingBalance = balance
+	return statement, nil
+}
+
+func paymentMethodLabel(method models.PaymentMethod) string {
+	switch method {
+	case models.PaymentMethodGCash:
+		return "GCash"
+	case models.PaymentMethodCashOnDelivery:
+		return "Cash"
+	case models.PaymentMethodCredit:
+		return "Credit"
+	}
+	return string(method)
+}
+
+func renderStatementPDF(statement accountStatement) ([]byte, error) {
+	pdf := newBrandedPDF("Statement", fmt.Sprintf("%s to %s", statement.From, statement.To))
+	pdf.SetFont("Arial", "B", 11)
+	pdf.CellFormat(93, 7, "Supplier", "", 0, "L", false, 0, "")
+	pdf.CellFormat(93, 7, "Store", "", 1, "R", false, 0, "")
+	pdf.SetFont("Arial", "", 10)
+	pdf.CellFormat(93, 6, statement.Supplier.Name, "", 0, "L", false, 0, "")
+	pdf.CellFormat(93, 6, statement.Store.Name, "", 1, "R", false, 0, "")
+	pdf.CellFormat(93, 6, statement.Supplier.Address, "", 0, "L", false, 0, "")
+	pdf.CellFormat(93, 6, statement.Store.Address, "", 1, "R", false, 0, "")
+	pdf.Ln(4)
+
+	pdf.SetFont("Arial", "B", 10)
+	pdf.CellFormat(22, 8, "Date", "1", 0, "L", false, 0, "")
+	pdf.CellFormat(30, 8, "Reference", "1", 0, "L", false, 0, "")
+	pdf.CellFormat(62, 8, "Description", "1", 0, "L", false, 0, "")
+	pdf.CellFormat(24, 8, "Debit", "1", 0, "R", false, 0, "")
+	pdf.CellFormat(24, 8, "Credit", "1", 0, "R", false, 0, "")
+	pdf.CellFormat(24, 8, "Balance", "1", 1, "R", false, 0, "")
+
+	pdf.SetFont("Arial", "I", 9)
+	pdf.CellFormat(162, 7, "Balance brought forward", "1", 0, "L", false, 0, "")
+	pdf.CellFormat(24, 7, fmt.Sprintf("%.2f", statement.OpeningBalance), "1", 1, "R", false, 0, "")
+
+	amount := func(v float64) string {
+		if v == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.2f", v)
+	}
+	pdf.SetFont("Arial", "", 9)
+	for _, line := range statement.Lines {
+		pdf.CellFormat(22, 7, line.Date.In(philippineTZ).Format(dateLayout), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(30, 7, line.Reference, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(62, 7, line.Description, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(24, 7, amount(line.Debit), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(24, 7, amount(line.Credit), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(24, 7, fmt.Sprintf("%.2f", line.Balance), "1", 1, "R", false, 0, "")
+	}
+
+	pdf.SetFont("Arial", "B", 10)
+	pdf.CellFormat(114, 8, "Totals", "1", 0, "R", false, 0, "")
+	pdf.CellFormat(24, 8, fmt.Sprintf("%.2f", statement.TotalDebits), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(24, 8, fmt.Sprintf("%.2f", statement.TotalCredits), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(24, 8, "", "1", 1, "R", false, 0, "")
+	pdf.CellFormat(162, 8, "Balance due (PHP)", "1", 0, "R", false, 0, "")
+	pdf.CellFormat(24, 8, fmt.Sprintf("%.2f", statement.ClosingBalance), "1", 1, "R", false, 0, "")
+
+	writePDFFooter(pdf)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func statementFilename(statement accountStatement) string {
+	return fmt.Sprintf("statement-%d-%d-%s.pdf", statement.Supplier.ID, statement.Store.ID, statement.From)
+}
+
+// sendMonthlyStatements emails every store its statement from each supplier
+// it dealt with last month. It runs hourly so the statements go out early on
+// the 1st, and a missed run catches up later in the month; StatementDelivery
+// keeps each one from being sent twice.
+func sendMonthlyStatements(now time.Time, emailService *services.EmailService) {
+	if emailService == nil {
+		return
+	}
+	start, end := previousMonth(now.In(philippineTZ))
+
+	type pair struct{ SupplierID, StoreID uint }
+	sources := []struct {
+		name  string
+		query *gorm.DB
+	}{
+		{"orders", database.DB.Model(&models.Order{}).Distinct("supplier_id", "store_id").
+			Where("status NOT IN ? AND created_at >= ? AND created_at < ?",
+				[]models.OrderStatus{models.OrderStatusDraft, models.OrderStatusCancelled}, start.UTC(), end.UTC())},
+		{"payments", database.DB.Model(&models.Payment{}).Joins("JOIN orders ON orders.id = payments.order_id").
+			Distinct("orders.supplier_id", "orders.store_id").
+			Where("payments.paid_at >= ? AND payments.paid_at < ?", start.UTC(), end.UTC())},
+		{"credit memos", database.DB.Model(&models.CreditMemo{}).Distinct("supplier_id", "store_id").
+			Where("created_at >= ? AND created_at < ?", start.UTC(), end.UTC())},
+	}
+	var pairs []pair
+	for _, source := range sources {
+		var found []pair
+		if err := source.query.Scan(&found).Error; err != nil {
+			log.Printf("monthly statements: failed to load %s: %v", source.name, err)
+			return
+		}
+		pairs = append(pairs, found...)
+	}
+
+	periodStart := storedDeliveryDate(start)
+	period := start.Format("January 2006")
+	seen := map[pair]bool{}
+	for _, p := range pairs {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+
+		var sent int64
+		database.DB.Model(&models.StatementDelivery{}).
+			Where("supplier_id = ? AND store_id = ? AND period_start = ?", p.SupplierID, p.StoreID, periodStart).
+			Count(&sent)
+		if sent > 0 {
+			continue
+		}
+
+		var supplier, store models.User
+		if err := database.DB.First(&supplier, p.SupplierID).Error; err != nil {
+			continue
+		}
+		if err := database.DB.First(&store, p.StoreID).Error; err != nil {
+			continue
+		}
+		statement, err := buildStatement(database.DB, supplier, store, start, end)
+		if err != nil {
+			log.Printf("monthly statements: failed to build statement for supplier %d store %d: %v", p.SupplierID, p.StoreID, err)
+			continue
+		}
+		data, err := renderStatementPDF(statement)
+		if err != nil {
+			log.Printf("monthly statements: failed to render statement for supplier %d store %d: %v", p.SupplierID, p.StoreID, err)
+			continue
+		}
+
+		// The delivery is recorded before sending so a concurrent run cannot
+		// send the same statement, and removed again if the email fails so
+		// the next run retries it.
+		delivery := models.StatementDelivery{SupplierID: p.SupplierID, StoreID: p.StoreID, PeriodStart: periodStart, SentAt: now}
+		if err := database.DB.Create(&delivery).Error; err != nil {
+			log.Printf("monthly statements: failed to record statement for supplier %d store %d: %v", p.SupplierID, p.StoreID, err)
+			continue
+		}
+		if err := emailService.SendStatementEmail(store, supplier, period, statement.ClosingBalance, statementFilename(statement), data); err != nil {
+			log.Printf("monthly statements: failed to email store %d: %v", p.StoreID, err)
+			if err := database.DB.Delete(&delivery).Error; err != nil {
+				log.Printf("monthly statements: failed to clear statement for supplier %d store %d: %v", p.SupplierID, p.StoreID, err)
+			}
+		}
+	}
+}
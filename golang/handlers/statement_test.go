@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"siargao-trading-road/config"
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+	"siargao-trading-road/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupStatementTestDB(t *testing.T) (models.User, models.User) {
+	t.Helper()
+	store, supplier := setupOrderTestDB(t)
+	migrateTestModels(t, &models.CreditAccount{}, &models.CreditMemo{}, &models.Payment{}, &models.StatementDelivery{})
+	return store, supplier
+}
+
+func buildStatementRouter(user models.User) *gin.Engine {
+	r := buildOrderRouter(user)
+	r.GET("/me/statement", GetMyStatement)
+	r.GET("/me/statement/pdf", DownloadMyStatement)
+	return r
+}
+
+func TestStatementRunsBalanceAndIsEmailedOnce(t *testing.T) {
+	store, supplier := setupStatementTestDB(t)
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2026, month, d, 10, 0, 0, 0, philippineTZ)
+	}
+
+	august := models.Order{StoreID: store.ID, SupplierID: supplier.ID, Status: models.OrderStatusDelivered, PaymentMethod: models.PaymentMethodCredit, TotalAmount: 1000, CreatedAt: day(time.August, 20)}
+	september := models.Order{StoreID: store.ID, SupplierID: supplier.ID, Status: models.OrderStatusDelivered, PaymentMethod: models.PaymentMethodGCash, TotalAmount: 500, CreatedAt: day(time.September, 5)}
+	cancelled := models.Order{StoreID: store.ID, SupplierID: supplier.ID, Status: models.OrderStatusCancelled, TotalAmount: 900, CreatedAt: day(time.September, 6)}
+	for _, order := range []*models.Order{&august, &september, &cancelled} {
+		database.DB.Create(order)
+	}
+	database.DB.Create(&models.Payment{OrderID: august.ID, Method: models.PaymentMethodCashOnDelivery, Amount: 400, PaidAt: day(time.August, 25)})
+	database.DB.Create(&models.Payment{OrderID: august.ID, Method: models.PaymentMethodGCash, Amount: 600, Reference: "111222", PaidAt: day(time.September, 10)})
+	database.DB.Create(&models.Payment{OrderID: september.ID, Method: models.PaymentMethodGCash, Amount: 550, PaidAt: day(time.September, 12)})
+	database.DB.Create(&models.Payment{OrderID: september.ID, Method: models.PaymentMethodGCash, Amount: -50, PaidAt: day(time.September, 13)})
+	database.DB.Create(&models.CreditMemo{MemoNumber: "CM-000001", ReturnRequestID: 1, OrderID: september.ID, StoreID: store.ID, SupplierID: supplier.ID, Amount: 100, CreatedAt: day(time.September, 20)})
+
+	router := buildStatementRouter(supplier)
+	w := doOrderRequest(router, http.MethodGet, fmt.Sprintf("/me/statement?store_id=%d&from=2026-09-01&to=2026-09-30", store.ID), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var statement accountStatement
+	json.Unmarshal(w.Body.Bytes(), &statement)
+	if statement.OpeningBalance != 600 || len(statement.Lines) != 5 || statement.ClosingBalance != -100 {
+		t.Fatalf("unexpected statement: %s", w.Body.String())
+	}
+	wantTypes := []string{statementLineInvoice, statementLinePayment, statementLinePayment, statementLineRefund, statementLineCreditMemo}
+	wantBalances := []float64{1100, 500, -50, 0, -100}
+	for i, line := range statement.Lines {
+		if line.Type != wantTypes[i] || line.Balance != wantBalances[i] {
+			t.Fatalf("line %d: expected %s with balance %.2f, got %+v", i, wantTypes[i], wantBalances[i], line)
+		}
+	}
+
+	if w := doOrderRequest(router, http.MethodGet, "/me/statement", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected a missing store to be refused, got %d", w.Code)
+	}
+	w = doOrderRequest(buildStatementRouter(store), http.MethodGet, fmt.Sprintf("/me/statement/pdf?supplier_id=%d&from=2026-09-01&to=2026-09-30", supplier.ID), "")
+	if w.Code != http.StatusOK || !bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF")) {
+		t.Fatalf("expected a PDF statement, got %d", w.Code)
+	}
+
+	firstOfOctober := time.Date(2026, time.October, 1, 1, 0, 0, 0, philippineTZ)
+	unreachable := services.NewEmailService(&config.Config{SMTPHost: "127.0.0.1", SMTPPort: "1", SMTPUser: "user", SMTPPassword: "secret"})
+	sendMonthlyStatements(firstOfOctober, unreachable)
+	var failed int64
+	database.DB.Model(&models.StatementDelivery{}).Count(&failed)
+	if failed != 0 {
+		t.Fatalf("expected a failed send not to be recorded, got %d deliveries", failed)
+	}
+
+	emailService := services.NewEmailService(&config.Config{})
+	sendMonthlyStatements(firstOfOctober, emailService)
+	sendMonthlyStatements(firstOfOctober.Add(time.Hour), emailService)
+	var deliveries []models.StatementDelivery
+	database.DB.Find(&deliveries)
+	if len(deliveries) != 1 || deliveries[0].StoreID != store.ID || deliveries[0].PeriodStart.Format(dateLayout) != "2026-09-01" {
+		t.Fatalf("expected one September statement delivery, got %+v", deliveries)
+	}
+}
@@ -30,8 +30,34 @@ func GetSuppliers(c *gin.Context) {
 
 	search := strings.TrimSpace(strings.ToLower(c.Query("search")))
 	status := strings.TrimSpace(strings.ToLower(c.Query("status")))
+	sortBy := strings.TrimSpace(strings.ToLower(c.Query("sort")))
 	now := nowInPH()
 
+	if sortBy != "" && sortBy != "distance" && sortBy != "rating" && sortBy != "name" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be distance, rating or name"})
+		return
+	}
+
+	var origin *models.GeoPoint
+	if near := strings.TrimSpace(c.Query("near")); near != "" {
+		point, ok := parseLatLng(near)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "near must be lat,lng"})
+			return
+		}
+		origin = &point
+	}
+
+	var radiusKm float64
+	if radius := strings.TrimSpace(c.Query("radius_km")); radius != "" {
+		value, err := strconv.ParseFloat(radius, 64)
+		if err != nil || value <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "radius_km must be a positive number"})
+			return
+		}
+		radiusKm = value
+	}
+
 	db := database.DB.Where("role = ?", "supplier")
 	if search != "" {
 		db = db.Where("LOWER(name) LIKE ?", "%"+search+"%")
@@ -43,6 +69,39 @@ func GetSuppliers(c *gin.Context) {
 		return
 	}
 
+	// Stores see whether each supplier delivers to them, and can ask for
+	// only those that do.
+	var caller *models.User
+	if role == "store" {
+		if userID, err := getUserID(c); err == nil {
+			var store models.User
+			if database.DB.First(&store, userID).Error == nil {
+				caller = &store
+			}
+		}
+	}
+	deliversToMeOnly := c.Query("delivers_to_me") == "true"
+	var serviceAreas map[uint]models.SupplierDeliverySettings
+	if caller != nil {
+		serviceAreas = loadServiceAreas(suppliers)
+	}
+
+	// Distances are measured from near, or else the store's own location.
+	if origin == nil && caller != nil && caller.Latitude != nil && caller.Longitude != nil {
+		origin = &models.GeoPoint{Lat: *caller.Latitude, Lng: *caller.Longitude}
+	}
+	if origin == nil && (radiusKm > 0 || sortBy == "distance") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "near is required to filter or sort by distance"})
+		return
+	}
+
+	supplierIDs := make([]uint, 0, len(suppliers))
+	for _, supplier := range suppliers {
+		supplierIDs = append(supplierIDs, supplier.ID)
+	}
+	productCounts := countProductsBySupplier(supplierIDs)
+	ratings := ratingStatsByUser(supplierIDs)
+
 	type SupplierInfo struct {
 		ID            uint     `json:"id"`
 		Name          string   `json:"name"`
@@ -60,6 +119,8 @@ func GetSuppliers(c *gin.Context) {
 		IsOpen        bool     `json:"is_open"`
 		Latitude      *float64 `json:"latitude,omitempty"`
 		Longitude     *float64 `json:"longitude,omitempty"`
+		DeliversToYou *bool    `json:"delivers_to_you,omitempty"`
+		DistanceKm    *float64 `json:"distance_km,omitempty"`
 	}
 
 	supplierInfos := make([]SupplierInfo, 0, len(suppliers))
@@ -72,17 +133,26 @@ func GetSuppliers(c *gin.Context) {
 			continue
 		}
 
-		var productCount int64
-		database.DB.Model(&models.Product{}).Where("supplier_id = ?", supplier.ID).Count(&productCount)
+		var deliversToYou *bool
+		if caller != nil {
+			delivers := deliversTo(serviceAreas[supplier.ID], supplier, *caller)
+			if deliversToMeOnly && !delivers {
+				continue
+			}
+			deliversToYou = &delivers
+		}
 
-		var ratingStats struct {
-			AverageRating float64
-			RatingCount   int64
+		var distanceKm *float64
+		if origin != nil && supplier.Latitude != nil && supplier.Longitude != nil {
+			distance := roundTo2(haversineKm(origin.Lat, origin.Lng, *supplier.Latitude, *supplier.Longitude))
+			distanceKm = &distance
 		}
-		database.DB.Model(&models.Rating{}).
-			Where("rated_id = ?", supplier.ID).
-			Select("COALESCE(AVG(rating), 0) as average_rating, COUNT(*) as rating_count").
-			Scan(&ratingStats)
+		if radiusKm > 0 && (distanceKm == nil || *distanceKm > radiusKm) {
+			continue
+		}
+
+		productCount := productCounts[supplier.ID]
+		ratingStats := ratings[supplier.ID]
 
 		log.Printf("Supplier %s (ID: %d) - LogoURL: '%s', BannerURL: '%s'", supplier.Name, supplier.ID, supplier.LogoURL, supplier.BannerURL)
 
@@ -110,19 +180,92 @@ func GetSuppliers(c *gin.Context) {
 			IsOpen:        openNow,
 			Latitude:      supplier.Latitude,
 			Longitude:     supplier.Longitude,
+			DeliversToYou: deliversToYou,
+			DistanceKm:    distanceKm,
 		})
 	}
 
-	sort.Slice(supplierInfos, func(i, j int) bool {
-		if supplierInfos[i].IsOpen == supplierInfos[j].IsOpen {
-			return strings.ToLower(supplierInfos[i].Name) < strings.ToLower(supplierInfos[j].Name)
+	sort.SliceStable(supplierInfos, func(i, j int) bool {
+		a, b := supplierInfos[i], supplierInfos[j]
+		byName := strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		switch sortBy {
+		case "name":
+			return byName
+		case "distance":
+			if (a.DistanceKm == nil) != (b.DistanceKm == nil) {
+				return a.DistanceKm != nil
+			}
+			if a.DistanceKm != nil && *a.DistanceKm != *b.DistanceKm {
+				return *a.DistanceKm < *b.DistanceKm
+			}
+			return byName
+		case "rating":
+			if (a.AverageRating == nil) != (b.AverageRating == nil) {
+				return a.AverageRating != nil
+			}
+			if a.AverageRating != nil && *a.AverageRating != *b.AverageRating {
+				return *a.AverageRating > *b.AverageRating
+			}
+			if a.RatingCount != b.RatingCount {
+				return a.RatingCount > b.RatingCount
+			}
+			return byName
 		}
-		return supplierInfos[i].IsOpen && !supplierInfos[j].IsOpen
+		if a.IsOpen == b.IsOpen {
+			return byName
+		}
+		return a.IsOpen && !b.IsOpen
 	})
 
 	c.JSON(http.StatusOK, supplierInfos)
 }
 
+type ratingStat struct {
+	RatedID       uint
+	AverageRating float64
+	RatingCount   int64
+}
+
+// countProductsBySupplier counts live products for several suppliers in one query.
+func countProductsBySupplier(supplierIDs []uint) map[uint]int64 {
+	counts := make(map[uint]int64, len(supplierIDs))
+	if len(supplierIDs) == 0 {
+		return counts
+	}
+	var rows []struct {
+		SupplierID uint
+		Count      int64
+	}
+	database.DB.Model(&models.Product{}).
+		Select("supplier_id, COUNT(*) as count").
+		Where("supplier_id IN ?", supplierIDs).
+		Group("supplier_id").
+		Scan(&rows)
+	for _, row := range rows {
+		counts[row.SupplierID] = row.Count
+	}
+	return counts
+}
+
+// ratingStatsByUser loads the average rating and rating count for several
+// users in one query.
+func ratingStatsByUser(userIDs []uint) map[uint]ratingStat {
+	stats := make(map[uint]ratingStat, len(userIDs))
+	if len(userIDs) == 0 {
+		return stats
+	}
+	var rows []ratingStat
+	database.DB.Model(&models.Rating{}).
+		Select("rated_id, COALESCE(AVG(rating), 0) as average_rating, COUNT(*) as rating_count").
+		Where("rated_id IN ?", userIDs).
+		Group("rated_id").
+		Scan(&rows)
+	for _, row := range rows {
+		stats[row.RatedID] = row
+	}
+	return stats
+}
+
 func GetSupplierProducts(c *gin.Context) {
 	supplierID := c.Param("id")
 	role, _ := c.Get("role")
@@ -144,6 +287,7 @@ func GetSupplierProducts(c *gin.Context) {
 		return
 	}
 
+	fillAvailableQuantities(products)
 	c.JSON(http.StatusOK, products)
 }
 
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupSupplierTestDB(t *testing.T) (models.User, models.User) {
+	t.Helper()
+	store, supplier := setupOrderTestDB(t)
+	migrateTestModels(t, &models.Rating{}, &models.SupplierDeliverySettings{}, &models.DeliveryFeeBand{}, &models.DeliveryFeeZone{}, &models.ServiceAreaPlace{})
+	return store, supplier
+}
+
+func TestGetSuppliersNearSortAndRadius(t *testing.T) {
+	store, supplier := setupSupplierTestDB(t)
+
+	coords := func(lat, lng float64) (*float64, *float64) { return &lat, &lng }
+	supplier.Latitude, supplier.Longitude = coords(9.80, 126.16)
+	database.DB.Save(&supplier)
+	far := models.User{Email: "far@example.com", Password: "x", Name: "Far", Role: models.RoleSupplier}
+	far.Latitude, far.Longitude = coords(9.90, 126.05)
+	near := models.User{Email: "near@example.com", Password: "x", Name: "Near", Role: models.RoleSupplier}
+	near.Latitude, near.Longitude = coords(9.785, 126.157)
+	database.DB.Create(&far)
+	database.DB.Create(&near)
+
+	database.DB.Create(&models.Product{SupplierID: far.ID, Name: "Rice", SKU: "R-1", Price: 10})
+	database.DB.Create(&models.Product{SupplierID: far.ID, Name: "Oil", SKU: "O-1", Price: 10})
+	database.DB.Create(&models.Rating{OrderID: 1, RaterID: store.ID, RatedID: far.ID, Rating: 5})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", float64(store.ID))
+		c.Set("role", "store")
+	})
+	router.GET("/suppliers", GetSuppliers)
+
+	type supplierInfo struct {
+		ID            uint     `json:"id"`
+		ProductCount  int      `json:"product_count"`
+		AverageRating *float64 `json:"average_rating"`
+		DistanceKm    *float64 `json:"distance_km"`
+	}
+	get := func(query string) []supplierInfo {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/suppliers"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", query, w.Code, w.Body.String())
+		}
+		var infos []supplierInfo
+		json.Unmarshal(w.Body.Bytes(), &infos)
+		return infos
+	}
+
+	byDistance := get("?near=9.7833,126.1567&sort=distance")
+	if len(byDistance) != 3 || byDistance[0].ID != near.ID || byDistance[2].ID != far.ID || byDistance[0].DistanceKm == nil {
+		t.Fatalf("unexpected distance order: %+v", byDistance)
+	}
+
+	withinRadius := get("?near=9.7833,126.1567&radius_km=5")
+	if len(withinRadius) != 2 {
+		t.Fatalf("expected 2 suppliers within 5 km, got %d", len(withinRadius))
+	}
+
+	byRating := get("?sort=rating")
+	if byRating[0].ID != far.ID || byRating[0].ProductCount != 2 || byRating[0].AverageRating == nil || *byRating[0].AverageRating != 5 {
+		t.Fatalf("unexpected rating order: %+v", byRating[0])
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/suppliers?sort=distance", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 without a location, got %d", w.Code)
+	}
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// deliveryAverageSpeedKmh turns the straight-line distance to the store
+	// into an ETA. It is deliberately low to allow for island roads.
+	deliveryAverageSpeedKmh = 25.0
+	// trackingTrailLimit caps the breadcrumb trail returned to clients.
+	trackingTrailLimit = 500
+	// deliveryPingRetention is how long points are kept once an order is no
+	// longer in transit.
+	deliveryPingRetention = 24 * time.Hour
+)
+
+type orderTracking struct {
+	OrderID          uint                          `json:"order_id"`
+	Status           models.OrderStatus            `json:"status"`
+	Driver           *models.Employee              `json:"driver,omitempty"`
+	Latest           *models.DeliveryLocationPing  `json:"latest"`
+	Trail            []models.DeliveryLocationPing `json:"trail"`
+	DistanceKm       *float64                      `json:"distance_km"`
+	ETAMinutes       *int                          `json:"eta_minutes"`
+	EstimatedArrival *time.Time                    `json:"estimated_arrival"`
+}
+
+// RecordDeliveryLocation stores a GPS point for an in-transit order. The
+// supplier may post for any of its orders, a driver only for the orders
+// assigned to them.
+func RecordDeliveryLocation(c *gin.Context) {
+	id := c.Param("id")
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	role, _ := c.Get("role")
+	if role != "supplier" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only suppliers and their drivers can post locations"})
+		return
+	}
+	empCtx := getEmployeeContext(c)
+	driverID, isDriver := supplierDriverID(c, empCtx)
+	if !isDriver && !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+
+	var req struct {
+		Latitude   *float64   `json:"latitude" binding:"required"`
+		Longitude  *float64   `json:"longitude" binding:"required"`
+		Accuracy   *float64   `json:"accuracy"`
+		RecordedAt *time.Time `json:"recorded_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validCoordinates(*req.Latitude, *req.Longitude) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid coordinates"})
+		return
+	}
+	recordedAt := time.Now()
+	if req.RecordedAt != nil {
+		// Points queued while offline arrive late, but never from the future.
+		if req.RecordedAt.After(recordedAt.Add(5 * time.Minute)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "recorded_at cannot be in the future"})
+			return
+		}
+		recordedAt = *req.RecordedAt
+	}
+
+	var order models.Order
+	query := database.DB.Where("id = ? AND supplier_id = ?", id, userID)
+	if isDriver {
+		query = query.Where("driver_id = ?", driverID)
+	}
+	if err := query.First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch order"})
+		return
+	}
+	if order.Status != models.OrderStatusInTransit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "location can only be posted for in_transit orders"})
+		return
+	}
+
+	ping := models.DeliveryLocationPing{
+		OrderID:    order.ID,
+		EmployeeID: getOrderActor(c).EmployeeID,
+		Latitude:   *req.Latitude,
+		Longitude:  *req.Longitude,
+		Accuracy:   req.Accuracy,
+		RecordedAt: recordedAt,
+	}
+	if err := database.DB.Create(&ping).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record location"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ping)
+}
+
+// GetOrderTracking returns the latest position of an order, its breadcrumb
+// trail and, while it is in transit, an ETA to the store.
+func GetOrderTracking(c *gin.Context) {
+	id := c.Param("id")
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	role, _ := c.Get("role")
+	empCtx := getEmployeeContext(c)
+	driverID, isDriver := supplierDriverID(c, empCtx)
+	if !isDriver && !ensureEmployeePermission(c, empCtx.CanManageOrders, "orders") {
+		return
+	}
+
+	var order models.Order
+	query := database.DB.Preload("Store").Preload("Driver").Where("id = ?", id)
+	switch role {
+	case "supplier":
+		query = query.Where("supplier_id = ?", userID)
+		if isDriver {
+			query = query.Where("driver_id = ?", driverID)
+		}
+	case "store":
+		query = query.Where("store_id = ?", userID)
+	case "admin":
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		return
+	}
+	if err := query.First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		return
+	}
+
+	// The newest points are kept when the trail is capped, then put back in
+	// the order they were recorded.
+	var trail []models.DeliveryLocationPing
+	if err := database.DB.Where("order_id = ?", order.ID).Order("recorded_at DESC, id DESC").Limit(trackingTrailLimit).Find(&trail).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch tracking"})
+		return
+	}
+	for i, j := 0, len(trail)-1; i < j; i, j = i+1, j-1 {
+		trail[i], trail[j] = trail[j], trail[i]
+	}
+
+	tracking := orderTracking{
+		OrderID: order.ID,
+		Status:  order.Status,
+		Driver:  order.Driver,
+		Trail:   trail,
+	}
+	if len(trail) > 0 {
+		latest := trail[len(trail)-1]
+		tracking.Latest = &latest
+		if order.Status == models.OrderStatusInTransit && order.Store.Latitude != nil && order.Store.Longitude != nil {
+			distance := haversineKm(latest.Latitude, latest.Longitude, *order.Store.Latitude, *order.Store.Longitude)
+			eta := int(math.Ceil(distance / deliveryAverageSpeedKmh * 60))
+			arrival := time.Now().Add(time.Duration(eta) * time.Minute)
+			distance = roundTo2(distance)
+			tracking.DistanceKm = &distance
+			tracking.ETAMinutes = &eta
+			tracking.EstimatedArrival = &arrival
+		}
+	}
+
+	c.JSON(http.StatusOK, tracking)
+}
+
+// pruneDeliveryPings deletes the points of orders that are no longer in
+// transit once they are older than the retention period.
+func pruneDeliveryPings(now time.Time) {
+	inTransit := database.DB.Unscoped().Model(&models.Order{}).Select("id").Where("status = ?", models.OrderStatusInTransit)
+	result := database.DB.Where("recorded_at < ? AND order_id NOT IN (?)", now.Add(-deliveryPingRetention), inTransit).Delete(&models.DeliveryLocationPing{})
+	if result.Error != nil {
+		log.Printf("pruneDeliveryPings: failed to prune pings: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("pruneDeliveryPings: pruned %d pings", result.RowsAffected)
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"siargao-trading-road/database"
+	"siargao-trading-road/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupTrackingTestDB(t *testing.T) (models.User, models.User) {
+	t.Helper()
+	store, supplier := setupOrderTestDB(t)
+	migrateTestModels(t, &models.DeliveryLocationPing{})
+	return store, supplier
+}
+
+func buildTrackingRouter(user models.User) *gin.Engine {
+	r := buildOrderRouter(user)
+	r.POST("/orders/:id/location", RecordDeliveryLocation)
+	r.GET("/orders/:id/tracking", GetOrderTracking)
+	return r
+}
+
+func TestOrderTrackingReturnsTrailAndETA(t *testing.T) {
+	store, supplier := setupTrackingTestDB(t)
+	database.DB.Model(&store).Updates(map[string]interface{}{"latitude": 9.85, "longitude": 126.05})
+	order := createTestOrder(t, store, supplier, models.OrderStatusInTransit)
+	supplierRouter := buildTrackingRouter(supplier)
+
+	pingPath := fmt.Sprintf("/orders/%d/location", order.ID)
+	if w := doOrderRequest(buildTrackingRouter(store), http.MethodPost, pingPath, `{"latitude":9.75,"longitude":126.05}`); w.Code != http.StatusForbidden {
+		t.Fatalf("expected store to be refused, got %d", w.Code)
+	}
+	earlier := time.Now().Add(-10 * time.Minute).UTC().Format(time.RFC3339)
+	for _, body := range []string{
+		fmt.Sprintf(`{"latitude":9.70,"longitude":126.05,"recorded_at":%q}`, earlier),
+		`{"latitude":9.75,"longitude":126.05}`,
+	} {
+		if w := doOrderRequest(supplierRouter, http.MethodPost, pingPath, body); w.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	w := doOrderRequest(buildTrackingRouter(store), http.MethodGet, fmt.Sprintf("/orders/%d/tracking", order.ID), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var tracking orderTracking
+	json.Unmarshal(w.Body.Bytes(), &tracking)
+	if len(tracking.Trail) != 2 || tracking.Latest == nil || tracking.Latest.Latitude != 9.75 {
+		t.Fatalf("unexpected trail: %s", w.Body.String())
+	}
+	// 0.1 degrees of latitude is about 11.1 km, 27 minutes at 25 km/h.
+	if tracking.DistanceKm == nil || *tracking.DistanceKm < 11 || *tracking.DistanceKm > 11.2 || tracking.ETAMinutes == nil || *tracking.ETAMinutes != 27 {
+		t.Fatalf("unexpected ETA: %s", w.Body.String())
+	}
+
+	delivered := createTestOrder(t, store, supplier, models.OrderStatusDelivered)
+	database.DB.Create(&models.DeliveryLocationPing{OrderID: delivered.ID, Latitude: 9.8, Longitude: 126.1, RecordedAt: time.Now().Add(-2 * time.Hour)})
+	if w := doOrderRequest(supplierRouter, http.MethodPost, fmt.Sprintf("/orders/%d/location", delivered.ID), `{"latitude":9.8,"longitude":126.1}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected delivered order to be refused, got %d", w.Code)
+	}
+
+	pruneDeliveryPings(time.Now().Add(deliveryPingRetention))
+	var remaining []models.DeliveryLocationPing
+	database.DB.Find(&remaining)
+	if len(remaining) != 2 {
+		t.Fatalf("expected only the in-transit order's pings to remain, got %d", len(remaining))
+	}
+	for _, ping := range remaining {
+		if ping.OrderID != order.ID {
+			t.Fatalf("expected delivered order's pings to be pruned, found %+v", ping)
+		}
+	}
+}
@@ -34,6 +34,8 @@ func GetMe(c *gin.Context) {
 			"name":                user.Name,
 			"phone":               user.Phone,
 			"address":             user.Address,
+			"barangay":            user.Barangay,
+			"municipality":        user.Municipality,
 			"latitude":            user.Latitude,
 			"longitude":           user.Longitude,
 			"logo_url":            user.LogoURL,
@@ -67,6 +69,8 @@ func GetMe(c *gin.Context) {
 			"name":                user.Name,
 			"phone":               user.Phone,
 			"address":             user.Address,
+			"barangay":            user.Barangay,
+			"municipality":        user.Municipality,
 			"latitude":            user.Latitude,
 			"longitude":           user.Longitude,
 			"logo_url":            user.LogoURL,
@@ -113,6 +117,8 @@ func GetMe(c *gin.Context) {
 			"name":                user.Name,
 			"phone":               user.Phone,
 			"address":             user.Address,
+			"barangay":            user.Barangay,
+			"municipality":        user.Municipality,
 			"latitude":            user.Latitude,
 			"longitude":           user.Longitude,
 			"logo_url":            user.LogoURL,
@@ -148,6 +154,8 @@ func GetMe(c *gin.Context) {
 		"name":                user.Name,
 		"phone":               user.Phone,
 		"address":             user.Address,
+		"barangay":            user.Barangay,
+		"municipality":        user.Municipality,
 		"latitude":            user.Latitude,
 		"longitude":           user.Longitude,
 		"logo_url":            user.LogoURL,
@@ -189,6 +197,8 @@ func UpdateMe(c *gin.Context) {
 		Name             string   `json:"name"`
 		Phone            string   `json:"phone"`
 		Address          string   `json:"address"`
+		Barangay         string   `json:"barangay"`
+		Municipality     string   `json:"municipality"`
 		Latitude         *float64 `json:"latitude"`
 		Longitude        *float64 `json:"longitude"`
 		LogoURL          string   `json:"logo_url"`
@@ -220,6 +230,12 @@ func UpdateMe(c *gin.Context) {
 	if req.Address != "" {
 		user.Address = req.Address
 	}
+	if req.Barangay != "" {
+		user.Barangay = req.Barangay
+	}
+	if req.Municipality != "" {
+		user.Municipality = req.Municipality
+	}
 	if req.Latitude != nil {
 		user.Latitude = req.Latitude
 	}
@@ -412,15 +428,23 @@ func GetMyAnalytics(c *gin.Context) {
 		averageRating = &ratingStats.AverageRating
 	}
 
+	returnsColumn := "store_id"
+	if user.Role == models.RoleSupplier {
+		returnsColumn = "supplier_id"
+	}
+	returns := returnAnalytics(returnsColumn, user.ID)
+
 	c.JSON(http.StatusOK, gin.H{
 		"total_orders":          totalOrders,
 		"total_earnings":        totalEarnings,
+		"net_earnings":          totalEarnings - returns["total_credited"].(float64),
 		"total_products_bought": totalProductsBought,
 		"orders":                orders,
 		"products_bought":       productsBought,
 		"recent_orders":         recentOrders,
 		"average_rating":        averageRating,
 		"rating_count":          ratingStats.RatingCount,
+		"returns":               returns,
 	})
 }
 
@@ -594,15 +618,23 @@ func GetUserAnalytics(c *gin.Context) {
 		averageRating = &ratingStats.AverageRating
 	}
 
+	returnsColumn := "store_id"
+	if user.Role == models.RoleSupplier {
+		returnsColumn = "supplier_id"
+	}
+	returns := returnAnalytics(returnsColumn, user.ID)
+
 	c.JSON(http.StatusOK, gin.H{
 		"total_orders":          totalOrders,
 		"total_earnings":        totalEarnings,
+		"net_earnings":          totalEarnings - returns["total_credited"].(float64),
 		"total_products_bought": totalProductsBought,
 		"orders":                orders,
 		"products_bought":       productsBought,
 		"recent_orders":         recentOrders,
 		"average_rating":        averageRating,
 		"rating_count":          ratingStats.RatingCount,
+		"returns":               returns,
 	})
 }
 
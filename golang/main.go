@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 
 	"siargao-trading-road/config"
 	"siargao-trading-road/database"
+	"siargao-trading-road/handlers"
 	"siargao-trading-road/middleware"
 	"siargao-trading-road/routes"
+	"siargao-trading-road/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -36,6 +39,8 @@ func main() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	handlers.StartBackgroundJobs(context.Background(), services.NewEmailService(cfg))
+
 	r := gin.Default()
 
 	r.Use(middleware.RecoveryMiddleware())
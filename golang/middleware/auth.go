@@ -74,6 +74,9 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 		if val, ok := claims["can_rate"]; ok {
 			c.Set("can_rate", val)
 		}
+		if val, ok := claims["is_driver"]; ok {
+			c.Set("is_driver", val)
+		}
 
 		c.Next()
 	}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// CreditAccount lets a store buy from a supplier on payment terms. Orders
+// paid by credit fall due TermsDays after they are submitted, and the
+// store's unpaid credit balance may not go over CreditLimit.
+type CreditAccount struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	SupplierID  uint      `gorm:"not null;uniqueIndex:idx_credit_account_supplier_store,priority:1" json:"supplier_id"`
+	Supplier    User      `gorm:"foreignKey:SupplierID" json:"supplier,omitempty"`
+	StoreID     uint      `gorm:"not null;uniqueIndex:idx_credit_account_supplier_store,priority:2;index" json:"store_id"`
+	Store       User      `gorm:"foreignKey:StoreID" json:"store,omitempty"`
+	CreditLimit float64   `gorm:"type:decimal(10,2);not null" json:"credit_limit"`
+	TermsDays   int       `gorm:"not null" json:"terms_days"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
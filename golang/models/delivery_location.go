@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// DeliveryLocationPing is a GPS point posted while an order is in transit.
+type DeliveryLocationPing struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	OrderID    uint      `gorm:"not null;index:idx_delivery_ping_order_time,priority:1" json:"order_id"`
+	EmployeeID *uint     `json:"employee_id,omitempty"`
+	Latitude   float64   `gorm:"type:decimal(10,8);not null" json:"latitude"`
+	Longitude  float64   `gorm:"type:decimal(11,8);not null" json:"longitude"`
+	Accuracy   *float64  `gorm:"type:decimal(10,2)" json:"accuracy,omitempty"` // Metres, as reported by the device
+	RecordedAt time.Time `gorm:"not null;index:idx_delivery_ping_order_time,priority:2" json:"recorded_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
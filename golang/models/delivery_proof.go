@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// DeliveryProof is captured at the door when an order is handed over. An
+// order can only be marked delivered together with its proof.
+type DeliveryProof struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	OrderID      uint      `gorm:"not null;uniqueIndex" json:"order_id"`
+	ReceiverName string    `gorm:"type:varchar(255);not null" json:"receiver_name"`
+	SignatureURL string    `gorm:"type:varchar(500);not null" json:"signature_url"`
+	PhotoURLs    []string  `gorm:"type:text;serializer:json" json:"photo_urls"`
+	Latitude     *float64  `gorm:"type:decimal(10,8)" json:"latitude,omitempty"`
+	Longitude    *float64  `gorm:"type:decimal(11,8)" json:"longitude,omitempty"`
+	DeliveredAt  time.Time `gorm:"not null" json:"delivered_at"`
+	RecordedByID *uint     `json:"recorded_by_id,omitempty"`
+	EmployeeID   *uint     `json:"employee_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DeliverySlot is a weekly delivery window a supplier offers. Capacity is
+// the number of orders the supplier can deliver in the window on one date.
+type DeliverySlot struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	SupplierID uint           `gorm:"not null;index" json:"supplier_id"`
+	Weekday    int            `gorm:"not null" json:"weekday"`                    // 0=Sunday, 1=Monday, etc.
+	StartTime  string         `gorm:"type:varchar(5);not null" json:"start_time"` // HH:MM, Philippine time
+	EndTime    string         `gorm:"type:varchar(5);not null" json:"end_time"`
+	Capacity   int            `gorm:"not null" json:"capacity"`
+	Active     bool           `gorm:"default:true" json:"active"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+}
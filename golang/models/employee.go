@@ -6,6 +6,10 @@ import (
 	"gorm.io/gorm"
 )
 
+// EmployeeRoleDriver marks a supplier employee who delivers orders. Drivers
+// only see and update the orders assigned to them.
+const EmployeeRoleDriver = "driver"
+
 // Employee accounts are owned by a supplier or store user.
 type Employee struct {
 	ID                 uint           `gorm:"primaryKey" json:"id"`
@@ -30,3 +34,7 @@ type Employee struct {
 func (Employee) TableName() string {
 	return "employees"
 }
+
+func (e Employee) IsDriver() bool {
+	return e.Role == EmployeeRoleDriver
+}
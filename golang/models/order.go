@@ -21,6 +21,7 @@ type PaymentMethod string
 const (
 	PaymentMethodCashOnDelivery PaymentMethod = "cash_on_delivery"
 	PaymentMethodGCash          PaymentMethod = "gcash"
+	PaymentMethodCredit         PaymentMethod = "credit" // On the store's CreditAccount terms
 )
 
 type DeliveryOption string
@@ -33,43 +34,102 @@ const (
 type PaymentStatus string
 
 const (
-	PaymentStatusPending PaymentStatus = "pending"
-	PaymentStatusPaid    PaymentStatus = "paid"
-	PaymentStatusFailed  PaymentStatus = "failed"
+	PaymentStatusUnpaid   PaymentStatus = "unpaid"
+	PaymentStatusPartial  PaymentStatus = "partial"
+	PaymentStatusPaid     PaymentStatus = "paid"
+	PaymentStatusOverpaid PaymentStatus = "overpaid"
+	PaymentStatusFailed   PaymentStatus = "failed" // Nothing paid and the latest GCash proof was rejected
+)
+
+// OrderItemFulfilment records how a supplier filled one line of an order.
+type OrderItemFulfilment string
+
+const (
+	ItemFulfilmentPending      OrderItemFulfilment = "pending"
+	ItemFulfilmentFulfilled    OrderItemFulfilment = "fulfilled"
+	ItemFulfilmentShortShipped OrderItemFulfilment = "short_shipped"
+	ItemFulfilmentSubstituted  OrderItemFulfilment = "substituted"
 )
 
 type Order struct {
-	ID              uint           `gorm:"primaryKey" json:"id"`
-	StoreID         uint           `gorm:"not null;index" json:"store_id"`
-	Store           User           `gorm:"foreignKey:StoreID;references:ID" json:"store"`
-	SupplierID      uint           `gorm:"not null;index" json:"supplier_id"`
-	Supplier        User           `gorm:"foreignKey:SupplierID;references:ID" json:"supplier,omitempty"`
-	Status          OrderStatus    `gorm:"type:varchar(20);not null;default:'draft'" json:"status"`
-	TotalAmount     float64        `gorm:"type:decimal(10,2);default:0" json:"total_amount"`
-	PaymentMethod   PaymentMethod  `gorm:"type:varchar(20)" json:"payment_method"`
-	PaymentStatus   PaymentStatus  `gorm:"type:varchar(20);default:'pending'" json:"payment_status"`
-	PaymentProofURL string         `gorm:"type:varchar(500)" json:"payment_proof_url,omitempty"`
-	InvoiceURL      string         `gorm:"type:varchar(500)" json:"invoice_url,omitempty"`
-	DeliveryOption  DeliveryOption `gorm:"type:varchar(20)" json:"delivery_option"`
-	DeliveryFee     float64        `gorm:"type:decimal(10,2);default:0" json:"delivery_fee"`
-	Distance        float64        `gorm:"type:decimal(10,2);default:0" json:"distance"`
-	ShippingAddress string         `gorm:"type:text" json:"shipping_address"`
-	Notes           string         `gorm:"type:text" json:"notes"`
-	OrderItems      []OrderItem    `gorm:"foreignKey:OrderID" json:"order_items"`
-	CreatedAt       time.Time      `json:"created_at"`
-	UpdatedAt       time.Time      `json:"updated_at"`
-	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                   uint                  `gorm:"primaryKey" json:"id"`
+	StoreID              uint                  `gorm:"not null;index" json:"store_id"`
+	Store                User                  `gorm:"foreignKey:StoreID;references:ID" json:"store"`
+	SupplierID           uint                  `gorm:"not null;index" json:"supplier_id"`
+	Supplier             User                  `gorm:"foreignKey:SupplierID;references:ID" json:"supplier,omitempty"`
+	Status               OrderStatus           `gorm:"type:varchar(20);not null;default:'draft'" json:"status"`
+	TotalAmount          float64               `gorm:"type:decimal(10,2);default:0" json:"total_amount"`
+	PaymentMethod        PaymentMethod         `gorm:"type:varchar(20)" json:"payment_method"`
+	PaymentStatus        PaymentStatus         `gorm:"type:varchar(20);default:'unpaid'" json:"payment_status"` // Derived from the Payments ledger
+	AmountPaid           float64               `gorm:"type:decimal(10,2);not null;default:0" json:"amount_paid"`
+	PaymentProofURL      string                `gorm:"type:varchar(500)" json:"payment_proof_url,omitempty"`
+	InvoiceURL           string                `gorm:"type:varchar(500)" json:"invoice_url,omitempty"`
+	DeliveryOption       DeliveryOption        `gorm:"type:varchar(20)" json:"delivery_option"`
+	DeliveryFee          float64               `gorm:"type:decimal(10,2);default:0" json:"delivery_fee"`
+	Distance             float64               `gorm:"type:decimal(10,2);default:0" json:"distance"`
+	ShippingAddress      string                `gorm:"type:text" json:"shipping_address"`
+	Notes                string                `gorm:"type:text" json:"notes"`
+	CancellationReason   string                `gorm:"type:text" json:"cancellation_reason,omitempty"`
+	StandingOrderID      *uint                 `gorm:"index" json:"standing_order_id,omitempty"`
+	QuoteID              *uint                 `gorm:"index" json:"quote_id,omitempty"`
+	DeliveryDate         *time.Time            `gorm:"type:date;index" json:"delivery_date,omitempty"`
+	DueDate              *time.Time            `gorm:"type:date;index" json:"due_date,omitempty"` // Set for credit orders
+	LastReminderAt       *time.Time            `json:"last_reminder_at,omitempty"`
+	DeliverySlotID       *uint                 `gorm:"index" json:"delivery_slot_id,omitempty"`
+	DeliverySlot         *DeliverySlot         `gorm:"foreignKey:DeliverySlotID" json:"delivery_slot,omitempty"`
+	DriverID             *uint                 `gorm:"index" json:"driver_id,omitempty"`
+	ShipmentID           *uint                 `gorm:"index" json:"shipment_id,omitempty"`
+	Driver               *Employee             `gorm:"foreignKey:DriverID" json:"driver,omitempty"`
+	OrderItems           []OrderItem           `gorm:"foreignKey:OrderID" json:"order_items"`
+	CreditMemos          []CreditMemo          `gorm:"foreignKey:OrderID" json:"credit_memos,omitempty"`
+	DeliveryProof        *DeliveryProof        `gorm:"foreignKey:OrderID" json:"delivery_proof,omitempty"`
+	PaymentVerifications []PaymentVerification `gorm:"foreignKey:OrderID" json:"payment_verifications,omitempty"`
+	Payments             []Payment             `gorm:"foreignKey:OrderID" json:"payments,omitempty"`
+	CreatedAt            time.Time             `json:"created_at"`
+	UpdatedAt            time.Time             `json:"updated_at"`
+	DeletedAt            gorm.DeletedAt        `gorm:"index" json:"-"`
 }
 
 type OrderItem struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	OrderID   uint      `gorm:"not null;index" json:"order_id"`
-	Order     Order     `gorm:"foreignKey:OrderID" json:"-"`
-	ProductID uint      `gorm:"not null;index" json:"product_id"`
-	Product   Product   `gorm:"foreignKey:ProductID" json:"product"`
-	Quantity  int       `gorm:"not null" json:"quantity"`
-	UnitPrice float64   `gorm:"type:decimal(10,2);not null" json:"unit_price"`
-	Subtotal  float64   `gorm:"type:decimal(10,2);not null" json:"subtotal"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                  uint                `gorm:"primaryKey" json:"id"`
+	OrderID             uint                `gorm:"not null;index" json:"order_id"`
+	Order               Order               `gorm:"foreignKey:OrderID" json:"-"`
+	ProductID           uint                `gorm:"not null;index" json:"product_id"`
+	Product             Product             `gorm:"foreignKey:ProductID" json:"product"`
+	Quantity            int                 `gorm:"not null" json:"quantity"`
+	UnitPrice           float64             `gorm:"type:decimal(10,2);not null" json:"unit_price"`
+	Subtotal            float64             `gorm:"type:decimal(10,2);not null" json:"subtotal"`
+	FulfilmentStatus    OrderItemFulfilment `gorm:"type:varchar(20);not null;default:'pending'" json:"fulfilment_status"` // Set by the supplier while preparing; Subtotal follows what is shipped
+	ShippedQuantity     *int                `json:"shipped_quantity,omitempty"`
+	SubstituteProductID *uint               `gorm:"index" json:"substitute_product_id,omitempty"`
+	SubstituteProduct   *Product            `gorm:"foreignKey:SubstituteProductID" json:"substitute_product,omitempty"`
+	SubstituteUnitPrice *float64            `gorm:"type:decimal(10,2)" json:"substitute_unit_price,omitempty"`
+	FulfilmentNote      string              `gorm:"type:text" json:"fulfilment_note,omitempty"`
+	CreatedAt           time.Time           `json:"created_at"`
+	UpdatedAt           time.Time           `json:"updated_at"`
+}
+
+// ShippedProductID is the product actually sent for this line.
+func (i OrderItem) ShippedProductID() uint {
+	if i.FulfilmentStatus == ItemFulfilmentSubstituted && i.SubstituteProductID != nil {
+		return *i.SubstituteProductID
+	}
+	return i.ProductID
+}
+
+// BilledQuantity is the quantity the store pays for: the shipped quantity
+// once the line is short-shipped or substituted, the ordered one otherwise.
+func (i OrderItem) BilledQuantity() int {
+	if i.ShippedQuantity != nil && (i.FulfilmentStatus == ItemFulfilmentShortShipped || i.FulfilmentStatus == ItemFulfilmentSubstituted) {
+		return *i.ShippedQuantity
+	}
+	return i.Quantity
+}
+
+// BilledUnitPrice is the unit price charged for what is shipped.
+func (i OrderItem) BilledUnitPrice() float64 {
+	if i.FulfilmentStatus == ItemFulfilmentSubstituted && i.SubstituteUnitPrice != nil {
+		return *i.SubstituteUnitPrice
+	}
+	return i.UnitPrice
 }
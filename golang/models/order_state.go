@@ -0,0 +1,113 @@
+package models
+
+// OrderSideEffect names work that has to happen when an order changes status.
+type OrderSideEffect string
+
+const (
+	OrderEffectNotifySubmitted    OrderSideEffect = "notify_submitted"
+	OrderEffectNotifyStatusChange OrderSideEffect = "notify_status_change"
+	OrderEffectNotifyDelivered    OrderSideEffect = "notify_delivered"
+	OrderEffectRestoreStock       OrderSideEffect = "restore_stock"
+	OrderEffectReleaseReservation OrderSideEffect = "release_reservation"
+)
+
+// OrderTransition describes one legal move in the order lifecycle, who may
+// make it, and what runs afterwards. Internal transitions are only reachable
+// through a dedicated flow (e.g. submitting a draft), not the generic status
+// update endpoint.
+type OrderTransition struct {
+	From     OrderStatus
+	To       OrderStatus
+	Roles    []UserRole
+	Effects  []OrderSideEffect
+	Internal bool
+}
+
+var orderTransitions = []OrderTransition{
+	{
+		From:     OrderStatusDraft,
+		To:       OrderStatusPreparing,
+		Roles:    []UserRole{RoleStore},
+		Effects:  []OrderSideEffect{OrderEffectNotifySubmitted},
+		Internal: true,
+	},
+	{
+		From:    OrderStatusDraft,
+		To:      OrderStatusCancelled,
+		Roles:   []UserRole{RoleStore, RoleAdmin},
+		Effects: []OrderSideEffect{OrderEffectReleaseReservation},
+	},
+	{
+		From:    OrderStatusPreparing,
+		To:      OrderStatusInTransit,
+		Roles:   []UserRole{RoleSupplier, RoleAdmin},
+		Effects: []OrderSideEffect{OrderEffectNotifyStatusChange},
+	},
+	{
+		From:    OrderStatusPreparing,
+		To:      OrderStatusCancelled,
+		Roles:   []UserRole{RoleStore, RoleSupplier, RoleAdmin},
+		Effects: []OrderSideEffect{OrderEffectRestoreStock, OrderEffectNotifyStatusChange},
+	},
+	{
+		// Delivering needs proof of delivery, so it goes through the
+		// delivery confirmation endpoint.
+		From:     OrderStatusInTransit,
+		To:       OrderStatusDelivered,
+		Roles:    []UserRole{RoleSupplier, RoleAdmin},
+		Effects:  []OrderSideEffect{OrderEffectNotifyDelivered},
+		Internal: true,
+	},
+	{
+		From:    OrderStatusInTransit,
+		To:      OrderStatusCancelled,
+		Roles:   []UserRole{RoleSupplier, RoleAdmin},
+		Effects: []OrderSideEffect{OrderEffectRestoreStock, OrderEffectNotifyStatusChange},
+	},
+}
+
+// OrderTransitions returns the full transition table.
+func OrderTransitions() []OrderTransition {
+	return orderTransitions
+}
+
+// FindOrderTransition looks up the transition from one status to another.
+func FindOrderTransition(from, to OrderStatus) (OrderTransition, bool) {
+	for _, t := range orderTransitions {
+		if t.From == from && t.To == to {
+			return t, true
+		}
+	}
+	return OrderTransition{}, false
+}
+
+func (t OrderTransition) AllowsRole(role UserRole) bool {
+	for _, r := range t.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (t OrderTransition) HasEffect(effect OrderSideEffect) bool {
+	for _, e := range t.Effects {
+		if e == effect {
+			return true
+		}
+	}
+	return false
+}
+
+func (s OrderStatus) IsValid() bool {
+	switch s {
+	case OrderStatusDraft, OrderStatusPreparing, OrderStatusInTransit, OrderStatusDelivered, OrderStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// IsTerminal reports whether no further transitions are possible.
+func (s OrderStatus) IsTerminal() bool {
+	return s == OrderStatusDelivered || s == OrderStatusCancelled
+}
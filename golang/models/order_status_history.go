@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// OrderStatusHistory records every status change an order goes through.
+type OrderStatusHistory struct {
+	ID         uint        `gorm:"primaryKey" json:"id"`
+	OrderID    uint        `gorm:"not null;index" json:"order_id"`
+	FromStatus OrderStatus `gorm:"type:varchar(20)" json:"from_status"`
+	ToStatus   OrderStatus `gorm:"type:varchar(20);not null" json:"to_status"`
+	ActorID    *uint       `gorm:"index" json:"actor_id,omitempty"`
+	Actor      *User       `gorm:"foreignKey:ActorID;references:ID" json:"actor,omitempty"`
+	ActorRole  string      `gorm:"type:varchar(20)" json:"actor_role"`
+	EmployeeID *uint       `gorm:"index" json:"employee_id,omitempty"`
+	Employee   *Employee   `gorm:"foreignKey:EmployeeID;references:ID" json:"employee,omitempty"`
+	Reason     string      `gorm:"type:text" json:"reason,omitempty"`
+	CreatedAt  time.Time   `gorm:"index" json:"created_at"`
+}
+
+func (OrderStatusHistory) TableName() string {
+	return "order_status_history"
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// Payment is one dated entry in an order's payment ledger. Refunds and
+// reversals are negative entries, so the order's amount paid is always the
+// sum of its entries.
+type Payment struct {
+	ID           uint          `gorm:"primaryKey" json:"id"`
+	OrderID      uint          `gorm:"not null;index" json:"order_id"`
+	Method       PaymentMethod `gorm:"type:varchar(20);not null" json:"method"`
+	Amount       float64       `gorm:"type:decimal(10,2);not null" json:"amount"`
+	Reference    string        `gorm:"type:varchar(100)" json:"reference,omitempty"`
+	ExternalID   *string       `gorm:"type:varchar(150);uniqueIndex" json:"external_id,omitempty"` // Provider and payment or refund id, so a gateway event is only recorded once
+	Note         string        `gorm:"type:text" json:"note,omitempty"`
+	PaidAt       time.Time     `gorm:"not null" json:"paid_at"`
+	RecordedByID *uint         `json:"recorded_by_id,omitempty"`
+	RecordedBy   *User         `gorm:"foreignKey:RecordedByID" json:"recorded_by,omitempty"`
+	EmployeeID   *uint         `json:"employee_id,omitempty"`
+	Employee     *Employee     `gorm:"foreignKey:EmployeeID" json:"employee,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// PaymentStatusFor derives an order's payment status from its total and the
+// sum of its ledger. Amounts within half a centavo count as equal. rejected
+// is whether the order's latest payment verification was a rejection.
+func PaymentStatusFor(total, paid float64, rejected bool) PaymentStatus {
+	switch {
+	case paid < 0.005 && rejected:
+		return PaymentStatusFailed
+	case paid < 0.005:
+		return PaymentStatusUnpaid
+	case math.Abs(paid-total) < 0.005:
+		return PaymentStatusPaid
+	case paid < total:
+		return PaymentStatusPartial
+	default:
+		return PaymentStatusOverpaid
+	}
+}
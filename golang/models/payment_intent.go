@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// PaymentIntent is a request to an online payment provider to collect an
+// amount for an order. The ledger entry is written when the provider
+// reports the payment succeeded.
+type PaymentIntent struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	OrderID          uint      `gorm:"not null;index" json:"order_id"`
+	Provider         string    `gorm:"type:varchar(30);not null;uniqueIndex:idx_payment_intent_provider,priority:1" json:"provider"`
+	ProviderIntentID string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_payment_intent_provider,priority:2" json:"provider_intent_id"`
+	Amount           float64   `gorm:"type:decimal(10,2);not null" json:"amount"`
+	Status           string    `gorm:"type:varchar(20);not null" json:"status"`
+	CheckoutURL      string    `gorm:"type:varchar(500)" json:"checkout_url,omitempty"`
+	CreatedByID      *uint     `json:"created_by_id,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+type PaymentDecision string
+
+const (
+	PaymentDecisionApproved PaymentDecision = "approved"
+	PaymentDecisionRejected PaymentDecision = "rejected"
+)
+
+// PaymentVerification records what a supplier checked before accepting or
+// rejecting a GCash payment. Every decision is kept, so a reference may
+// appear more than once on an order; PaymentReference ties it to that order.
+type PaymentVerification struct {
+	ID              uint            `gorm:"primaryKey" json:"id"`
+	OrderID         uint            `gorm:"not null;index" json:"order_id"`
+	ReferenceNumber string          `gorm:"type:varchar(50);not null;index:idx_payment_verifications_reference" json:"reference_number"`
+	AmountPaid      float64         `gorm:"type:decimal(10,2);not null" json:"amount_paid"`
+	SenderNumber    string          `gorm:"type:varchar(30)" json:"sender_number"`
+	ScreenshotURL   string          `gorm:"type:varchar(500)" json:"screenshot_url"`
+	Decision        PaymentDecision `gorm:"type:varchar(20);not null" json:"decision"`
+	Reason          string          `gorm:"type:text" json:"reason,omitempty"`
+	VerifiedByID    *uint           `json:"verified_by_id,omitempty"`
+	VerifiedBy      *User           `gorm:"foreignKey:VerifiedByID" json:"verified_by,omitempty"`
+	EmployeeID      *uint           `json:"employee_id,omitempty"`
+	Employee        *Employee       `gorm:"foreignKey:EmployeeID" json:"employee,omitempty"`
+	VerifiedAt      time.Time       `gorm:"not null" json:"verified_at"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+// PaymentReference claims a GCash reference number for the first order it
+// was verified on, so a screenshot reused on another order is caught.
+type PaymentReference struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	ReferenceNumber string    `gorm:"type:varchar(50);not null;uniqueIndex" json:"reference_number"`
+	OrderID         uint      `gorm:"not null;index" json:"order_id"`
+	CreatedAt       time.Time `json:"created_at"`
+}
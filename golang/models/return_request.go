@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+)
+
+type ReturnStatus string
+
+const (
+	ReturnStatusPending  ReturnStatus = "pending"
+	ReturnStatusApproved ReturnStatus = "approved"
+	ReturnStatusRejected ReturnStatus = "rejected"
+)
+
+// ReturnRequest is a store's claim against a delivered order for damaged or
+// wrong goods. The supplier approves or rejects it as a whole.
+type ReturnRequest struct {
+	ID              uint         `gorm:"primaryKey" json:"id"`
+	OrderID         uint         `gorm:"not null;index" json:"order_id"`
+	Order           *Order       `gorm:"foreignKey:OrderID" json:"order,omitempty"`
+	StoreID         uint         `gorm:"not null;index" json:"store_id"`
+	Store           User         `gorm:"foreignKey:StoreID" json:"store,omitempty"`
+	SupplierID      uint         `gorm:"not null;index" json:"supplier_id"`
+	Supplier        User         `gorm:"foreignKey:SupplierID" json:"supplier,omitempty"`
+	Status          ReturnStatus `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	Notes           string       `gorm:"type:text" json:"notes,omitempty"`
+	RejectionReason string       `gorm:"type:text" json:"rejection_reason,omitempty"`
+	ReviewedByID    *uint        `json:"reviewed_by_id,omitempty"`
+	ReviewedAt      *time.Time   `json:"reviewed_at,omitempty"`
+	Items           []ReturnItem `gorm:"foreignKey:ReturnRequestID" json:"items"`
+	CreditMemo      *CreditMemo  `gorm:"foreignKey:ReturnRequestID" json:"credit_memo,omitempty"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+}
+
+// ReturnItem is one order line being returned. ProductID is the product that
+// was shipped, which differs from the ordered one when it was substituted.
+type ReturnItem struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	ReturnRequestID uint      `gorm:"not null;index" json:"return_request_id"`
+	OrderItemID     uint      `gorm:"not null;index" json:"order_item_id"`
+	ProductID       uint      `gorm:"not null;index" json:"product_id"`
+	Product         Product   `gorm:"foreignKey:ProductID" json:"product"`
+	Quantity        int       `gorm:"not null" json:"quantity"`
+	UnitPrice       float64   `gorm:"type:decimal(10,2);not null" json:"unit_price"`
+	Reason          string    `gorm:"type:text;not null" json:"reason"`
+	PhotoURLs       []string  `gorm:"type:text;serializer:json" json:"photo_urls"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// CreditMemo is issued when a return is approved; Amount is owed back to the
+// store against the original order.
+type CreditMemo struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	MemoNumber      string    `gorm:"type:varchar(30);uniqueIndex" json:"memo_number"`
+	ReturnRequestID uint      `gorm:"not null;uniqueIndex" json:"return_request_id"`
+	OrderID         uint      `gorm:"not null;index" json:"order_id"`
+	StoreID         uint      `gorm:"not null;index" json:"store_id"`
+	SupplierID      uint      `gorm:"not null;index" json:"supplier_id"`
+	Amount          float64   `gorm:"type:decimal(10,2);not null" json:"amount"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
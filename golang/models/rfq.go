@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+)
+
+type RFQStatus string
+
+const (
+	RFQStatusOpen     RFQStatus = "open"
+	RFQStatusAccepted RFQStatus = "accepted"
+	RFQStatusClosed   RFQStatus = "closed"
+)
+
+type QuoteStatus string
+
+const (
+	QuoteStatusSubmitted QuoteStatus = "submitted"
+	QuoteStatusAccepted  QuoteStatus = "accepted"
+	QuoteStatusRejected  QuoteStatus = "rejected"
+)
+
+// RFQ is a store's request for quote, sent to one or more suppliers before a
+// bulk or seasonal order. Lines describe what is wanted; each supplier maps
+// them to its own products when it quotes.
+type RFQ struct {
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	StoreID         uint           `gorm:"not null;index" json:"store_id"`
+	Store           User           `gorm:"foreignKey:StoreID;references:ID" json:"store,omitempty"`
+	Title           string         `gorm:"type:varchar(255);not null" json:"title"`
+	Notes           string         `gorm:"type:text" json:"notes"`
+	Status          RFQStatus      `gorm:"type:varchar(20);not null;default:'open';index" json:"status"`
+	AcceptedQuoteID *uint          `json:"accepted_quote_id,omitempty"`
+	OrderID         *uint          `json:"order_id,omitempty"`
+	Lines           []RFQLine      `gorm:"foreignKey:RFQID" json:"lines"`
+	Recipients      []RFQRecipient `gorm:"foreignKey:RFQID" json:"recipients"`
+	Quotes          []Quote        `gorm:"foreignKey:RFQID" json:"quotes"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+func (RFQ) TableName() string {
+	return "rfqs"
+}
+
+type RFQLine struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	RFQID       uint      `gorm:"column:rfq_id;not null;index" json:"rfq_id"`
+	Description string    `gorm:"type:varchar(255);not null" json:"description"`
+	Quantity    int       `gorm:"not null" json:"quantity"`
+	Unit        string    `gorm:"type:varchar(50)" json:"unit"`
+	ProductID   *uint     `json:"product_id,omitempty"` // Optional hint at a product the store already knows
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (RFQLine) TableName() string {
+	return "rfq_lines"
+}
+
+// RFQRecipient is a supplier the RFQ was sent to; only recipients may quote.
+type RFQRecipient struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	RFQID      uint      `gorm:"column:rfq_id;not null;uniqueIndex:idx_rfq_recipient" json:"rfq_id"`
+	SupplierID uint      `gorm:"not null;uniqueIndex:idx_rfq_recipient;index" json:"supplier_id"`
+	Supplier   User      `gorm:"foreignKey:SupplierID;references:ID" json:"supplier,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (RFQRecipient) TableName() string {
+	return "rfq_recipients"
+}
+
+// Quote is one supplier's answer to an RFQ. Accepting it places an order at
+// the quoted prices, provided it has not expired.
+type Quote struct {
+	ID         uint        `gorm:"primaryKey" json:"id"`
+	RFQID      uint        `gorm:"column:rfq_id;not null;uniqueIndex:idx_rfq_quote_supplier" json:"rfq_id"`
+	SupplierID uint        `gorm:"not null;uniqueIndex:idx_rfq_quote_supplier;index" json:"supplier_id"`
+	Supplier   User        `gorm:"foreignKey:SupplierID;references:ID" json:"supplier,omitempty"`
+	Status     QuoteStatus `gorm:"type:varchar(20);not null;default:'submitted'" json:"status"`
+	ExpiresAt  time.Time   `gorm:"not null" json:"expires_at"`
+	Notes      string      `gorm:"type:text" json:"notes"`
+	Total      float64     `gorm:"type:decimal(10,2);default:0" json:"total"`
+	Lines      []QuoteLine `gorm:"foreignKey:QuoteID" json:"lines"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+type QuoteLine struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	QuoteID   uint      `gorm:"not null;index" json:"quote_id"`
+	RFQLineID uint      `gorm:"column:rfq_line_id;not null" json:"rfq_line_id"`
+	ProductID uint      `gorm:"not null" json:"product_id"`
+	Product   Product   `gorm:"foreignKey:ProductID" json:"product"`
+	Quantity  int       `gorm:"not null" json:"quantity"`
+	UnitPrice float64   `gorm:"type:decimal(10,2);not null" json:"unit_price"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
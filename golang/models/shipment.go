@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+type ShipmentStatus string
+
+const (
+	ShipmentStatusPlanned   ShipmentStatus = "planned"
+	ShipmentStatusInTransit ShipmentStatus = "in_transit"
+	ShipmentStatusArrived   ShipmentStatus = "arrived"
+	ShipmentStatusCancelled ShipmentStatus = "cancelled"
+)
+
+// Shipment consolidates several of a supplier's orders on one carrier, such
+// as the mainland ferry, under a single manifest. Its orders go in transit
+// together when it departs.
+type Shipment struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	SupplierID     uint           `gorm:"not null;index" json:"supplier_id"`
+	Supplier       User           `gorm:"foreignKey:SupplierID;references:ID" json:"supplier,omitempty"`
+	ManifestNumber string         `gorm:"type:varchar(30);uniqueIndex" json:"manifest_number"`
+	CarrierName    string         `gorm:"type:varchar(255);not null" json:"carrier_name"`
+	VesselName     string         `gorm:"type:varchar(255)" json:"vessel_name"`
+	DepartureDate  *time.Time     `gorm:"type:date" json:"departure_date,omitempty"`
+	ArrivalDate    *time.Time     `gorm:"type:date" json:"arrival_date,omitempty"`
+	Status         ShipmentStatus `gorm:"type:varchar(20);not null;default:'planned';index" json:"status"`
+	Notes          string         `gorm:"type:text" json:"notes"`
+	DepartedAt     *time.Time     `json:"departed_at,omitempty"`
+	ArrivedAt      *time.Time     `json:"arrived_at,omitempty"`
+	Orders         []Order        `gorm:"foreignKey:ShipmentID" json:"orders"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type StandingOrderRecurrence string
+
+const (
+	// StandingOrderWeekly runs on the weekdays listed in Weekdays.
+	StandingOrderWeekly StandingOrderRecurrence = "weekly"
+	// StandingOrderInterval runs every IntervalDays days.
+	StandingOrderInterval StandingOrderRecurrence = "interval"
+)
+
+// ClosedDayPolicy decides what happens when a run falls on a day the
+// supplier is closed.
+type ClosedDayPolicy string
+
+const (
+	ClosedDaySkip  ClosedDayPolicy = "skip"
+	ClosedDayDefer ClosedDayPolicy = "defer"
+)
+
+type StandingOrderRunStatus string
+
+const (
+	StandingOrderRunSubmitted StandingOrderRunStatus = "submitted"
+	StandingOrderRunFailed    StandingOrderRunStatus = "failed"
+	StandingOrderRunSkipped   StandingOrderRunStatus = "skipped"
+	StandingOrderRunDeferred  StandingOrderRunStatus = "deferred"
+)
+
+// StandingOrder is a store's recurring order template with one supplier. The
+// scheduler turns it into a submitted order on every run date.
+type StandingOrder struct {
+	ID              uint                    `gorm:"primaryKey" json:"id"`
+	StoreID         uint                    `gorm:"not null;index" json:"store_id"`
+	Store           User                    `gorm:"foreignKey:StoreID;references:ID" json:"store,omitempty"`
+	SupplierID      uint                    `gorm:"not null;index" json:"supplier_id"`
+	Supplier        User                    `gorm:"foreignKey:SupplierID;references:ID" json:"supplier,omitempty"`
+	Name            string                  `gorm:"type:varchar(255)" json:"name"`
+	Recurrence      StandingOrderRecurrence `gorm:"type:varchar(20);not null" json:"recurrence"`
+	Weekdays        string                  `gorm:"type:varchar(20)" json:"weekdays"` // Comma-separated: 0=Sunday, 1=Monday, etc.
+	IntervalDays    int                     `gorm:"default:0" json:"interval_days"`
+	ClosedDayPolicy ClosedDayPolicy         `gorm:"type:varchar(20);not null;default:'skip'" json:"closed_day_policy"`
+	PaymentMethod   PaymentMethod           `gorm:"type:varchar(20);not null" json:"payment_method"`
+	DeliveryOption  DeliveryOption          `gorm:"type:varchar(20);not null" json:"delivery_option"`
+	ShippingAddress string                  `gorm:"type:text" json:"shipping_address"`
+	Notes           string                  `gorm:"type:text" json:"notes"`
+	Active          bool                    `gorm:"default:true;index" json:"active"`
+	NextRunAt       time.Time               `gorm:"not null;index" json:"next_run_at"`
+	LastRunAt       *time.Time              `json:"last_run_at,omitempty"`
+	LastRunStatus   StandingOrderRunStatus  `gorm:"type:varchar(20)" json:"last_run_status,omitempty"`
+	LastRunMessage  string                  `gorm:"type:text" json:"last_run_message,omitempty"`
+	LastOrderID     *uint                   `json:"last_order_id,omitempty"`
+	Items           []StandingOrderItem     `gorm:"foreignKey:StandingOrderID" json:"items"`
+	CreatedAt       time.Time               `json:"created_at"`
+	UpdatedAt       time.Time               `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt          `gorm:"index" json:"-"`
+}
+
+type StandingOrderItem struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	StandingOrderID uint      `gorm:"not null;index" json:"standing_order_id"`
+	ProductID       uint      `gorm:"not null;index" json:"product_id"`
+	Product         Product   `gorm:"foreignKey:ProductID" json:"product"`
+	Quantity        int       `gorm:"not null" json:"quantity"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
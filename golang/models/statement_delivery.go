@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// StatementDelivery records that a store was emailed its statement of
+// account from a supplier for the month starting PeriodStart, so the monthly
+// job sends each statement once.
+type StatementDelivery struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	SupplierID  uint      `gorm:"not null;uniqueIndex:idx_statement_delivery_period,priority:1" json:"supplier_id"`
+	StoreID     uint      `gorm:"not null;uniqueIndex:idx_statement_delivery_period,priority:2" json:"store_id"`
+	PeriodStart time.Time `gorm:"type:date;not null;uniqueIndex:idx_statement_delivery_period,priority:3" json:"period_start"`
+	SentAt      time.Time `gorm:"not null" json:"sent_at"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+type ReservationStatus string
+
+const (
+	ReservationStatusActive   ReservationStatus = "active"
+	ReservationStatusReleased ReservationStatus = "released"
+	ReservationStatusConsumed ReservationStatus = "consumed"
+)
+
+// StockReservation holds product stock for a draft order until it is
+// submitted (consumed), removed or cancelled, or expires (released).
+// Available-to-sell is StockQuantity minus active, unexpired reservations.
+type StockReservation struct {
+	ID         uint              `gorm:"primaryKey" json:"id"`
+	ProductID  uint              `gorm:"not null;index;uniqueIndex:idx_reservation_order_product,priority:2" json:"product_id"`
+	Product    Product           `gorm:"foreignKey:ProductID" json:"-"`
+	OrderID    uint              `gorm:"not null;index;uniqueIndex:idx_reservation_order_product,priority:1" json:"order_id"`
+	Order      Order             `gorm:"foreignKey:OrderID" json:"-"`
+	Quantity   int               `gorm:"not null" json:"quantity"`
+	Status     ReservationStatus `gorm:"type:varchar(20);not null;default:'active';index" json:"status"`
+	ExpiresAt  time.Time         `gorm:"not null;index" json:"expires_at"`
+	ReleasedAt *time.Time        `json:"released_at,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+)
+
+type DeliveryFeeType string
+
+const (
+	// DeliveryFeeTypeFlat charges FlatFee for every delivery.
+	DeliveryFeeTypeFlat DeliveryFeeType = "flat"
+	// DeliveryFeeTypeDistance charges by the per-km band the distance falls in.
+	DeliveryFeeTypeDistance DeliveryFeeType = "distance"
+	// DeliveryFeeTypeZone charges by the store's barangay.
+	DeliveryFeeTypeZone DeliveryFeeType = "zone"
+)
+
+type ServiceAreaType string
+
+const (
+	// ServiceAreaAnywhere means the supplier delivers to every store.
+	ServiceAreaAnywhere ServiceAreaType = ""
+	// ServiceAreaRadius covers stores within ServiceRadiusKm of the supplier.
+	ServiceAreaRadius ServiceAreaType = "radius"
+	// ServiceAreaPolygon covers stores inside ServiceAreaPolygon.
+	ServiceAreaPolygon ServiceAreaType = "polygon"
+	// ServiceAreaNamed covers stores in the listed barangays or municipalities.
+	ServiceAreaNamed ServiceAreaType = "named"
+)
+
+// GeoPoint is a latitude/longitude pair.
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// DefaultMinimumOrderAmount applies to suppliers that have not set their own.
+const DefaultMinimumOrderAmount = 5000.0
+
+// SupplierDeliverySettings holds a supplier's order minimum and delivery fee
+// schedule. FreeDeliveryThreshold of zero means delivery is never free.
+type SupplierDeliverySettings struct {
+	ID                    uint               `gorm:"primaryKey" json:"id"`
+	SupplierID            uint               `gorm:"not null;uniqueIndex" json:"supplier_id"`
+	Supplier              User               `gorm:"foreignKey:SupplierID;references:ID" json:"-"`
+	MinimumOrderAmount    float64            `gorm:"type:decimal(10,2);not null;default:5000" json:"minimum_order_amount"`
+	FreeDeliveryThreshold float64            `gorm:"type:decimal(10,2);default:0" json:"free_delivery_threshold"`
+	FeeType               DeliveryFeeType    `gorm:"type:varchar(20);not null;default:'flat'" json:"fee_type"`
+	FlatFee               float64            `gorm:"type:decimal(10,2);default:0" json:"flat_fee"`
+	DistanceBands         []DeliveryFeeBand  `gorm:"foreignKey:SettingsID" json:"distance_bands"`
+	Zones                 []DeliveryFeeZone  `gorm:"foreignKey:SettingsID" json:"zones"`
+	ServiceAreaType       ServiceAreaType    `gorm:"type:varchar(20);default:''" json:"service_area_type"`
+	ServiceRadiusKm       float64            `gorm:"type:decimal(10,2);default:0" json:"service_radius_km"`
+	ServiceAreaPolygon    []GeoPoint         `gorm:"type:text;serializer:json" json:"service_area_polygon"`
+	ServiceAreaPlaces     []ServiceAreaPlace `gorm:"foreignKey:SettingsID" json:"service_area_places"`
+	CreatedAt             time.Time          `json:"created_at"`
+	UpdatedAt             time.Time          `json:"updated_at"`
+}
+
+func (SupplierDeliverySettings) TableName() string {
+	return "supplier_delivery_settings"
+}
+
+// DeliveryFeeBand charges Fee for distances up to UpToKm, above the previous
+// band's limit.
+type DeliveryFeeBand struct {
+	ID         uint    `gorm:"primaryKey" json:"id"`
+	SettingsID uint    `gorm:"not null;index" json:"-"`
+	UpToKm     float64 `gorm:"type:decimal(10,2);not null" json:"up_to_km"`
+	Fee        float64 `gorm:"type:decimal(10,2);not null" json:"fee"`
+}
+
+// DeliveryFeeZone charges Fee for stores in a barangay. An empty
+// Municipality matches the barangay in any municipality.
+type DeliveryFeeZone struct {
+	ID           uint    `gorm:"primaryKey" json:"id"`
+	SettingsID   uint    `gorm:"not null;index" json:"-"`
+	Barangay     string  `gorm:"type:varchar(100);not null" json:"barangay"`
+	Municipality string  `gorm:"type:varchar(100)" json:"municipality"`
+	Fee          float64 `gorm:"type:decimal(10,2);not null" json:"fee"`
+}
+
+// ServiceAreaPlace is one named place in a supplier's service area. An empty
+// Barangay covers the whole municipality.
+type ServiceAreaPlace struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	SettingsID   uint   `gorm:"not null;index" json:"-"`
+	Barangay     string `gorm:"type:varchar(100)" json:"barangay"`
+	Municipality string `gorm:"type:varchar(100)" json:"municipality"`
+}
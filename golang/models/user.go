@@ -21,6 +21,8 @@ type User struct {
 	Name             string         `gorm:"not null" json:"name"`
 	Phone            string         `json:"phone"`
 	Address          string         `json:"address"`
+	Barangay         string         `gorm:"type:varchar(100)" json:"barangay"`
+	Municipality     string         `gorm:"type:varchar(100)" json:"municipality"`
 	Latitude         *float64       `gorm:"type:decimal(10,8)" json:"latitude,omitempty"`
 	Longitude        *float64       `gorm:"type:decimal(11,8)" json:"longitude,omitempty"`
 	LogoURL          string         `json:"logo_url"`
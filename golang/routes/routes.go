@@ -12,6 +12,12 @@ import (
 func SetupRoutes(r *gin.Engine, cfg *config.Config) {
 	emailService := services.NewEmailService(cfg)
 
+	paymentProviders := services.NewPaymentProviderRegistry()
+	if cfg.PaymentSimulatorSecret != "" {
+		paymentProviders.Register(services.NewSimulatedPaymentProvider(cfg.PaymentSimulatorSecret))
+	}
+	handlers.SetPaymentProviders(paymentProviders)
+
 	r.Use(func(c *gin.Context) {
 		c.Set("config", cfg)
 		c.Set("email_service", emailService)
@@ -26,6 +32,7 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config) {
 		api.POST("/login", handlers.UnifiedLogin)
 		api.POST("/employee/login", handlers.EmployeeLogin)
 		api.GET("/public/metrics", handlers.GetPublicMetrics)
+		api.POST("/payments/webhooks/:provider", handlers.HandlePaymentWebhook)
 
 		protected := api.Group("/")
 		protected.Use(middleware.AuthMiddleware(cfg))
@@ -39,6 +46,23 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config) {
 			protected.POST("/upload", handlers.UploadImage)
 			protected.GET("/me/analytics", handlers.GetMyAnalytics)
 			protected.GET("/me/ratings", handlers.GetMyRatings)
+			protected.GET("/me/delivery-settings", handlers.GetMyDeliverySettings)
+			protected.PUT("/me/delivery-settings", handlers.UpdateMyDeliverySettings)
+			protected.PUT("/me/service-area", handlers.UpdateMyServiceArea)
+			protected.GET("/me/delivery-slots", handlers.GetMyDeliverySlots)
+			protected.POST("/me/delivery-slots", handlers.CreateDeliverySlot)
+			protected.PUT("/me/delivery-slots/:id", handlers.UpdateDeliverySlot)
+			protected.DELETE("/me/delivery-slots/:id", handlers.DeleteDeliverySlot)
+			protected.GET("/me/orders/by-slot", handlers.GetMyOrdersBySlot)
+			protected.GET("/me/deliveries", handlers.GetMyDeliveries)
+			protected.GET("/me/route-plan", handlers.GetRoutePlan)
+			protected.GET("/me/route-plan/run-sheet", handlers.DownloadRunSheet)
+			protected.GET("/me/credit-accounts", handlers.GetMyCreditAccounts)
+			protected.PUT("/me/credit-accounts/:store_id", handlers.SetCreditAccount)
+			protected.DELETE("/me/credit-accounts/:store_id", handlers.DeleteCreditAccount)
+			protected.GET("/me/receivables/aging", handlers.GetReceivablesAging)
+			protected.GET("/me/statement", handlers.GetMyStatement)
+			protected.GET("/me/statement/pdf", handlers.DownloadMyStatement)
 
 			protected.GET("/products", handlers.GetProducts)
 			protected.GET("/products/:id", handlers.GetProduct)
@@ -54,21 +78,64 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config) {
 			protected.GET("/orders", handlers.GetOrders)
 			protected.GET("/orders/draft", handlers.GetDraftOrder)
 			protected.POST("/orders/draft", handlers.CreateDraftOrder)
+			protected.POST("/orders/checkout", handlers.CheckoutDrafts)
 			protected.GET("/orders/:id/messages", handlers.GetOrderMessages)
 			protected.POST("/orders/:id/messages", handlers.CreateOrderMessage)
+			protected.GET("/orders/:id/delivery-quote", handlers.GetDeliveryQuote)
 			protected.GET("/orders/:id/invoice", handlers.DownloadInvoice)
 			protected.POST("/orders/:id/send-invoice", handlers.SendInvoiceEmail)
 			protected.POST("/orders/:id/submit", handlers.SubmitOrder)
+			protected.POST("/orders/:id/reorder", handlers.ReorderOrder)
 			protected.POST("/orders/:id/items", handlers.AddOrderItem)
 			protected.PUT("/orders/:id/status", handlers.UpdateOrderStatus)
+			protected.PUT("/orders/:id/fulfilment", handlers.UpdateOrderFulfilment)
+			protected.POST("/orders/:id/deliver", handlers.ConfirmDelivery)
+			protected.PUT("/orders/:id/driver", handlers.AssignOrderDriver)
+			protected.POST("/orders/:id/location", handlers.RecordDeliveryLocation)
+			protected.GET("/orders/:id/tracking", handlers.GetOrderTracking)
 			protected.POST("/orders/:id/payment/paid", handlers.MarkPaymentAsPaid)
 			protected.POST("/orders/:id/payment/pending", handlers.MarkPaymentAsPending)
+			protected.POST("/orders/:id/payment/verify", handlers.VerifyPayment)
+			protected.GET("/orders/:id/payment/verifications", handlers.GetPaymentVerifications)
+			protected.GET("/orders/:id/payments", handlers.GetOrderPayments)
+			protected.POST("/orders/:id/payments", handlers.RecordOrderPayment)
+			protected.GET("/orders/:id/payment/intents", handlers.GetPaymentIntents)
+			protected.POST("/orders/:id/payment/intents", handlers.CreatePaymentIntent)
+			protected.POST("/orders/:id/payment/intents/:intent_id/refund", handlers.RefundPaymentIntent)
+			protected.POST("/orders/:id/returns", handlers.CreateReturnRequest)
 			protected.GET("/orders/:id", handlers.GetOrder)
 			protected.PUT("/orders/items/:item_id", handlers.UpdateOrderItem)
 			protected.DELETE("/orders/items/:item_id", handlers.RemoveOrderItem)
 
+			protected.GET("/returns", handlers.GetReturnRequests)
+			protected.GET("/returns/:id", handlers.GetReturnRequest)
+			protected.POST("/returns/:id/approve", handlers.ApproveReturnRequest)
+			protected.POST("/returns/:id/reject", handlers.RejectReturnRequest)
+
+			protected.GET("/rfqs", handlers.GetRFQs)
+			protected.POST("/rfqs", handlers.CreateRFQ)
+			protected.GET("/rfqs/:id", handlers.GetRFQ)
+			protected.POST("/rfqs/:id/close", handlers.CloseRFQ)
+			protected.POST("/rfqs/:id/quotes", handlers.SubmitQuote)
+			protected.POST("/rfqs/:id/quotes/:quote_id/accept", handlers.AcceptQuote)
+
+			protected.GET("/shipments", handlers.GetShipments)
+			protected.POST("/shipments", handlers.CreateShipment)
+			protected.GET("/shipments/:id", handlers.GetShipment)
+			protected.PUT("/shipments/:id", handlers.UpdateShipment)
+			protected.PUT("/shipments/:id/status", handlers.UpdateShipmentStatus)
+			protected.GET("/shipments/:id/manifest", handlers.DownloadShipmentManifest)
+
+			protected.GET("/standing-orders", handlers.GetStandingOrders)
+			protected.POST("/standing-orders", handlers.CreateStandingOrder)
+			protected.GET("/standing-orders/:id", handlers.GetStandingOrder)
+			protected.PUT("/standing-orders/:id", handlers.UpdateStandingOrder)
+			protected.DELETE("/standing-orders/:id", handlers.DeleteStandingOrder)
+
 			protected.GET("/suppliers", handlers.GetSuppliers)
 			protected.GET("/suppliers/:id/products", handlers.GetSupplierProducts)
+			protected.GET("/suppliers/:id/delivery-settings", handlers.GetSupplierDeliverySettings)
+			protected.GET("/suppliers/:id/delivery-slots", handlers.GetSupplierDeliverySlots)
 
 			protected.GET("/stores", handlers.GetStores)
 
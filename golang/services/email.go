@@ -4,10 +4,12 @@ import (
 	_ "embed"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"log"
 	"siargao-trading-road/config"
 	"siargao-trading-road/models"
 	"strconv"
+	"time"
 
 	"gopkg.in/gomail.v2"
 )
@@ -52,7 +54,17 @@ func (es *EmailService) getEmailFooter() string {
 	`
 }
 
+// EmailAttachment is a file sent along with an email.
+type EmailAttachment struct {
+	Filename string
+	Data     []byte
+}
+
 func (es *EmailService) SendEmail(to, subject, body string) error {
+	return es.SendEmailWithAttachments(to, subject, body)
+}
+
+func (es *EmailService) SendEmailWithAttachments(to, subject, body string, attachments ...EmailAttachment) error {
 	if es.config.SMTPHost == "" || es.config.SMTPUser == "" || es.config.SMTPPassword == "" {
 		log.Printf("Email not configured, skipping email to %s", to)
 		return nil
@@ -63,6 +75,13 @@ func (es *EmailService) SendEmail(to, subject, body string) error {
 	m.SetHeader("To", to)
 	m.SetHeader("Subject", subject)
 	m.SetBody("text/html", body)
+	for _, attachment := range attachments {
+		data := attachment.Data
+		m.Attach(attachment.Filename, gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		}))
+	}
 
 	port := 587
 	if es.config.SMTPPort != "" {
@@ -112,6 +131,8 @@ func (es *EmailService) SendOrderSuccessEmail(order models.Order) error {
 	var paymentInfo string
 	if order.PaymentMethod == models.PaymentMethodGCash {
 		paymentInfo = fmt.Sprintf("Payment Method: GCash (Status: %s)", order.PaymentStatus)
+	} else if order.PaymentMethod == models.PaymentMethodCredit && order.DueDate != nil {
+		paymentInfo = fmt.Sprintf("Payment Method: Credit (Due: %s)", order.DueDate.Format("January 2, 2006"))
 	} else {
 		paymentInfo = "Payment Method: Cash on Delivery"
 	}
@@ -241,6 +262,161 @@ func (es *EmailService) SendOrderStatusChangeEmail(order models.Order, oldStatus
 	return nil
 }
 
+// SendOrderFulfilmentChangedEmail tells the store which lines of a preparing
+// order will be short-shipped or substituted, before it is dispatched.
+func (es *EmailService) SendOrderFulfilmentChangedEmail(order models.Order) error {
+	if order.Store.Email == "" {
+		return nil
+	}
+
+	subject := fmt.Sprintf("Changes to Order #%d", order.ID)
+
+	itemsList := ""
+	for _, item := range order.OrderItems {
+		var change string
+		switch item.FulfilmentStatus {
+		case models.ItemFulfilmentShortShipped:
+			change = fmt.Sprintf("Short-shipped: %d of %d", item.BilledQuantity(), item.Quantity)
+		case models.ItemFulfilmentSubstituted:
+			substitute := "another product"
+			if item.SubstituteProduct != nil {
+				substitute = item.SubstituteProduct.Name
+			}
+			change = fmt.Sprintf("Substituted with %s: %d at ₱%.2f", substitute, item.BilledQuantity(), item.BilledUnitPrice())
+		default:
+			continue
+		}
+		if item.FulfilmentNote != "" {
+			change += " (" + item.FulfilmentNote + ")"
+		}
+		itemsList += fmt.Sprintf("<tr><td style=\"padding: 10px; border: 1px solid #ddd;\">%s</td><td style=\"padding: 10px; border: 1px solid #ddd;\">%s</td><td style=\"padding: 10px; border: 1px solid #ddd;\">₱%.2f</td></tr>",
+			item.Product.Name, change, item.Subtotal)
+	}
+
+	body := fmt.Sprintf(`
+		<html>
+		<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; margin: 0; padding: 0; background-color: #f4f4f4;">
+			<div style="max-width: 600px; margin: 0 auto; background-color: #ffffff;">
+				%s
+				<div style="padding: 20px;">
+					<h1 style="color: #2c3e50; margin-top: 0;">Changes to Your Order</h1>
+					<p>Dear %s,</p>
+					<p>%s can't ship every item of order #%d as ordered. The following lines have changed:</p>
+					<table style="width: 100%%; border-collapse: collapse; margin: 20px 0;">
+						<thead>
+							<tr style="background-color: #34495e; color: white;">
+								<th style="padding: 10px; text-align: left; border: 1px solid #ddd;">Ordered Product</th>
+								<th style="padding: 10px; text-align: left; border: 1px solid #ddd;">Change</th>
+								<th style="padding: 10px; text-align: left; border: 1px solid #ddd;">Subtotal</th>
+							</tr>
+						</thead>
+						<tbody>
+							%s
+						</tbody>
+					</table>
+					<p style="text-align: right; font-size: 18px; font-weight: bold;">
+						<strong>New Total Amount: ₱%.2f</strong>
+					</p>
+					<p>You will only be charged for what is delivered. Contact the supplier if you have any questions.</p>
+					<p>Best regards,<br>The Siargao Trading Road Team</p>
+				</div>
+				%s
+			</div>
+		</body>
+		</html>
+	`, es.getEmailHeader(), order.Store.Name, order.Supplier.Name, order.ID, itemsList, order.TotalAmount, es.getEmailFooter())
+
+	if err := es.SendEmail(order.Store.Email, subject, body); err != nil {
+		log.Printf("Failed to send fulfilment change email to %s: %v", order.Store.Email, err)
+	}
+
+	return nil
+}
+
+func (es *EmailService) SendRFQReceivedEmail(rfq models.RFQ, supplier models.User) error {
+	if supplier.Email == "" {
+		return nil
+	}
+
+	subject := fmt.Sprintf("Request for Quote #%d from %s", rfq.ID, rfq.Store.Name)
+
+	linesList := ""
+	for _, line := range rfq.Lines {
+		linesList += fmt.Sprintf("<tr><td style=\"padding: 10px; border: 1px solid #ddd;\">%s</td><td style=\"padding: 10px; border: 1px solid #ddd;\">%d %s</td></tr>",
+			line.Description, line.Quantity, line.Unit)
+	}
+
+	body := fmt.Sprintf(`
+		<html>
+		<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; margin: 0; padding: 0; background-color: #f4f4f4;">
+			<div style="max-width: 600px; margin: 0 auto; background-color: #ffffff;">
+				%s
+				<div style="padding: 20px;">
+					<h1 style="color: #2c3e50; margin-top: 0;">New Request for Quote</h1>
+					<p>Dear %s,</p>
+					<p>%s would like a quote for the following items:</p>
+					<p><strong>%s</strong></p>
+					<table style="width: 100%%; border-collapse: collapse; margin: 20px 0;">
+						<thead>
+							<tr style="background-color: #34495e; color: white;">
+								<th style="padding: 10px; text-align: left; border: 1px solid #ddd;">Item</th>
+								<th style="padding: 10px; text-align: left; border: 1px solid #ddd;">Quantity</th>
+							</tr>
+						</thead>
+						<tbody>
+							%s
+						</tbody>
+					</table>
+					<p>Open the app to send your prices.</p>
+					<p>Best regards,<br>The Siargao Trading Road Team</p>
+				</div>
+				%s
+			</div>
+		</body>
+		</html>
+	`, es.getEmailHeader(), supplier.Name, rfq.Store.Name, rfq.Title, linesList, es.getEmailFooter())
+
+	if err := es.SendEmail(supplier.Email, subject, body); err != nil {
+		log.Printf("Failed to send RFQ email to %s: %v", supplier.Email, err)
+	}
+
+	return nil
+}
+
+func (es *EmailService) SendQuoteReceivedEmail(rfq models.RFQ, quote models.Quote) error {
+	if rfq.Store.Email == "" {
+		return nil
+	}
+
+	subject := fmt.Sprintf("New Quote for \"%s\"", rfq.Title)
+
+	body := fmt.Sprintf(`
+		<html>
+		<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; margin: 0; padding: 0; background-color: #f4f4f4;">
+			<div style="max-width: 600px; margin: 0 auto; background-color: #ffffff;">
+				%s
+				<div style="padding: 20px;">
+					<h1 style="color: #2c3e50; margin-top: 0;">You Received a Quote</h1>
+					<p>Dear %s,</p>
+					<p>%s has quoted on your request for quote <strong>%s</strong>.</p>
+					<p><strong>Quoted Total:</strong> ₱%.2f</p>
+					<p><strong>Valid Until:</strong> %s</p>
+					<p>Open the app to compare quotes and place your order.</p>
+					<p>Best regards,<br>The Siargao Trading Road Team</p>
+				</div>
+				%s
+			</div>
+		</body>
+		</html>
+	`, es.getEmailHeader(), rfq.Store.Name, quote.Supplier.Name, rfq.Title, quote.Total, quote.ExpiresAt.Format("January 2, 2006 3:04 PM"), es.getEmailFooter())
+
+	if err := es.SendEmail(rfq.Store.Email, subject, body); err != nil {
+		log.Printf("Failed to send quote email to %s: %v", rfq.Store.Email, err)
+	}
+
+	return nil
+}
+
 func (es *EmailService) SendPaymentPaidEmail(order models.Order) error {
 	subject := fmt.Sprintf("Payment Confirmed for Order #%d", order.ID)
 
@@ -284,6 +460,81 @@ func (es *EmailService) SendPaymentPaidEmail(order models.Order) error {
 	return nil
 }
 
+// SendPaymentRejectedEmail tells the store its GCash payment could not be
+// verified and why.
+func (es *EmailService) SendPaymentRejectedEmail(order models.Order, verification models.PaymentVerification) error {
+	subject := fmt.Sprintf("Payment for Order #%d Could Not Be Verified", order.ID)
+
+	body := fmt.Sprintf(`
+		<html>
+		<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; margin: 0; padding: 0; background-color: #f4f4f4;">
+			<div style="max-width: 600px; margin: 0 auto; background-color: #ffffff;">
+				%s
+				<div style="padding: 20px;">
+					<h1 style="color: #e74c3c; margin-top: 0;">Payment Not Verified</h1>
+					<p>Dear %s,</p>
+					<p>%s could not verify the GCash payment submitted for your order.</p>
+					<h2 style="color: #34495e;">Payment Details</h2>
+					<p><strong>Order ID:</strong> #%d</p>
+					<p><strong>Reference Number:</strong> %s</p>
+					<p><strong>Amount Paid:</strong> ₱%.2f</p>
+					<p><strong>Order Total:</strong> ₱%.2f</p>
+					<p><strong>Reason:</strong> %s</p>
+					<p>Please check the payment in your GCash app and contact the supplier or send a new proof of payment.</p>
+					<p>Best regards,<br>The Siargao Trading Road Team</p>
+				</div>
+				%s
+			</div>
+		</body>
+		</html>
+	`, es.getEmailHeader(), order.Store.Name, order.Supplier.Name, order.ID, verification.ReferenceNumber, verification.AmountPaid, order.TotalAmount, verification.Reason, es.getEmailFooter())
+
+	if order.Store.Email == "" {
+		return nil
+	}
+	return es.SendEmail(order.Store.Email, subject, body)
+}
+
+// SendPaymentOverdueEmail reminds the store that a credit order is past its
+// due date.
+func (es *EmailService) SendPaymentOverdueEmail(order models.Order, daysOverdue int) error {
+	subject := fmt.Sprintf("Payment Overdue for Order #%d", order.ID)
+
+	dueDate := ""
+	if order.DueDate != nil {
+		dueDate = order.DueDate.Format("January 2, 2006")
+	}
+
+	body := fmt.Sprintf(`
+		<html>
+		<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; margin: 0; padding: 0; background-color: #f4f4f4;">
+			<div style="max-width: 600px; margin: 0 auto; background-color: #ffffff;">
+				%s
+				<div style="padding: 20px;">
+					<h1 style="color: #e67e22; margin-top: 0;">Payment Overdue</h1>
+					<p>Dear %s,</p>
+					<p>Payment for your credit order with %s is %d day(s) past due.</p>
+					<h2 style="color: #34495e;">Payment Details</h2>
+					<p><strong>Order ID:</strong> #%d</p>
+					<p><strong>Due Date:</strong> %s</p>
+					<p><strong>Order Total:</strong> ₱%.2f</p>
+					<p><strong>Amount Paid:</strong> ₱%.2f</p>
+					<p><strong>Balance Due:</strong> ₱%.2f</p>
+					<p>Please settle the balance with the supplier as soon as possible. If you have already paid, please send them your proof of payment.</p>
+					<p>Best regards,<br>The Siargao Trading Road Team</p>
+				</div>
+				%s
+			</div>
+		</body>
+		</html>
+	`, es.getEmailHeader(), order.Store.Name, order.Supplier.Name, daysOverdue, order.ID, dueDate, order.TotalAmount, order.AmountPaid, order.TotalAmount-order.AmountPaid, es.getEmailFooter())
+
+	if order.Store.Email == "" {
+		return nil
+	}
+	return es.SendEmail(order.Store.Email, subject, body)
+}
+
 func (es *EmailService) SendOrderDeliveredEmail(order models.Order) error {
 	subject := fmt.Sprintf("Order #%d Delivered", order.ID)
 
@@ -364,3 +615,68 @@ func (es *EmailService) SendInvoiceEmail(order models.Order, invoiceURL string)
 
 	return es.SendEmail(order.Store.Email, subject, body)
 }
+
+// SendStatementEmail sends the store its monthly statement of account from a
+// supplier with the PDF attached.
+func (es *EmailService) SendStatementEmail(store, supplier models.User, period string, closingBalance float64, filename string, pdf []byte) error {
+	subject := fmt.Sprintf("Statement of Account from %s for %s", supplier.Name, period)
+
+	body := fmt.Sprintf(`
+		<html>
+		<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; margin: 0; padding: 0; background-color: #f4f4f4;">
+			<div style="max-width: 600px; margin: 0 auto; background-color: #ffffff;">
+				%s
+				<div style="padding: 20px;">
+					<h1 style="color: #2c3e50; margin-top: 0;">Statement of Account</h1>
+					<p>Dear %s,</p>
+					<p>Please find attached your statement of account from %s for %s.</p>
+					<p><strong>Balance as of the end of the period:</strong> ₱%.2f</p>
+					<p>If anything on the statement does not match your records, please contact the supplier.</p>
+					<p>Best regards,<br>The Siargao Trading Road Team</p>
+				</div>
+				%s
+			</div>
+		</body>
+		</html>
+	`, es.getEmailHeader(), store.Name, supplier.Name, period, closingBalance, es.getEmailFooter())
+
+	if store.Email == "" {
+		return nil
+	}
+	return es.SendEmailWithAttachments(store.Email, subject, body, EmailAttachment{Filename: filename, Data: pdf})
+}
+
+func (es *EmailService) SendStandingOrderFailedEmail(standingOrder models.StandingOrder, runDate time.Time, reason string) error {
+	subject := fmt.Sprintf("Standing Order #%d Could Not Be Placed", standingOrder.ID)
+
+	name := standingOrder.Name
+	if name == "" {
+		name = fmt.Sprintf("Standing order #%d", standingOrder.ID)
+	}
+
+	body := fmt.Sprintf(`
+		<html>
+		<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; margin: 0; padding: 0; background-color: #f4f4f4;">
+			<div style="max-width: 600px; margin: 0 auto; background-color: #ffffff;">
+				%s
+				<div style="padding: 20px;">
+					<h1 style="color: #e74c3c; margin-top: 0;">Standing Order Not Placed</h1>
+					<p>Dear %s,</p>
+					<p>We could not place your scheduled order with %s for %s.</p>
+					<h2 style="color: #34495e;">Details</h2>
+					<p><strong>Standing Order:</strong> %s</p>
+					<p><strong>Reason:</strong> %s</p>
+					<p>Please review the standing order in the app. It will run again on its next scheduled date.</p>
+					<p>Best regards,<br>The Siargao Trading Road Team</p>
+				</div>
+				%s
+			</div>
+		</body>
+		</html>
+	`, es.getEmailHeader(), standingOrder.Store.Name, standingOrder.Supplier.Name, runDate.Format("January 2, 2006"), name, reason, es.getEmailFooter())
+
+	if standingOrder.Store.Email == "" {
+		return nil
+	}
+	return es.SendEmail(standingOrder.Store.Email, subject, body)
+}
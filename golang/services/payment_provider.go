@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"siargao-trading-road/models"
+)
+
+// ErrInvalidWebhookSignature is returned by ParseWebhook when a callback was
+// not signed by the provider.
+var ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+type PaymentIntentStatus string
+
+const (
+	PaymentIntentPending   PaymentIntentStatus = "pending"
+	PaymentIntentSucceeded PaymentIntentStatus = "succeeded"
+	PaymentIntentFailed    PaymentIntentStatus = "failed"
+)
+
+type PaymentEventType string
+
+const (
+	PaymentEventSucceeded PaymentEventType = "payment.succeeded"
+	PaymentEventFailed    PaymentEventType = "payment.failed"
+	PaymentEventRefunded  PaymentEventType = "refund.succeeded"
+)
+
+// ProviderIntent is a provider's view of a request to collect an amount for
+// an order. The store pays at CheckoutURL.
+type ProviderIntent struct {
+	ID          string
+	Status      PaymentIntentStatus
+	Amount      float64
+	CheckoutURL string
+}
+
+// ProviderRefund is a refund the provider has accepted.
+type ProviderRefund struct {
+	ID     string
+	Amount float64
+}
+
+// PaymentEvent is a verified webhook callback. ID identifies the thing that
+// moved money, the intent for payments and the refund for refunds, so the
+// same event delivered twice can be recognised.
+type PaymentEvent struct {
+	ID         string
+	Type       PaymentEventType
+	IntentID   string
+	Amount     float64
+	OccurredAt time.Time
+}
+
+// PaymentProvider is an online payment gateway. Its Name is the order
+// payment method it handles.
+type PaymentProvider interface {
+	Name() string
+	CreateIntent(ctx context.Context, order models.Order, amount float64) (ProviderIntent, error)
+	GetIntent(ctx context.Context, intentID string) (ProviderIntent, error)
+	// ParseWebhook verifies a callback's signature and decodes it.
+	ParseWebhook(header http.Header, body []byte) (PaymentEvent, error)
+	Refund(ctx context.Context, intentID string, amount float64) (ProviderRefund, error)
+}
+
+// PaymentProviderRegistry holds the configured providers by name.
+type PaymentProviderRegistry struct {
+	mu        sync.RWMutex
+	providers map[string]PaymentProvider
+}
+
+func NewPaymentProviderRegistry(providers ...PaymentProvider) *PaymentProviderRegistry {
+	registry := &PaymentProviderRegistry{providers: map[string]PaymentProvider{}}
+	for _, provider := range providers {
+		registry.Register(provider)
+	}
+	return registry
+}
+
+// Register adds a provider, replacing any with the same name.
+func (r *PaymentProviderRegistry) Register(provider PaymentProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[provider.Name()] = provider
+}
+
+func (r *PaymentProviderRegistry) Get(name string) (PaymentProvider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+// Names lists the registered providers in alphabetical order.
+func (r *PaymentProviderRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
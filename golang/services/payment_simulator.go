@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"siargao-trading-road/models"
+)
+
+const (
+	// SimulatedPaymentMethod is the payment method served by the simulator.
+	SimulatedPaymentMethod = "simulated"
+	// SimulatorSignatureHeader carries the hex HMAC-SHA256 of the body.
+	SimulatorSignatureHeader = "X-Simulator-Signature"
+)
+
+// SimulatedPaymentProvider is a local stand-in for an online gateway, for
+// development and tests. Intents live in memory and are settled by calling
+// Settle, which returns the signed webhook a real gateway would send.
+type SimulatedPaymentProvider struct {
+	secret []byte
+
+	mu       sync.Mutex
+	nextID   int
+	intents  map[string]*ProviderIntent
+	refunded map[string]float64
+}
+
+func NewSimulatedPaymentProvider(secret string) *SimulatedPaymentProvider {
+	return &SimulatedPaymentProvider{
+		secret:   []byte(secret),
+		intents:  map[string]*ProviderIntent{},
+		refunded: map[string]float64{},
+	}
+}
+
+type simulatorWebhook struct {
+	ID         string           `json:"id"`
+	Type       PaymentEventType `json:"type"`
+	IntentID   string           `json:"intent_id"`
+	Amount     float64          `json:"amount"`
+	OccurredAt time.Time        `json:"occurred_at"`
+}
+
+func (p *SimulatedPaymentProvider) Name() string {
+	return SimulatedPaymentMethod
+}
+
+func (p *SimulatedPaymentProvider) CreateIntent(ctx context.Context, order models.Order, amount float64) (ProviderIntent, error) {
+	if amount <= 0 {
+		return ProviderIntent{}, fmt.Errorf("amount must be greater than zero")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nextID++
+	id := fmt.Sprintf("sim_pi_%d_%d", order.ID, p.nextID)
+	intent := &ProviderIntent{
+		ID:          id,
+		Status:      PaymentIntentPending,
+		Amount:      amount,
+		CheckoutURL: "simulated://checkout/" + id,
+	}
+	p.intents[id] = intent
+	return *intent, nil
+}
+
+func (p *SimulatedPaymentProvider) GetIntent(ctx context.Context, intentID string) (ProviderIntent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return ProviderIntent{}, fmt.Errorf("intent %s not found", intentID)
+	}
+	return *intent, nil
+}
+
+func (p *SimulatedPaymentProvider) ParseWebhook(header http.Header, body []byte) (PaymentEvent, error) {
+	signature, err := hex.DecodeString(header.Get(SimulatorSignatureHeader))
+	if err != nil || !hmac.Equal(signature, p.sign(body)) {
+		return PaymentEvent{}, ErrInvalidWebhookSignature
+	}
+	var webhook simulatorWebhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return PaymentEvent{}, fmt.Errorf("invalid webhook body: %w", err)
+	}
+	return PaymentEvent(webhook), nil
+}
+
+func (p *SimulatedPaymentProvider) Refund(ctx context.Context, intentID string, amount float64) (ProviderRefund, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return ProviderRefund{}, fmt.Errorf("intent %s not found", intentID)
+	}
+	if intent.Status != PaymentIntentSucceeded {
+		return ProviderRefund{}, fmt.Errorf("intent %s has not been paid", intentID)
+	}
+	if amount <= 0 || amount > intent.Amount-p.refunded[intentID]+0.005 {
+		return ProviderRefund{}, fmt.Errorf("refund must be between 0 and %.2f", intent.Amount-p.refunded[intentID])
+	}
+	p.refunded[intentID] = math.Round((p.refunded[intentID]+amount)*100) / 100
+	p.nextID++
+	return ProviderRefund{ID: fmt.Sprintf("sim_re_%d", p.nextID), Amount: amount}, nil
+}
+
+// Settle completes or fails a pending intent as if the store had finished
+// checkout, and returns the signed webhook body and signature to post.
+func (p *SimulatedPaymentProvider) Settle(intentID string, succeed bool) ([]byte, string, error) {
+	p.mu.Lock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		p.mu.Unlock()
+		return nil, "", fmt.Errorf("intent %s not found", intentID)
+	}
+	webhook := simulatorWebhook{ID: intentID, Type: PaymentEventSucceeded, IntentID: intentID, Amount: intent.Amount, OccurredAt: time.Now()}
+	intent.Status = PaymentIntentSucceeded
+	if !succeed {
+		webhook.Type = PaymentEventFailed
+		intent.Status = PaymentIntentFailed
+	}
+	p.mu.Unlock()
+
+	body, err := json.Marshal(webhook)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, hex.EncodeToString(p.sign(body)), nil
+}
+
+func (p *SimulatedPaymentProvider) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
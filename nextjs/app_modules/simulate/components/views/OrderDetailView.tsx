@@ -298,12 +298,15 @@ export function OrderDetailView({
               </Typography>
               <Chip 
                 label={order.payment_status === 'paid' ? 'Paid' : 
-                       order.payment_status === 'pending' ? 'Pending' : 
+                       order.payment_status === 'unpaid' ? 'Unpaid' : 
+                       order.payment_status === 'partial' ? 'Partially Paid' : 
+                       order.payment_status === 'overpaid' ? 'Overpaid' : 
                        order.payment_status === 'failed' ? 'Failed' : 
                        order.payment_status}
                 color={
                   order.payment_status === 'paid' ? 'success' :
-                  order.payment_status === 'pending' ? 'warning' :
+                  order.payment_status === 'unpaid' || order.payment_status === 'partial' ? 'warning' :
+                  order.payment_status === 'overpaid' ? 'info' :
                   order.payment_status === 'failed' ? 'error' :
                   'default'
                 }
@@ -939,7 +942,7 @@ export function OrderDetailView({
           </Button>
         )}
 
-        {mobileUser?.role === 'supplier' && order.payment_method === 'gcash' && order.payment_status === 'pending' && onMarkPaymentAsPaid && (
+        {mobileUser?.role === 'supplier' && order.payment_method === 'gcash' && (order.payment_status === 'unpaid' || order.payment_status === 'partial') && onMarkPaymentAsPaid && (
           <Button
             variant="contained"
             fullWidth
@@ -151,12 +151,15 @@ export function OrdersView({
                   </Typography>
                   <Chip 
                     label={order.payment_status === 'paid' ? 'Paid' : 
-                           order.payment_status === 'pending' ? 'Pending' : 
+                           order.payment_status === 'unpaid' ? 'Unpaid' : 
+                           order.payment_status === 'partial' ? 'Partially Paid' : 
+                           order.payment_status === 'overpaid' ? 'Overpaid' : 
                            order.payment_status === 'failed' ? 'Failed' : 
                            order.payment_status}
                     color={
                       order.payment_status === 'paid' ? 'success' :
-                      order.payment_status === 'pending' ? 'warning' :
+                      order.payment_status === 'unpaid' || order.payment_status === 'partial' ? 'warning' :
+                      order.payment_status === 'overpaid' ? 'info' :
                       order.payment_status === 'failed' ? 'error' :
                       'default'
                     }
@@ -275,7 +278,7 @@ export function OrdersView({
                   </Button>
                 </Box>
               )}
-              {mobileUser?.role === 'supplier' && order.payment_method === 'gcash' && order.payment_status === 'pending' && onMarkPaymentAsPaid && (
+              {mobileUser?.role === 'supplier' && order.payment_method === 'gcash' && (order.payment_status === 'unpaid' || order.payment_status === 'partial') && onMarkPaymentAsPaid && (
                 <Box sx={{ mt: 2 }}>
                   <Button
                     variant="contained"
@@ -413,17 +413,19 @@ export default function OrdersScreen() {
                       </Text>
                       <Chip
                         style={[
-                          { backgroundColor: (order.payment_status === 'paid' ? '#4caf50' : order.payment_status === 'pending' ? '#ff9800' : '#f44336') + '20' }
+                          { backgroundColor: (order.payment_status === 'paid' || order.payment_status === 'overpaid' ? '#4caf50' : order.payment_status === 'unpaid' || order.payment_status === 'partial' ? '#ff9800' : '#f44336') + '20' }
                         ]}
                         textStyle={{ 
-                          color: order.payment_status === 'paid' ? '#4caf50' : 
-                                 order.payment_status === 'pending' ? '#ff9800' : 
+                          color: order.payment_status === 'paid' || order.payment_status === 'overpaid' ? '#4caf50' : 
+                                 order.payment_status === 'unpaid' || order.payment_status === 'partial' ? '#ff9800' : 
                                  '#f44336',
                           fontSize: 12
                         }}
                       >
                         {order.payment_status === 'paid' ? 'Paid' :
-                         order.payment_status === 'pending' ? 'Pending' :
+                         order.payment_status === 'unpaid' ? 'Unpaid' :
+                         order.payment_status === 'partial' ? 'Partially Paid' :
+                         order.payment_status === 'overpaid' ? 'Overpaid' :
                          order.payment_status === 'failed' ? 'Failed' :
                          order.payment_status}
                       </Chip>
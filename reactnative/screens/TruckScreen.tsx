@@ -111,7 +111,7 @@ export default function TruckScreen() {
               await orderService.submitOrder(draftOrder.id, {
                 payment_method: paymentMethod,
                 delivery_option: deliveryOption,
-                payment_status: paymentMethod === 'gcash' ? 'pending' : undefined,
+                payment_status: paymentMethod === 'gcash' ? 'unpaid' : undefined,
                 notes: notes.trim() || undefined,
                 delivery_fee: deliveryFee,
                 distance: 0,